@@ -0,0 +1,116 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/mauve/terraform-index/index"
+)
+
+// ArchiveFile is a file read from an archive, files larger than
+// index.MaxFileSize are not read and have TooLarge set instead of Contents
+type ArchiveFile struct {
+	Path     string
+	Contents []byte
+	TooLarge *index.FileTooLargeError
+}
+
+func IsArchive(path string) bool {
+	for _, extension := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(path, extension) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ArchiveFiles returns the terraform files of a zip or tar archive, like the
+// module archives served by registries, with paths relative to the archive
+// root
+func ArchiveFiles(name string, contents []byte) ([]ArchiveFile, error) {
+	if strings.HasSuffix(name, ".zip") {
+		return zipFiles(contents)
+	}
+
+	var reader io.Reader = bytes.NewReader(contents)
+	if strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tgz") {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	return tarFiles(reader)
+}
+
+func archivePath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+func zipFiles(contents []byte) ([]ArchiveFile, error) {
+	reader, err := zip.NewReader(bytes.NewReader(contents), int64(len(contents)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := []ArchiveFile{}
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || !index.IsTerraformFile(file.Name) {
+			continue
+		}
+
+		fileReader, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		fileContents, err := index.ReadLimited(fileReader, archivePath(file.Name))
+		fileReader.Close()
+		if tooLarge, ok := err.(*index.FileTooLargeError); ok {
+			files = append(files, ArchiveFile{Path: archivePath(file.Name), TooLarge: tooLarge})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, ArchiveFile{Path: archivePath(file.Name), Contents: fileContents})
+	}
+
+	return files, nil
+}
+
+func tarFiles(reader io.Reader) ([]ArchiveFile, error) {
+	tarReader := tar.NewReader(reader)
+	files := []ArchiveFile{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg || !index.IsTerraformFile(header.Name) {
+			continue
+		}
+
+		fileContents, err := index.ReadLimited(tarReader, archivePath(header.Name))
+		if tooLarge, ok := err.(*index.FileTooLargeError); ok {
+			files = append(files, ArchiveFile{Path: archivePath(header.Name), TooLarge: tooLarge})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, ArchiveFile{Path: archivePath(header.Name), Contents: fileContents})
+	}
+}
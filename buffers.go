@@ -0,0 +1,136 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/mauve/terraform-index/index"
+)
+
+// buffers indexes module directories from the files on disk overlaid with
+// the unsaved contents of open buffers. A changed buffer replaces its file in
+// the index of its directory, the directory is only reindexed when files are
+// added or removed or overrides are involved, and then only the changed
+// files are parsed again. files holds the files of each directory as of its
+// last indexing
+type buffers struct {
+	options index.AnalysisOptions
+	indexes map[string]*index.Index
+	open    map[string][]byte
+	files   map[string][]string
+	cache   *index.FileCache
+}
+
+func newBuffers(options index.AnalysisOptions) *buffers {
+	return &buffers{
+		options: options,
+		indexes: map[string]*index.Index{},
+		open:    map[string][]byte{},
+		files:   map[string][]string{},
+		cache:   index.NewFileCache(""),
+	}
+}
+
+// set replaces the unsaved contents of path, nil contents close the buffer
+// so the file is read from disk again. Returns the directory whose index
+// changed
+func (buffers *buffers) set(path string, contents []byte) string {
+	if contents == nil {
+		delete(buffers.open, path)
+	} else {
+		buffers.open[path] = contents
+	}
+
+	directory := filepath.Dir(path)
+	if indexed, ok := buffers.indexes[directory]; !ok || !buffers.update(indexed, path) {
+		delete(buffers.indexes, directory)
+	}
+	return directory
+}
+
+// update replaces a file already in the index of its directory with its
+// current contents, returns false when the directory has to be reindexed
+func (buffers *buffers) update(indexed *index.Index, path string) bool {
+	files := buffers.files[filepath.Dir(path)]
+	if found := sort.SearchStrings(files, path); found == len(files) || files[found] != path {
+		return false
+	}
+
+	contents, ok := buffers.open[path]
+	if !ok {
+		var err error
+		if contents, err = index.ReadFile(path); err != nil {
+			return false
+		}
+	}
+	if err := indexed.UpdateFile(path, contents); err == index.ErrOverridesApplied {
+		return false
+	}
+
+	indexed.Resolve()
+	indexed.Analyze(buffers.options)
+	return true
+}
+
+// indexFor returns the index of the module directory holding path
+func (buffers *buffers) indexFor(path string) *index.Index {
+	return buffers.directoryIndex(filepath.Dir(path))
+}
+
+func (buffers *buffers) directoryIndex(directory string) *index.Index {
+	if index, ok := buffers.indexes[directory]; ok {
+		return index
+	}
+
+	paths := map[string]bool{}
+	if entries, err := ioutil.ReadDir(directory); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() && index.IsTerraformFile(entry.Name()) {
+				paths[filepath.Join(directory, entry.Name())] = true
+			}
+		}
+	}
+	for open := range buffers.open {
+		if filepath.Dir(open) == directory {
+			paths[open] = true
+		}
+	}
+
+	files := []string{}
+	for path := range paths {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+
+	index := index.NewIndex()
+	for _, path := range files {
+		contents, ok := buffers.open[path]
+		if !ok {
+			var err error
+			if contents, err = Contents(path, ""); err != nil {
+				if tooLarge, ok := isTooLarge(err); ok {
+					index.SkipFile(tooLarge)
+				}
+				continue
+			}
+		}
+		index.CollectCached(buffers.cache, contents, path, false)
+	}
+	index.Resolve()
+	index.Analyze(buffers.options)
+
+	buffers.indexes[directory] = index
+	buffers.files[directory] = files
+	return index
+}
+
+// contents returns the unsaved contents of a document, or the saved ones
+func (buffers *buffers) contents(path string) []byte {
+	if contents, ok := buffers.open[path]; ok {
+		return contents
+	}
+
+	contents, _ := index.ReadFile(path)
+	return contents
+}
@@ -0,0 +1,144 @@
+// Command capi is built with -buildmode=c-shared into a library embedding the
+// indexer in editors written in C, C++ or Rust, and in Python through cffi.
+// The generated header declares:
+//
+//	int   TerraformIndexNew(void);
+//	void  TerraformIndexFree(int handle);
+//	char *TerraformIndexCollectString(int handle, char *contents, char *path);
+//	char *TerraformIndexLookup(int handle, char *address);
+//	char *TerraformIndexReferences(int handle, char *address);
+//	void  TerraformIndexFreeString(char *result);
+//
+// An index is referred to by the handle TerraformIndexNew returns, handles
+// are never zero. CollectString returns NULL or the message of the parse
+// error, Lookup returns the declaration of an address as JSON or NULL when
+// it is not declared and References returns the JSON array of the positions
+// referring to an address. Returned strings belong to the caller who frees
+// them with TerraformIndexFreeString. The functions may be called from any
+// thread
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"sync"
+	"unsafe"
+
+	"github.com/mauve/terraform-index/index"
+)
+
+// library is an index collected through the C API, it is resolved again
+// before the first query following a collection
+type library struct {
+	index    *index.Index
+	resolved bool
+}
+
+var (
+	mutex   sync.Mutex
+	handles = map[C.int]*library{}
+	next    = C.int(1)
+)
+
+// resolved returns the resolved index of handle, the mutex has to be held
+func resolved(handle C.int) (*index.Index, bool) {
+	entry, ok := handles[handle]
+	if !ok {
+		return nil, false
+	}
+
+	if !entry.resolved {
+		entry.index.Resolve()
+		entry.index.Analyze(index.AnalysisOptions{})
+		entry.resolved = true
+	}
+	return entry.index, true
+}
+
+// jsonString returns value as a JSON string allocated by C
+func jsonString(value interface{}) *C.char {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+
+	return C.CString(string(encoded))
+}
+
+//export TerraformIndexNew
+func TerraformIndexNew() C.int {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	handle := next
+	next++
+	handles[handle] = &library{index: index.NewIndex()}
+	return handle
+}
+
+//export TerraformIndexFree
+func TerraformIndexFree(handle C.int) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	delete(handles, handle)
+}
+
+//export TerraformIndexCollectString
+func TerraformIndexCollectString(handle C.int, contents *C.char, path *C.char) *C.char {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	entry, ok := handles[handle]
+	if !ok {
+		return C.CString("unknown index handle")
+	}
+
+	entry.resolved = false
+	if err := entry.index.CollectString([]byte(C.GoString(contents)), C.GoString(path), false); err != nil {
+		return C.CString(err.Error())
+	}
+	return nil
+}
+
+//export TerraformIndexLookup
+func TerraformIndexLookup(handle C.int, address *C.char) *C.char {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	indexed, ok := resolved(handle)
+	if !ok {
+		return nil
+	}
+
+	hover, ok := indexed.HoverFor(C.GoString(address))
+	if !ok {
+		return nil
+	}
+	return jsonString(hover)
+}
+
+//export TerraformIndexReferences
+func TerraformIndexReferences(handle C.int, address *C.char) *C.char {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	indexed, ok := resolved(handle)
+	if !ok {
+		return nil
+	}
+
+	return jsonString(indexed.ReferencesToAddress(C.GoString(address)))
+}
+
+//export TerraformIndexFreeString
+func TerraformIndexFreeString(result *C.char) {
+	C.free(unsafe.Pointer(result))
+}
+
+// main is required by -buildmode=c-shared but never runs
+func main() {}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// command is a subcommand of the CLI, run gets the arguments following its
+// name
+type command struct {
+	name    string
+	summary string
+	run     func(args []string)
+}
+
+// commands lists the subcommands in the order help shows them
+func commands() []command {
+	return []command{
+		{"diff", "compare two indexes and print what was added, removed or moved", diff},
+		{"index", "print the index of Terraform files, the default command", indexFiles},
+		{"merge", "combine indexes printed by separate runs into one", merge},
+		{"query", "send a query to a daemon started with serve -socket", query},
+		{"refs", "print every reference to an address as path:line:column", refs},
+		{"rename", "print or apply the edits renaming a declaration", rename},
+		{"serve", "run the language server, the query services or a daemon", serve},
+		{"stats", "count the declarations, resources, diagnostics and references", stats},
+		{"watch", "write the symbols and diagnostics of the files changing in directories", watch},
+	}
+}
+
+// help prints the commands, or the usage of the command it is given
+func help(args []string) {
+	for _, command := range commands() {
+		if len(args) > 0 && args[0] == command.name {
+			command.run([]string{"-h"})
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [options] [arguments]\n", BINARY)
+	fmt.Fprintf(os.Stderr, "       %s [options] <paths>\n\n", BINARY)
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	for _, command := range commands() {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", command.name, command.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s help <command>' for the options of a command\n", BINARY)
+	if len(args) > 0 {
+		os.Exit(1)
+	}
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "help" {
+		help(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 {
+		for _, command := range commands() {
+			if os.Args[1] == command.name {
+				command.run(os.Args[2:])
+				return
+			}
+		}
+	}
+
+	indexFiles(os.Args[1:])
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mauve/terraform-index/index"
+)
+
+// Daemon keeps the index of the files matching its patterns up to date by
+// polling them and answers queries over a Unix socket with the JSON-RPC
+// methods of the stdio server, several clients may be connected at once.
+// Changed files are replaced in live, queries are answered from a snapshot
+// of it taken once it is resolved and analyzed again
+type Daemon struct {
+	patterns []string
+	options  index.AnalysisOptions
+	mutex    sync.RWMutex
+	cache    string
+	files    *index.FileCache
+	live     *index.Index
+	index    *index.Index
+	stamps   map[string]index.FileStamp
+}
+
+// NewDaemon creates a daemon, with a cache the index is restored from and
+// saved to that file on every refresh. Otherwise only the files changed
+// since the last refresh are parsed, with the results kept in files or in
+// memory when files is nil
+func NewDaemon(patterns []string, options index.AnalysisOptions, cache string, files *index.FileCache) *Daemon {
+	if files == nil {
+		files = index.NewFileCache("")
+	}
+
+	return &Daemon{
+		patterns: patterns,
+		options:  options,
+		cache:    cache,
+		files:    files,
+		index:    index.NewIndex(),
+		stamps:   map[string]index.FileStamp{},
+	}
+}
+
+// scan stamps the files currently matching the patterns, except the ignored
+// ones loadIndex skips as well
+func (daemon *Daemon) scan() (map[string]index.FileStamp, error) {
+	ignore := index.NewIgnoreList()
+	if err := ignore.LoadIgnoreFile("."); err != nil {
+		return nil, err
+	}
+
+	paths, err := index.ExpandGlobs(daemon.patterns, false)
+	if err != nil {
+		return nil, err
+	}
+
+	stamps := map[string]index.FileStamp{}
+	for _, path := range paths {
+		if ignore.Match(path, false) {
+			continue
+		}
+		if stamp, err := index.StampFile(path); err == nil {
+			stamps[path] = stamp
+		}
+	}
+	return stamps, nil
+}
+
+// update replaces the changed files in the live index and removes the
+// removed ones, returns false when everything has to be indexed again
+func (daemon *Daemon) update(changed []string, removed []string) bool {
+	for _, path := range removed {
+		if daemon.live.RemoveFile(path) != nil {
+			return false
+		}
+	}
+	for _, path := range changed {
+		// files too large are reported by indexing everything again
+		contents, err := index.ReadFile(path)
+		if err != nil {
+			return false
+		}
+
+		if err := daemon.live.UpdateFile(path, contents); err == index.ErrOverridesApplied {
+			return false
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Could not parse '%s': %s\n", path, err)
+		}
+	}
+
+	daemon.live.Resolve()
+	daemon.live.Analyze(daemon.options)
+	return true
+}
+
+// Refresh reindexes the files if any of them was added, changed or removed
+// since the last refresh, returns whether it did
+func (daemon *Daemon) Refresh() (bool, error) {
+	paths, err := daemon.refresh()
+	return len(paths) > 0, err
+}
+
+// refresh is Refresh returning the paths added, changed or removed, sorted
+func (daemon *Daemon) refresh() ([]string, error) {
+	stamps, err := daemon.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+	for path, stamp := range stamps {
+		if previous, ok := daemon.stamps[path]; !ok || previous != stamp {
+			changed = append(changed, path)
+		}
+	}
+	removed := []string{}
+	for path := range daemon.stamps {
+		if _, ok := stamps[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	if len(changed) == 0 && len(removed) == 0 {
+		return nil, nil
+	}
+	sort.Strings(changed)
+	sort.Strings(removed)
+
+	// with a cache file the index is restored from it instead
+	if daemon.live == nil || daemon.cache != "" || !daemon.update(changed, removed) {
+		loaded, err := loadIndex(daemon.patterns, daemon.options, daemon.cache, daemon.files)
+		if err != nil {
+			return nil, err
+		}
+		loaded.Compact()
+		daemon.live = loaded
+	}
+
+	snapshot := daemon.live.Snapshot()
+	daemon.mutex.Lock()
+	daemon.index = snapshot
+	daemon.stamps = stamps
+	daemon.mutex.Unlock()
+
+	paths := append(changed, removed...)
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Watch refreshes the index every interval until the process ends
+func (daemon *Daemon) Watch(interval time.Duration) {
+	for range time.Tick(interval) {
+		if _, err := daemon.Refresh(); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Cannot reindex: %s\n", err)
+		}
+	}
+}
+
+func (daemon *Daemon) current() *index.Index {
+	daemon.mutex.RLock()
+	defer daemon.mutex.RUnlock()
+	return daemon.index
+}
+
+func (daemon *Daemon) run(method string, params rpcParams) (interface{}, bool) {
+	return rpcQuery(daemon.current(), method, params)
+}
+
+func (daemon *Daemon) serveConnection(connection net.Conn) {
+	defer connection.Close()
+
+	reader := bufio.NewReader(connection)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			response := rpcReply(line, func(message lspMessage) interface{} {
+				return rpcHandle(message, daemon.run)
+			})
+			if response != nil && writeRPC(connection, response) != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// ListenUnix serves the daemon on the Unix socket at path. A socket left
+// behind by a daemon which is gone is replaced, one which still answers is
+// an error
+func (daemon *Daemon) ListenUnix(path string) error {
+	if connection, err := net.Dial("unix", path); err == nil {
+		connection.Close()
+		return fmt.Errorf("a daemon is already serving '%s'", path)
+	}
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	for {
+		connection, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go daemon.serveConnection(connection)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mauve/terraform-index/index"
+)
+
+// diff runs the diff subcommand, which compares two indexes printed by the
+// index command
+func diff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the differences as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s diff [-json] <old index> <new index>\n\n", BINARY)
+		fmt.Fprintf(os.Stderr, "Compares two indexes printed as JSON or with -format binary, like those\n")
+		fmt.Fprintf(os.Stderr, "of the base and head of a pull request, and prints the declarations\n")
+		fmt.Fprintf(os.Stderr, "added, removed or moved to another file and the names referenced a\n")
+		fmt.Fprintf(os.Stderr, "different number of times. Exits with 1 when the indexes differ\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	indexes := []*index.Index{}
+	for _, path := range flags.Args() {
+		loaded, err := index.ReadIndexFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Cannot read index '%s': %s\n", path, err)
+			os.Exit(2)
+		}
+		indexes = append(indexes, loaded)
+	}
+
+	differences := index.DiffIndexes(indexes[0], indexes[1])
+	if *asJSON {
+		writeJSON(differences)
+	} else {
+		for _, change := range differences.Added {
+			fmt.Printf("+ %s %s (%s)\n", change.Kind, change.Address, formatPosition(change.Location))
+		}
+		for _, change := range differences.Removed {
+			fmt.Printf("- %s %s (%s)\n", change.Kind, change.Address, formatPosition(change.Location))
+		}
+		for _, change := range differences.Moved {
+			fmt.Printf("> %s %s (%s -> %s)\n", change.Kind, change.Address, formatPosition(change.Previous), formatPosition(change.Location))
+		}
+		for _, change := range differences.References {
+			fmt.Printf("* %s referenced %d -> %d times\n", change.Name, change.Before, change.After)
+		}
+	}
+
+	if !differences.Empty() {
+		os.Exit(1)
+	}
+}
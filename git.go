@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitOutput runs git and returns its output, failures carry git's message
+func gitOutput(args ...string) ([]byte, error) {
+	output, err := exec.Command("git", args...).Output()
+	if exitError, ok := err.(*exec.ExitError); ok {
+		return nil, fmt.Errorf("%s", strings.TrimSpace(string(exitError.Stderr)))
+	}
+
+	return output, err
+}
+
+// GitContents returns the contents of path, relative to the current
+// directory, at revision
+func GitContents(revision string, path string) ([]byte, error) {
+	return gitOutput("show", revision+":./"+filepath.ToSlash(path))
+}
+
+// GitFiles lists the files below the current directory at revision
+func GitFiles(revision string) ([]string, error) {
+	output, err := gitOutput("ls-tree", "-r", "-z", "--name-only", revision)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, file := range strings.Split(string(output), "\x00") {
+		if file != "" {
+			files = append(files, filepath.FromSlash(file))
+		}
+	}
+
+	return files, nil
+}
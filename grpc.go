@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+	"github.com/mauve/terraform-index/index"
+	"github.com/mauve/terraform-index/index/protobuf"
+)
+
+// GRPC_SERVICE is the path prefix of the methods of the service defined in
+// proto/terraform_index.proto
+const GRPC_SERVICE = "/terraform_index.v1.Index/"
+
+const (
+	GRPC_OK               = 0
+	GRPC_INVALID_ARGUMENT = 3
+	GRPC_NOT_FOUND        = 5
+	GRPC_UNIMPLEMENTED    = 12
+	GRPC_INTERNAL         = 13
+)
+
+// GRPCServer answers the queries of the gRPC service over HTTP/2 with the
+// hand written protocol buffer encoding of the protobuf package
+type GRPCServer struct {
+	index *index.Index
+}
+
+func NewGRPCServer(index *index.Index) *GRPCServer {
+	return &GRPCServer{index: index}
+}
+
+type grpcError struct {
+	status  int
+	message string
+}
+
+func (err *grpcError) Error() string {
+	return err.message
+}
+
+func textField(fields []protobuf.Field, number int) string {
+	for _, field := range fields {
+		if field.Number == number && field.Wire == protobuf.WIRE_BYTES {
+			return string(field.Bytes)
+		}
+	}
+	return ""
+}
+
+func intField(fields []protobuf.Field, number int) int {
+	for _, field := range fields {
+		if field.Number == number && field.Wire == protobuf.WIRE_VARINT {
+			return int(field.Varint)
+		}
+	}
+	return 0
+}
+
+func messageField(fields []protobuf.Field, number int) ([]protobuf.Field, error) {
+	for _, field := range fields {
+		if field.Number == number && field.Wire == protobuf.WIRE_BYTES {
+			return protobuf.Parse(field.Bytes)
+		}
+	}
+	return []protobuf.Field{}, nil
+}
+
+func positionMessage(pos hcltoken.Pos) protobuf.Message {
+	return protobuf.Message{}.Text(1, pos.Filename).Int(2, pos.Line).Int(3, pos.Column)
+}
+
+func symbolMessage(symbol index.Symbol) protobuf.Message {
+	message := protobuf.Message{}.Text(1, symbol.Name).Text(2, symbol.Kind).
+		Bytes(3, positionMessage(symbol.Location)).Bytes(4, positionMessage(symbol.EndLocation))
+	for _, child := range symbol.Children {
+		message = message.Bytes(5, symbolMessage(child))
+	}
+	return message
+}
+
+// address returns the address of a request, given either as address or as
+// the position of a symbol
+func (server *GRPCServer) address(fields []protobuf.Field) (string, error) {
+	if address := textField(fields, 1); address != "" {
+		return address, nil
+	}
+
+	position, err := messageField(fields, 2)
+	if err != nil {
+		return "", err
+	}
+	address, ok := server.index.AddressAt(textField(position, 1), intField(position, 2), intField(position, 3))
+	if !ok {
+		return "", &grpcError{GRPC_NOT_FOUND, "no symbol at position"}
+	}
+	return address, nil
+}
+
+func (server *GRPCServer) lookup(fields []protobuf.Field) (protobuf.Message, error) {
+	address, err := server.address(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	hover, ok := server.index.HoverFor(address)
+	if !ok {
+		return protobuf.Message{}, nil
+	}
+	declaration := protobuf.Message{}.Text(1, hover.Address).Text(2, hover.Kind).Text(3, hover.Type).
+		Text(4, hover.Default).Text(5, hover.Description).Bool(6, hover.Sensitive).
+		Bytes(7, positionMessage(hover.Location))
+	return protobuf.Message{}.Bool(1, true).Bytes(2, declaration), nil
+}
+
+func (server *GRPCServer) references(fields []protobuf.Field) (protobuf.Message, error) {
+	address, err := server.address(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	response := protobuf.Message{}
+	for _, location := range server.index.ReferencesToAddress(address) {
+		response = response.Bytes(1, positionMessage(location))
+	}
+	return response, nil
+}
+
+func (server *GRPCServer) symbols(fields []protobuf.Field) (protobuf.Message, error) {
+	files := server.index.Files()
+	if file := textField(fields, 1); file != "" {
+		files = []string{file}
+	}
+
+	response := protobuf.Message{}
+	for _, file := range files {
+		for _, symbol := range server.index.SymbolsInFile(file) {
+			response = response.Bytes(1, symbolMessage(symbol))
+		}
+	}
+	return response, nil
+}
+
+func (server *GRPCServer) diagnostics(fields []protobuf.Field) (protobuf.Message, error) {
+	file := textField(fields, 1)
+
+	response := protobuf.Message{}
+	for _, diagnostic := range server.index.Diagnostics {
+		if file != "" && diagnostic.Location.Filename != file {
+			continue
+		}
+		response = response.Bytes(1, protobuf.Message{}.Text(1, diagnostic.Severity).Text(2, diagnostic.Code).
+			Text(3, diagnostic.Message).Bytes(4, positionMessage(diagnostic.Location)).
+			Bytes(5, positionMessage(diagnostic.EndLocation)))
+	}
+	return response, nil
+}
+
+// call decodes a length prefixed request message and runs method
+func (server *GRPCServer) call(method string, body []byte) (protobuf.Message, error) {
+	if len(body) < 5 {
+		return nil, &grpcError{GRPC_INVALID_ARGUMENT, "truncated request"}
+	}
+	if body[0] != 0 {
+		return nil, &grpcError{GRPC_UNIMPLEMENTED, "compressed requests are not supported"}
+	}
+	length := int(body[1])<<24 | int(body[2])<<16 | int(body[3])<<8 | int(body[4])
+	if len(body)-5 != length {
+		return nil, &grpcError{GRPC_INVALID_ARGUMENT, "truncated request"}
+	}
+	fields, err := protobuf.Parse(body[5:])
+	if err != nil {
+		return nil, &grpcError{GRPC_INVALID_ARGUMENT, err.Error()}
+	}
+
+	switch method {
+	case "Lookup":
+		return server.lookup(fields)
+	case "References":
+		return server.references(fields)
+	case "Symbols":
+		return server.symbols(fields)
+	case "Diagnostics":
+		return server.diagnostics(fields)
+	}
+
+	return nil, &grpcError{GRPC_UNIMPLEMENTED, "unknown method " + method}
+}
+
+func (server *GRPCServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost || !strings.HasPrefix(request.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(writer, "expected a gRPC request", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var response protobuf.Message
+	body, err := ioutil.ReadAll(request.Body)
+	if err == nil {
+		if !strings.HasPrefix(request.URL.Path, GRPC_SERVICE) {
+			err = &grpcError{GRPC_UNIMPLEMENTED, "unknown service " + request.URL.Path}
+		} else {
+			response, err = server.call(strings.TrimPrefix(request.URL.Path, GRPC_SERVICE), body)
+		}
+	}
+
+	writer.Header().Set("Content-Type", "application/grpc+proto")
+	writer.WriteHeader(http.StatusOK)
+
+	status := GRPC_OK
+	if err != nil {
+		status = GRPC_INTERNAL
+		var callError *grpcError
+		if errors.As(err, &callError) {
+			status = callError.status
+		}
+		writer.Header().Set(http.TrailerPrefix+"Grpc-Message", err.Error())
+	} else {
+		length := len(response)
+		writer.Write([]byte{0, byte(length >> 24), byte(length >> 16), byte(length >> 8), byte(length)})
+		writer.Write(response)
+	}
+	writer.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(status))
+}
+
+// newGRPCHTTPServer returns the HTTP server of the gRPC service. gRPC clients
+// connect without TLS by default, which takes unencrypted HTTP/2 with prior
+// knowledge, supported by net/http since Go 1.24 (http.Protocols)
+func newGRPCHTTPServer(address string, server *GRPCServer) *http.Server {
+	protocols := &http.Protocols{}
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Server{Addr: address, Handler: server, Protocols: protocols}
+}
+
+// ListenGRPC serves the gRPC service on address with unencrypted HTTP/2
+func ListenGRPC(address string, server *GRPCServer) error {
+	return newGRPCHTTPServer(address, server).ListenAndServe()
+}
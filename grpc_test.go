@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/mauve/terraform-index/index"
+	"github.com/mauve/terraform-index/index/protobuf"
+)
+
+const grpcTestConfiguration = `variable "region" {
+  description = "where to deploy"
+}
+
+provider "aws" {
+  region = var.region
+}
+`
+
+// grpcClient speaks gRPC the way generated clients do: unencrypted HTTP/2
+// with prior knowledge, length prefixed messages and the status in trailers
+type grpcClient struct {
+	client *http.Client
+	url    string
+}
+
+func startGRPCServer(t *testing.T) *grpcClient {
+	indexed := index.NewIndex()
+	if err := indexed.CollectString([]byte(grpcTestConfiguration), "main.tf", false); err != nil {
+		t.Fatal(err)
+	}
+	indexed.Resolve()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newGRPCHTTPServer("", NewGRPCServer(indexed))
+	go server.Serve(listener)
+	t.Cleanup(func() {
+		server.Close()
+	})
+
+	protocols := &http.Protocols{}
+	protocols.SetUnencryptedHTTP2(true)
+	transport := &http.Transport{Protocols: protocols}
+	t.Cleanup(transport.CloseIdleConnections)
+	return &grpcClient{&http.Client{Transport: transport}, "http://" + listener.Addr().String()}
+}
+
+// call sends request to method and returns the response message and status
+func (client *grpcClient) call(t *testing.T, method string, request protobuf.Message) ([]protobuf.Field, int) {
+	length := len(request)
+	body := append([]byte{0, byte(length >> 24), byte(length >> 16), byte(length >> 8), byte(length)}, request...)
+	httpRequest, err := http.NewRequest(http.MethodPost, client.url+GRPC_SERVICE+method, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpRequest.Header.Set("Content-Type", "application/grpc")
+	httpRequest.Header.Set("TE", "trailers")
+
+	response, err := client.client.Do(httpRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if response.ProtoMajor != 2 {
+		t.Fatalf("expected HTTP/2, got %s", response.Proto)
+	}
+	if contentType := response.Header.Get("Content-Type"); contentType != "application/grpc+proto" {
+		t.Fatalf("unexpected content type %q", contentType)
+	}
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := strconv.Atoi(response.Trailer.Get("Grpc-Status"))
+	if err != nil {
+		t.Fatalf("invalid grpc-status trailer %q", response.Trailer.Get("Grpc-Status"))
+	}
+	if len(data) == 0 {
+		return nil, status
+	}
+
+	if len(data) < 5 || data[0] != 0 {
+		t.Fatalf("invalid response frame %v", data)
+	}
+	if length := int(data[1])<<24 | int(data[2])<<16 | int(data[3])<<8 | int(data[4]); length != len(data)-5 {
+		t.Fatalf("response frame holds %d bytes, expected %d", len(data)-5, length)
+	}
+	fields, err := protobuf.Parse(data[5:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fields, status
+}
+
+func TestGRPCLookup(t *testing.T) {
+	client := startGRPCServer(t)
+
+	fields, status := client.call(t, "Lookup", protobuf.Message{}.Text(1, "var.region"))
+	if status != GRPC_OK {
+		t.Fatalf("expected status %d, got %d", GRPC_OK, status)
+	}
+	declaration, err := messageField(fields, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address := textField(declaration, 1); address != "var.region" {
+		t.Errorf("expected var.region, got %q", address)
+	}
+	if description := textField(declaration, 5); description != "where to deploy" {
+		t.Errorf("expected the description, got %q", description)
+	}
+}
+
+func TestGRPCReferences(t *testing.T) {
+	client := startGRPCServer(t)
+
+	position := protobuf.Message{}.Text(1, "main.tf").Int(2, 1).Int(3, 11)
+	fields, status := client.call(t, "References", protobuf.Message{}.Bytes(2, position))
+	if status != GRPC_OK {
+		t.Fatalf("expected status %d, got %d", GRPC_OK, status)
+	}
+	locations := 0
+	for _, field := range fields {
+		if field.Number != 1 {
+			continue
+		}
+		location, err := protobuf.Parse(field.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line := intField(location, 2); line != 6 {
+			t.Errorf("expected a reference on line 6, got %d", line)
+		}
+		locations++
+	}
+	if locations != 1 {
+		t.Errorf("expected 1 reference, got %d", locations)
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	client := startGRPCServer(t)
+
+	if _, status := client.call(t, "Unknown", protobuf.Message{}); status != GRPC_UNIMPLEMENTED {
+		t.Errorf("expected status %d for an unknown method, got %d", GRPC_UNIMPLEMENTED, status)
+	}
+	position := protobuf.Message{}.Text(1, "main.tf").Int(2, 100).Int(3, 1)
+	if _, status := client.call(t, "Lookup", protobuf.Message{}.Bytes(2, position)); status != GRPC_NOT_FOUND {
+		t.Errorf("expected status %d without a symbol, got %d", GRPC_NOT_FOUND, status)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/mauve/terraform-index/index"
+)
+
+// HTTPServer answers queries about an index as JSON, using the types and
+// field names of the JSON output
+type HTTPServer struct {
+	index *index.Index
+	mux   *http.ServeMux
+}
+
+func NewHTTPServer(index *index.Index) *HTTPServer {
+	server := &HTTPServer{index: index, mux: http.NewServeMux()}
+	server.mux.HandleFunc("GET /symbols", server.symbols)
+	server.mux.HandleFunc("GET /definition", server.definition)
+	server.mux.HandleFunc("GET /references", server.references)
+	server.mux.HandleFunc("GET /diagnostics", server.diagnostics)
+	return server
+}
+
+func (server *HTTPServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	server.mux.ServeHTTP(writer, request)
+}
+
+func writeJSONResponse(writer http.ResponseWriter, status int, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(value)
+}
+
+func writeErrorResponse(writer http.ResponseWriter, status int, message string) {
+	writeJSONResponse(writer, status, map[string]string{"Error": message})
+}
+
+// symbols returns the symbols of the file parameter, or of every file
+func (server *HTTPServer) symbols(writer http.ResponseWriter, request *http.Request) {
+	files := server.index.Files()
+	if file := request.URL.Query().Get("file"); file != "" {
+		files = []string{file}
+	}
+
+	symbols := []index.Symbol{}
+	for _, file := range files {
+		symbols = append(symbols, server.index.SymbolsInFile(file)...)
+	}
+	writeJSONResponse(writer, http.StatusOK, symbols)
+}
+
+// definition returns the declaration of the symbol at the file, line and col
+// parameters
+func (server *HTTPServer) definition(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	line, lineErr := strconv.Atoi(query.Get("line"))
+	col, colErr := strconv.Atoi(query.Get("col"))
+	if query.Get("file") == "" || lineErr != nil || colErr != nil {
+		writeErrorResponse(writer, http.StatusBadRequest, "expected the file, line and col parameters")
+		return
+	}
+
+	hover, ok := server.index.HoverAt(query.Get("file"), line, col)
+	if !ok {
+		writeErrorResponse(writer, http.StatusNotFound, "no declaration at position")
+		return
+	}
+	writeJSONResponse(writer, http.StatusOK, hover)
+}
+
+// references returns the locations referring to the address in the name
+// parameter
+func (server *HTTPServer) references(writer http.ResponseWriter, request *http.Request) {
+	name := request.URL.Query().Get("name")
+	if name == "" {
+		writeErrorResponse(writer, http.StatusBadRequest, "expected the name parameter")
+		return
+	}
+
+	writeJSONResponse(writer, http.StatusOK, server.index.ReferencesToAddress(name))
+}
+
+// diagnostics returns the diagnostics of the file parameter, or of every
+// file
+func (server *HTTPServer) diagnostics(writer http.ResponseWriter, request *http.Request) {
+	file := request.URL.Query().Get("file")
+
+	diagnostics := []index.Diagnostic{}
+	for _, diagnostic := range server.index.Diagnostics {
+		if file == "" || diagnostic.Location.Filename == file {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	writeJSONResponse(writer, http.StatusOK, diagnostics)
+}
+
+func ListenHTTP(address string, server *HTTPServer) error {
+	return http.ListenAndServe(address, server)
+}
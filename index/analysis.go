@@ -0,0 +1,182 @@
+package index
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	hclast "github.com/hashicorp/hcl/hcl/ast"
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+type AnalysisOptions struct {
+	WarnUnused bool
+}
+
+// Analyze cross-checks the collected files and replaces Diagnostics with the
+// findings, it should be called once all files have been collected
+func (index *Index) Analyze(options AnalysisOptions) {
+	index.lock()
+	defer index.mutex.Unlock()
+
+	index.Diagnostics = append([]Diagnostic{}, index.collectedDiagnostics...)
+	index.checkUndefinedVariables()
+	index.checkDuplicateDeclarations()
+	index.checkDanglingOutputReferences()
+	index.checkCycles()
+	index.checkUndeclaredAssignments()
+	index.checkModuleInterfaces()
+	if options.WarnUnused {
+		index.checkUnusedVariables()
+		index.checkUnusedLocals()
+	}
+
+	for _, name := range index.sortedChildNames() {
+		index.Children[name].Analyze(options)
+	}
+}
+
+func (index *Index) sortedReferenceNames() []string {
+	names := make([]string, 0, len(index.References))
+	for name := range index.References {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (index *Index) checkUndefinedVariables() {
+	declared := map[string]bool{}
+	for _, variable := range index.Variables {
+		declared[variable.Address()] = true
+	}
+
+	for _, name := range index.sortedReferenceNames() {
+		list := index.References[name]
+		if list.Kind != REFERENCE_KIND_VARIABLE || declared[name] {
+			continue
+		}
+
+		for _, location := range list.Locations {
+			diagnostic := index.addDiagnostic(SEVERITY_ERROR, CODE_UNDEFINED_VARIABLE, location,
+				"Reference to undeclared input variable '%s'", name)
+			diagnostic.EndLocation = endPos(location, name)
+		}
+	}
+}
+
+func (index *Index) isReferenced(address string) bool {
+	list, ok := index.References[address]
+	return ok && len(list.Locations) > 0
+}
+
+func (index *Index) UnusedVariables() []VariableDeclaration {
+	unused := []VariableDeclaration{}
+	for _, variable := range index.Variables {
+		if !index.isReferenced(variable.Address()) {
+			unused = append(unused, variable)
+		}
+	}
+
+	return unused
+}
+
+func (index *Index) checkUnusedVariables() {
+	for _, variable := range index.UnusedVariables() {
+		index.addDiagnostic(SEVERITY_WARNING, CODE_UNUSED_VARIABLE, variable.Location, "Input variable '%s' is declared but never used", variable.Name)
+	}
+}
+
+func (index *Index) UnusedLocals() []LocalDeclaration {
+	unused := []LocalDeclaration{}
+	for _, local := range index.Locals {
+		if !index.isReferenced(local.Address()) {
+			unused = append(unused, local)
+		}
+	}
+
+	return unused
+}
+
+func (index *Index) checkUnusedLocals() {
+	for _, local := range index.UnusedLocals() {
+		index.addDiagnostic(SEVERITY_WARNING, CODE_UNUSED_LOCAL, local.Location, "Local value '%s' is declared but never used", local.Name)
+	}
+}
+
+func (index *Index) checkDuplicateDeclarations() {
+	first := map[string]hcltoken.Pos{}
+	for _, declared := range index.declaredAddresses() {
+		previous, exists := first[declared.Address]
+		if !exists {
+			first[declared.Address] = declared.Location
+			continue
+		}
+
+		diagnostic := index.addDiagnostic(SEVERITY_ERROR, CODE_DUPLICATE_DECLARATION, declared.Location,
+			"Duplicate declaration of '%s', previously declared at %s", declared.Address, previous)
+		diagnostic.RelatedLocations = append(diagnostic.RelatedLocations, previous)
+	}
+}
+
+func (index *Index) checkDanglingOutputReferences() {
+	declarations := index.Declarations()
+	for _, output := range index.Outputs {
+		for _, reference := range output.valueReferences {
+			switch getReferenceKind(reference.Name) {
+			case REFERENCE_KIND_RESOURCE, REFERENCE_KIND_DATA:
+				if _, ok := declarations[reference.Name]; !ok {
+					diagnostic := index.addDiagnostic(SEVERITY_ERROR, CODE_DANGLING_REFERENCE, reference.Location,
+						"Output '%s' has a dangling reference to '%s', which is not declared", output.Name, reference.Name)
+					diagnostic.EndLocation = endPos(reference.Location, reference.Name)
+				}
+			}
+		}
+	}
+}
+
+// getInterpolationOnly returns the expression of a string which consists of
+// exactly one interpolation, like "${var.foo}"
+func getInterpolationOnly(text string) (string, bool) {
+	if !strings.HasPrefix(text, "${") || !strings.HasSuffix(text, "}") {
+		return "", false
+	}
+
+	depth := 0
+	for offset, char := range text {
+		switch char {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 && offset != len(text)-1 {
+				return "", false
+			}
+		}
+	}
+
+	return strings.TrimSpace(text[2 : len(text)-1]), depth == 0
+}
+
+func (index *Index) checkInterpolationOnly(item *hclast.ObjectItem, path string) {
+	literal, ok := item.Val.(*hclast.LiteralType)
+	if !ok || literal.Token.Type != hcltoken.STRING {
+		return
+	}
+
+	expression, ok := getInterpolationOnly(getText(literal.Token))
+	if !ok {
+		return
+	}
+
+	location := getPos(literal.Token, path)
+	index.addCollectedDiagnostic(Diagnostic{
+		Severity:         SEVERITY_INFO,
+		Code:             CODE_INTERPOLATION_ONLY,
+		Message:          fmt.Sprintf("Interpolation-only expressions are deprecated, use %s instead", expression),
+		Location:         location,
+		EndLocation:      endPos(location, literal.Token.Text),
+		RelatedLocations: []hcltoken.Pos{},
+		Suggestion:       expression,
+	})
+}
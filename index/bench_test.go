@@ -0,0 +1,143 @@
+package index
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+)
+
+const BENCH_DIRECTORY = "../testdata/bench"
+
+// benchFiles reads the files of the benchmark configuration, keyed by path
+func benchFiles(b *testing.B) map[string][]byte {
+	patterns := []string{"*.tf", "*.tf.json", "*.tfvars"}
+	files := map[string][]byte{}
+	for _, pattern := range patterns {
+		paths, err := filepath.Glob(filepath.Join(BENCH_DIRECTORY, pattern))
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, path := range paths {
+			contents, err := ioutil.ReadFile(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			files[path] = contents
+		}
+	}
+	if len(files) == 0 {
+		b.Fatalf("no files in %s", BENCH_DIRECTORY)
+	}
+	return files
+}
+
+// collectBench collects the files into a new index
+func collectBench(b *testing.B, files map[string][]byte) *Index {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	index := NewIndex()
+	for _, path := range paths {
+		if err := index.CollectString(files[path], path, false); err != nil {
+			b.Fatalf("cannot collect %s: %s", path, err)
+		}
+	}
+	return index
+}
+
+// benchIndex returns the resolved and analyzed index of the configuration
+func benchIndex(b *testing.B) *Index {
+	index := collectBench(b, benchFiles(b))
+	index.Resolve()
+	index.Analyze(AnalysisOptions{})
+	return index
+}
+
+func BenchmarkCollect(b *testing.B) {
+	files := benchFiles(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		collectBench(b, files)
+	}
+}
+
+func BenchmarkResolve(b *testing.B) {
+	index := collectBench(b, benchFiles(b))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.Resolve()
+	}
+}
+
+func BenchmarkNamesWithPrefix(b *testing.B) {
+	index := benchIndex(b)
+	if len(index.NamesWithPrefix("var.")) == 0 {
+		b.Fatal("no variables found")
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.NamesWithPrefix("var.")
+	}
+}
+
+func BenchmarkCompletionsAt(b *testing.B) {
+	index := benchIndex(b)
+	location := index.References["var.region"].Locations[0]
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.CompletionsAt(location.Filename, location.Line, location.Column, "var.")
+	}
+}
+
+func BenchmarkReferencesAt(b *testing.B) {
+	index := benchIndex(b)
+	location := index.References["var.region"].Locations[0]
+	if len(index.ReferencesAt(location.Filename, location.Line, location.Column)) < 2 {
+		b.Fatal("no references found")
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.ReferencesAt(location.Filename, location.Line, location.Column)
+	}
+}
+
+// heapBytes returns the bytes allocated on the heap which are still alive
+func heapBytes() uint64 {
+	runtime.GC()
+	stats := runtime.MemStats{}
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// BenchmarkCompact times Compact and reports the bytes the resolved index
+// keeps alive before and after it
+func BenchmarkCompact(b *testing.B) {
+	files := benchFiles(b)
+	base := heapBytes()
+	index := collectBench(b, files)
+	index.Resolve()
+	index.Analyze(AnalysisOptions{})
+	before := heapBytes() - base
+	index.Compact()
+	after := heapBytes() - base
+	runtime.KeepAlive(index)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.Compact()
+	}
+	// reported last, ResetTimer drops the metrics reported before it
+	b.ReportMetric(float64(before), "bytes-before")
+	b.ReportMetric(float64(after), "bytes-after")
+}
@@ -0,0 +1,193 @@
+package index
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// exportedIndex has the fields of Index without its methods, so encoding it
+// does not recurse into MarshalBinary
+type exportedIndex Index
+
+// binaryIndex is the gob encoded form of an index, the unexported state
+// editors and the analysis rely on is carried along while RawAst is left out
+type binaryIndex struct {
+	Index                *exportedIndex
+	CollectedDiagnostics []Diagnostic
+	Dependencies         map[string][]reference
+	Tokens               map[string][]SemanticToken
+	Links                map[string][]DocumentLink
+	Folds                map[string][]FoldingRange
+	OutputReferences     [][]reference
+	NullableSet          []bool
+	Stamps               map[string]FileStamp
+}
+
+// binary returns the index in its gob encoded form
+func (index *Index) binary() binaryIndex {
+	exported := exportedIndex(*index)
+	exported.RawAst = nil
+
+	outputReferences := [][]reference{}
+	for _, output := range index.Outputs {
+		outputReferences = append(outputReferences, output.valueReferences)
+	}
+	nullableSet := []bool{}
+	for _, variable := range index.Variables {
+		nullableSet = append(nullableSet, variable.nullableSet)
+	}
+
+	return binaryIndex{
+		Index:                &exported,
+		CollectedDiagnostics: index.collectedDiagnostics,
+		Dependencies:         index.dependencies,
+		Tokens:               index.tokens,
+		Links:                index.links,
+		Folds:                index.folds,
+		OutputReferences:     outputReferences,
+		NullableSet:          nullableSet,
+		Stamps:               index.stamps,
+	}
+}
+
+// restore returns the decoded index with its unexported state
+func (decoded *binaryIndex) restore() *Index {
+	if decoded.Index == nil {
+		decoded.Index = (*exportedIndex)(NewIndex())
+	}
+	emptySlices(reflect.ValueOf(decoded).Elem())
+
+	index := (*Index)(decoded.Index)
+	if index.mutex == nil {
+		index.mutex = &sync.Mutex{}
+	}
+	index.collectedDiagnostics = decoded.CollectedDiagnostics
+	index.dependencies = decoded.Dependencies
+	index.pendingOverrides = []*Index{}
+	index.tokens = decoded.Tokens
+	index.links = decoded.Links
+	index.folds = decoded.Folds
+	index.stamps = decoded.Stamps
+	for i, references := range decoded.OutputReferences {
+		if i < len(index.Outputs) {
+			index.Outputs[i].valueReferences = references
+		}
+	}
+	for i, set := range decoded.NullableSet {
+		if i < len(index.Variables) {
+			index.Variables[i].nullableSet = set
+		}
+	}
+	return index
+}
+
+// MarshalBinary encodes the index with gob, which is far smaller and faster
+// to load than the JSON output. The raw AST is not encoded
+func (index *Index) MarshalBinary() ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	err := gob.NewEncoder(buffer).Encode(index.binary())
+	return buffer.Bytes(), err
+}
+
+// UnmarshalBinary decodes an index encoded by MarshalBinary
+func (index *Index) UnmarshalBinary(data []byte) error {
+	mutex := index.mutex
+	*index = *NewIndex()
+	if mutex != nil {
+		index.mutex = mutex
+	}
+	decoded := binaryIndex{Index: (*exportedIndex)(index)}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&decoded); err != nil {
+		return err
+	}
+
+	decoded.restore()
+	index.compact(map[string]string{})
+	return nil
+}
+
+// emptySlices replaces the nil slices and maps below value with empty ones,
+// gob does not encode empty slices and the collectors never leave them nil
+func emptySlices(value reflect.Value) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		{
+			if !value.IsNil() {
+				emptySlices(value.Elem())
+			}
+			break
+		}
+	case reflect.Interface:
+		{
+			if !value.IsNil() && value.Elem().Kind() == reflect.Ptr {
+				emptySlices(value.Elem())
+			}
+			break
+		}
+	case reflect.Struct:
+		{
+			for i := 0; i < value.NumField(); i++ {
+				if field := value.Field(i); field.CanSet() {
+					emptySlices(field)
+				}
+			}
+			break
+		}
+	case reflect.Slice:
+		{
+			if value.IsNil() {
+				value.Set(reflect.MakeSlice(value.Type(), 0, 0))
+			}
+			for i := 0; i < value.Len(); i++ {
+				emptySlices(value.Index(i))
+			}
+			break
+		}
+	case reflect.Map:
+		{
+			if value.IsNil() {
+				value.Set(reflect.MakeMap(value.Type()))
+			}
+			for _, key := range value.MapKeys() {
+				item := reflect.New(value.Type().Elem()).Elem()
+				item.Set(value.MapIndex(key))
+				emptySlices(item)
+				value.SetMapIndex(key, item)
+			}
+			break
+		}
+	}
+}
+
+// WriteBinary writes the indexes as a gob stream of MarshalBinary encoded
+// indexes, ReadBinary reads them back
+func WriteBinary(writer io.Writer, indexes ...*Index) error {
+	encoder := gob.NewEncoder(writer)
+	for _, index := range indexes {
+		if err := encoder.Encode(index); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadBinary reads the indexes written by WriteBinary
+func ReadBinary(reader io.Reader) ([]*Index, error) {
+	indexes := []*Index{}
+	decoder := gob.NewDecoder(reader)
+	for {
+		index := NewIndex()
+		err := decoder.Decode(index)
+		if err == io.EOF {
+			return indexes, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, index)
+	}
+}
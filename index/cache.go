@@ -0,0 +1,330 @@
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+// cacheFile is the file of a cache directory holding the collected files
+const cacheFile = "files"
+
+// recentModTime is how recently a file has to be modified for its stamp not
+// to be trusted, it might change again without changing its stamp
+const recentModTime = 2 * time.Second
+
+// FileCache holds the index of every file collected through it keyed by the
+// SHA-256 of its path and contents, positions include the path so files
+// with the same contents are collected once per path. The latest contents
+// of every path are kept in memory, with a directory they are saved there
+// and loaded by later processes. Files collected from disk are stamped, so
+// they are neither read nor hashed again until their modification time or
+// size changes
+type FileCache struct {
+	directory string
+	entries   map[string]*cacheEntry
+	hashes    map[string]string
+	stamps    map[string]FileStamp
+	changed   bool
+}
+
+// cacheEntry is a collected file along with the error its collection failed
+// with, which is reported again whenever the entry is used
+type cacheEntry struct {
+	Error string
+	Index *Index
+}
+
+// cacheHeader starts the cache file, it is followed by Count cacheRecords in
+// the same gob stream so the types are only described once
+type cacheHeader struct {
+	Version string
+	Stamps  map[string]FileStamp
+	Hashes  map[string]string
+	Count   int
+}
+
+type cacheRecord struct {
+	Hash  string
+	Error string
+	Index binaryIndex
+}
+
+// NewFileCache creates a cache kept in directory, or only in memory when
+// directory is empty. A cache which cannot be loaded or was saved by another
+// version starts empty
+func NewFileCache(directory string) *FileCache {
+	cache := &FileCache{
+		directory: directory,
+		entries:   map[string]*cacheEntry{},
+		hashes:    map[string]string{},
+		stamps:    map[string]FileStamp{},
+	}
+	if directory != "" {
+		cache.load()
+	}
+	return cache
+}
+
+func (cache *FileCache) load() {
+	file, err := os.Open(filepath.Join(cache.directory, cacheFile))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	decoder := gob.NewDecoder(file)
+	header := cacheHeader{}
+	if err := decoder.Decode(&header); err != nil || header.Version != INDEX_VERSION {
+		return
+	}
+
+	entries := map[string]*cacheEntry{}
+	for i := 0; i < header.Count; i++ {
+		record := cacheRecord{}
+		if err := decoder.Decode(&record); err != nil {
+			return
+		}
+		entries[record.Hash] = &cacheEntry{record.Error, record.Index.restore()}
+	}
+
+	for path, hash := range header.Hashes {
+		if _, ok := entries[hash]; ok {
+			cache.hashes[path] = hash
+			if stamp, ok := header.Stamps[path]; ok {
+				cache.stamps[path] = stamp
+			}
+		}
+	}
+	cache.entries = entries
+}
+
+// Save writes the cache to its directory, unless nothing was collected since
+// it was loaded or last saved
+func (cache *FileCache) Save() error {
+	if cache.directory == "" || !cache.changed {
+		return nil
+	}
+
+	if err := os.MkdirAll(cache.directory, 0755); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(cache.directory, cacheFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	encoder := gob.NewEncoder(file)
+	err = encoder.Encode(cacheHeader{INDEX_VERSION, cache.stamps, cache.hashes, len(cache.entries)})
+	for hash, entry := range cache.entries {
+		if err == nil {
+			err = encoder.Encode(cacheRecord{hash, entry.Error, entry.Index.binary()})
+		}
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), filepath.Join(cache.directory, cacheFile))
+	}
+	if err == nil {
+		cache.changed = false
+	}
+	return err
+}
+
+// ContentHash returns the key of a file in a FileCache
+func ContentHash(contents []byte, path string) string {
+	hash := sha256.New()
+	hash.Write([]byte(path))
+	hash.Write([]byte{0})
+	hash.Write(contents)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Collect returns the index of a file collected on its own like
+// CollectFile, from the cache when the file was collected before. The raw
+// AST is not cached, so files collected with includeRaw are always parsed
+func (cache *FileCache) Collect(contents []byte, path string, includeRaw bool) (*Index, error) {
+	if includeRaw {
+		return CollectFile(contents, path, includeRaw)
+	}
+
+	hash := ContentHash(contents, path)
+	entry, ok := cache.entries[hash]
+	if !ok {
+		entry = &cacheEntry{}
+		var err error
+		if entry.Index, err = CollectFile(contents, path, false); err != nil {
+			entry.Error = err.Error()
+		}
+		cache.changed = true
+	}
+
+	if previous, ok := cache.hashes[path]; ok && previous != hash {
+		delete(cache.entries, previous)
+		delete(cache.stamps, path)
+	}
+	cache.hashes[path] = hash
+	cache.entries[hash] = entry
+	return entry.result()
+}
+
+func (entry *cacheEntry) result() (*Index, error) {
+	if entry.Error != "" {
+		return entry.Index, errors.New(entry.Error)
+	}
+	return entry.Index, nil
+}
+
+// CollectPath returns the index of the file at path like Collect. The file
+// is only read and hashed when its stamp changed since it was last
+// collected, or was modified too recently for the stamp to be trusted
+func (cache *FileCache) CollectPath(path string, includeRaw bool) (*Index, error) {
+	stamp, err := StampFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if known, ok := cache.stamps[path]; ok && known == stamp && !includeRaw {
+		if entry, ok := cache.entries[cache.hashes[path]]; ok {
+			return entry.result()
+		}
+	}
+
+	contents, err := ReadFile(path)
+	if tooLarge, ok := err.(*FileTooLargeError); ok {
+		skipped := NewIndex()
+		skipped.skipFile(tooLarge)
+		return skipped, err
+	} else if err != nil {
+		return nil, err
+	}
+	index, err := cache.Collect(contents, path, includeRaw)
+	if includeRaw {
+		// the contents were not hashed, so the stamp does not tell the cached
+		// entry of path is still current
+		return index, err
+	}
+	if time.Since(time.Unix(0, stamp.ModTime)) > recentModTime && cache.stamps[path] != stamp {
+		cache.stamps[path] = stamp
+		cache.changed = true
+	}
+	return index, err
+}
+
+// CollectCached collects a file like CollectString, the file is only parsed
+// when the cache has no result for its contents. Without a cache it is
+// CollectString
+func (index *Index) CollectCached(cache *FileCache, contents []byte, path string, includeRaw bool) error {
+	index.lock()
+	defer index.mutex.Unlock()
+	if cache == nil {
+		return index.addString(contents, path, includeRaw)
+	}
+
+	file, err := cache.Collect(contents, path, includeRaw)
+	if isOverrideFile(path) {
+		index.pendingOverrides = append(index.pendingOverrides, file)
+	} else {
+		index.merge(file)
+	}
+
+	return err
+}
+
+// CollectPathCached collects the file at path like CollectCached, the file
+// is not even read when its stamp did not change since the cache collected
+// it. Without a cache the file is read and collected with CollectString.
+// Errors reading the file are *os.PathError and leave the index unchanged,
+// parse errors and files larger than MaxFileSize are recorded as diagnostics
+// like CollectString does
+func (index *Index) CollectPathCached(cache *FileCache, path string, includeRaw bool) error {
+	index.lock()
+	defer index.mutex.Unlock()
+	if cache == nil {
+		contents, err := ReadFile(path)
+		if tooLarge, ok := err.(*FileTooLargeError); ok {
+			index.skipFile(tooLarge)
+			return err
+		} else if err != nil {
+			return err
+		}
+		return index.addString(contents, path, includeRaw)
+	}
+
+	file, err := cache.CollectPath(path, includeRaw)
+	if file == nil {
+		return err
+	}
+	if isOverrideFile(path) {
+		index.pendingOverrides = append(index.pendingOverrides, file)
+	} else {
+		index.merge(file)
+	}
+
+	return err
+}
+
+// merge adds everything collected in file to the index. The lists the index
+// appends to are copied, so file may be merged into several indexes
+func (index *Index) merge(file *Index) {
+	index.Variables = append(index.Variables, file.Variables...)
+	index.Resources = append(index.Resources, file.Resources...)
+	index.Data = append(index.Data, file.Data...)
+	index.Modules = append(index.Modules, file.Modules...)
+	index.Outputs = append(index.Outputs, file.Outputs...)
+	index.Locals = append(index.Locals, file.Locals...)
+	index.Settings = append(index.Settings, file.Settings...)
+	index.Imports = append(index.Imports, file.Imports...)
+	index.Checks = append(index.Checks, file.Checks...)
+	index.Removed = append(index.Removed, file.Removed...)
+	index.Assignments = append(index.Assignments, file.Assignments...)
+	index.Overrides = append(index.Overrides, file.Overrides...)
+	index.Diagnostics = append(index.Diagnostics, file.collectedDiagnostics...)
+	index.collectedDiagnostics = append(index.collectedDiagnostics, file.collectedDiagnostics...)
+
+	for name, list := range file.References {
+		existing, ok := index.References[name]
+		if !ok {
+			existing = list
+			existing.Locations = []hcltoken.Pos{}
+		}
+		existing.Locations = append(existing.Locations, list.Locations...)
+		index.References[name] = existing
+	}
+	for name, list := range file.FunctionCalls {
+		existing, ok := index.FunctionCalls[name]
+		if !ok {
+			existing = list
+			existing.Locations = []hcltoken.Pos{}
+		}
+		existing.Locations = append(existing.Locations, list.Locations...)
+		index.FunctionCalls[name] = existing
+	}
+	for address, references := range file.dependencies {
+		existing, ok := index.dependencies[address]
+		if !ok {
+			existing = []reference{}
+		}
+		index.dependencies[address] = append(existing, references...)
+	}
+
+	for path, tokens := range file.tokens {
+		index.tokens[path] = tokens
+	}
+	for path, links := range file.links {
+		index.links[path] = links
+	}
+	for path, folds := range file.folds {
+		index.folds[path] = folds
+	}
+}
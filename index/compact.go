@@ -0,0 +1,101 @@
+package index
+
+import (
+	"reflect"
+)
+
+// Compact shrinks the memory the index holds on to. Equal strings, above
+// all the filename of every position, are made to share one copy and the
+// lists are cut to their length. Positions are not packed into tables, they
+// stay hcltoken.Pos since every declaration exports them. Indexes loaded
+// from binary data are compacted already, collected ones are best compacted
+// once analyzed
+func (index *Index) Compact() {
+	index.lock()
+	defer index.mutex.Unlock()
+	index.compact(map[string]string{})
+}
+
+// compact compacts the index and its children with the strings interned so
+// far, the lists and maps are copied so snapshots sharing them stay intact
+func (index *Index) compact(strings map[string]string) {
+	compactValue(reflect.ValueOf(index).Elem(), strings)
+	compactValue(reflect.ValueOf(&index.collectedDiagnostics).Elem(), strings)
+	compactValue(reflect.ValueOf(&index.dependencies).Elem(), strings)
+	compactValue(reflect.ValueOf(&index.tokens).Elem(), strings)
+	compactValue(reflect.ValueOf(&index.links).Elem(), strings)
+	compactValue(reflect.ValueOf(&index.folds).Elem(), strings)
+	for i := range index.Outputs {
+		compactValue(reflect.ValueOf(&index.Outputs[i].valueReferences).Elem(), strings)
+	}
+
+	for _, child := range index.Children {
+		child.lock()
+		child.compact(strings)
+		child.mutex.Unlock()
+	}
+}
+
+// compactValue interns the strings below value and replaces its slices and
+// maps with copies of their exact size. Pointers are not followed, the raw
+// AST is left as it is and the children are compacted on their own
+func compactValue(value reflect.Value, strings map[string]string) {
+	switch value.Kind() {
+	case reflect.String:
+		{
+			value.SetString(intern(value.String(), strings))
+			break
+		}
+	case reflect.Struct:
+		{
+			for i := 0; i < value.NumField(); i++ {
+				if field := value.Field(i); field.CanSet() {
+					compactValue(field, strings)
+				}
+			}
+			break
+		}
+	case reflect.Slice:
+		{
+			if value.IsNil() {
+				break
+			}
+			compacted := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+			reflect.Copy(compacted, value)
+			for i := 0; i < compacted.Len(); i++ {
+				compactValue(compacted.Index(i), strings)
+			}
+			value.Set(compacted)
+			break
+		}
+	case reflect.Map:
+		{
+			if value.IsNil() {
+				break
+			}
+			compacted := reflect.MakeMapWithSize(value.Type(), value.Len())
+			for _, key := range value.MapKeys() {
+				item := reflect.New(value.Type().Elem()).Elem()
+				item.Set(value.MapIndex(key))
+				compactValue(item, strings)
+				if key.Kind() == reflect.String {
+					key = reflect.ValueOf(intern(key.String(), strings)).Convert(key.Type())
+				}
+				compacted.SetMapIndex(key, item)
+			}
+			value.Set(compacted)
+			break
+		}
+	}
+}
+
+// intern returns the copy of text kept in strings, keeping text when it is
+// the first one
+func intern(text string, strings map[string]string) string {
+	if interned, ok := strings[text]; ok {
+		return interned
+	}
+
+	strings[text] = text
+	return text
+}
@@ -0,0 +1,37 @@
+package index
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// WriteCSV writes one row of kind, type, name, file, line and column for
+// every declaration of the indexes after a header row. Fields are separated
+// by comma, use '\t' for tab separated values
+func WriteCSV(writer io.Writer, comma rune, indexes ...*Index) error {
+	records := csv.NewWriter(writer)
+	records.Comma = comma
+	if err := records.Write([]string{"kind", "type", "name", "file", "line", "column"}); err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		for _, tag := range index.tags() {
+			err := records.Write([]string{
+				tag.Kind,
+				tag.Type,
+				tag.Name,
+				tag.Location.Filename,
+				strconv.Itoa(tag.Location.Line),
+				strconv.Itoa(tag.Location.Column),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	records.Flush()
+	return records.Error()
+}
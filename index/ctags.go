@@ -0,0 +1,70 @@
+package index
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+// tag is a declaration as listed by the ctags, etags and csv writers, Name is
+// the bare name editors look up from the word under the cursor and Type the
+// type of resources and data sources
+type tag struct {
+	Name     string
+	Kind     string
+	Location hcltoken.Pos
+	Type     string
+}
+
+func (index *Index) tags() []tag {
+	tags := []tag{}
+	for _, variable := range index.Variables {
+		tags = append(tags, tag{variable.Name, SYMBOL_VARIABLE, variable.Location, ""})
+	}
+	for _, local := range index.Locals {
+		tags = append(tags, tag{local.Name, SYMBOL_LOCAL, local.Location, ""})
+	}
+	for _, resource := range index.Resources {
+		tags = append(tags, tag{resource.Name, SYMBOL_RESOURCE, resource.Location, resource.Type})
+	}
+	for _, data := range index.Data {
+		tags = append(tags, tag{data.Name, SYMBOL_DATA, data.Location, data.Type})
+	}
+	for _, module := range index.Modules {
+		tags = append(tags, tag{module.Name, SYMBOL_MODULE, module.Location, ""})
+	}
+	for _, output := range index.Outputs {
+		tags = append(tags, tag{output.Name, SYMBOL_OUTPUT, output.Location, ""})
+	}
+
+	return tags
+}
+
+// WriteCtags writes the declarations of the indexes as a sorted tags file in
+// the extended format of universal-ctags, addressed by line number
+func WriteCtags(writer io.Writer, indexes ...*Index) error {
+	lines := []string{}
+	for _, index := range indexes {
+		for _, tag := range index.tags() {
+			lines = append(lines, fmt.Sprintf("%s\t%s\t%d;\"\tkind:%s\tline:%d",
+				tag.Name, tag.Location.Filename, tag.Location.Line, tag.Kind, tag.Location.Line))
+		}
+	}
+	sort.Strings(lines)
+
+	header := []string{
+		"!_TAG_FILE_FORMAT\t2\t/extended format; --format=1 will not append ;\" to lines/",
+		"!_TAG_FILE_SORTED\t1\t/0=unsorted, 1=sorted, 2=foldcase/",
+		"!_TAG_PROGRAM_NAME\tterraform-index\t//",
+		"!_TAG_PROGRAM_VERSION\t" + INDEX_VERSION + "\t//",
+	}
+	for _, line := range append(header, lines...) {
+		if _, err := fmt.Fprintln(writer, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package index
+
+import (
+	"sort"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+// findCycles returns the strongly connected components of the declaration
+// dependency graph which form a cycle, each sorted by address
+func (index *Index) findCycles(declarations map[string]hcltoken.Pos) [][]string {
+	addresses := make([]string, 0, len(declarations))
+	for address := range declarations {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	// Tarjan's strongly connected components algorithm
+	counter := 0
+	order := map[string]int{}
+	lowlink := map[string]int{}
+	onStack := map[string]bool{}
+	stack := []string{}
+	cycles := [][]string{}
+
+	var visit func(address string)
+	visit = func(address string) {
+		order[address] = counter
+		lowlink[address] = counter
+		counter++
+		stack = append(stack, address)
+		onStack[address] = true
+
+		selfReference := false
+		for _, dependency := range index.dependencies[address] {
+			if _, declared := declarations[dependency.Name]; !declared {
+				continue
+			}
+
+			if dependency.Name == address {
+				selfReference = true
+			}
+
+			if _, visited := order[dependency.Name]; !visited {
+				visit(dependency.Name)
+				if lowlink[dependency.Name] < lowlink[address] {
+					lowlink[address] = lowlink[dependency.Name]
+				}
+			} else if onStack[dependency.Name] && order[dependency.Name] < lowlink[address] {
+				lowlink[address] = order[dependency.Name]
+			}
+		}
+
+		if lowlink[address] != order[address] {
+			return
+		}
+
+		component := []string{}
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == address {
+				break
+			}
+		}
+
+		if len(component) > 1 || selfReference {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, address := range addresses {
+		if _, visited := order[address]; !visited {
+			visit(address)
+		}
+	}
+
+	return cycles
+}
+
+func (index *Index) checkCycles() {
+	declarations := index.Declarations()
+	for _, cycle := range index.findCycles(declarations) {
+		members := map[string]bool{}
+		for _, address := range cycle {
+			members[address] = true
+		}
+
+		diagnostic := index.addDiagnostic(SEVERITY_ERROR, CODE_DEPENDENCY_CYCLE, declarations[cycle[0]],
+			"Dependency cycle between %s", strings.Join(cycle, ", "))
+		for _, address := range cycle {
+			if address != cycle[0] {
+				diagnostic.RelatedLocations = append(diagnostic.RelatedLocations, declarations[address])
+			}
+			for _, dependency := range index.dependencies[address] {
+				if members[dependency.Name] {
+					diagnostic.RelatedLocations = append(diagnostic.RelatedLocations, dependency.Location)
+				}
+			}
+		}
+	}
+}
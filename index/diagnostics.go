@@ -0,0 +1,85 @@
+package index
+
+import (
+	"fmt"
+
+	hclparser "github.com/hashicorp/hcl/hcl/parser"
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+const (
+	SEVERITY_ERROR   = "error"
+	SEVERITY_WARNING = "warning"
+	SEVERITY_INFO    = "info"
+)
+
+const (
+	CODE_PARSE_ERROR              = "parse-error"
+	CODE_INTERPOLATION_ERROR      = "interpolation-error"
+	CODE_INTERPOLATION_ONLY       = "interpolation-only"
+	CODE_UNDEFINED_VARIABLE       = "undefined-variable"
+	CODE_UNUSED_VARIABLE          = "unused-variable"
+	CODE_UNUSED_LOCAL             = "unused-local"
+	CODE_DUPLICATE_DECLARATION    = "duplicate-declaration"
+	CODE_DANGLING_REFERENCE       = "dangling-reference"
+	CODE_DEPENDENCY_CYCLE         = "dependency-cycle"
+	CODE_UNDECLARED_ASSIGNMENT    = "undeclared-assignment"
+	CODE_MISSING_OVERRIDE_BASE    = "missing-override-base"
+	CODE_MODULE_NOT_FOUND         = "module-not-found"
+	CODE_UNDECLARED_MODULE_INPUT  = "undeclared-module-input"
+	CODE_UNDECLARED_MODULE_OUTPUT = "undeclared-module-output"
+	CODE_MISSING_MODULE_INPUT     = "missing-module-input"
+	CODE_FILE_TOO_LARGE           = "file-too-large"
+)
+
+type Diagnostic struct {
+	Severity         string
+	Code             string
+	Message          string
+	Location         hcltoken.Pos
+	EndLocation      hcltoken.Pos
+	RelatedLocations []hcltoken.Pos
+	Suggestion       string
+}
+
+func makeDiagnostic(err error, path string) Diagnostic {
+	diagnostic := Diagnostic{
+		Severity:         SEVERITY_ERROR,
+		Code:             CODE_PARSE_ERROR,
+		Message:          err.Error(),
+		RelatedLocations: []hcltoken.Pos{},
+	}
+
+	if posError, ok := err.(*hclparser.PosError); ok {
+		diagnostic.Message = posError.Err.Error()
+		diagnostic.Location = posError.Pos
+		diagnostic.Location.Filename = path
+	}
+
+	diagnostic.EndLocation = diagnostic.Location
+	return diagnostic
+}
+
+// endPos returns the position just past text when it starts at location
+func endPos(location hcltoken.Pos, text string) hcltoken.Pos {
+	return literalSubPos(text, location, len(text), location.Filename)
+}
+
+// addCollectedDiagnostic records a diagnostic found while collecting a file,
+// unlike the analysis diagnostics these survive repeated calls to Analyze
+func (index *Index) addCollectedDiagnostic(diagnostic Diagnostic) {
+	index.collectedDiagnostics = append(index.collectedDiagnostics, diagnostic)
+	index.Diagnostics = append(index.Diagnostics, diagnostic)
+}
+
+func (index *Index) addDiagnostic(severity string, code string, location hcltoken.Pos, format string, args ...interface{}) *Diagnostic {
+	index.Diagnostics = append(index.Diagnostics, Diagnostic{
+		Severity:         severity,
+		Code:             code,
+		Message:          fmt.Sprintf(format, args...),
+		Location:         location,
+		EndLocation:      location,
+		RelatedLocations: []hcltoken.Pos{},
+	})
+	return &index.Diagnostics[len(index.Diagnostics)-1]
+}
@@ -0,0 +1,122 @@
+package index
+
+import (
+	"sort"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+// DeclarationChange is a declaration which was added, removed or moved to
+// another file. Location is where it is declared, or was for removed ones,
+// Previous is where a moved declaration was before
+type DeclarationChange struct {
+	Address  string
+	Kind     string
+	Location hcltoken.Pos
+	Previous hcltoken.Pos
+}
+
+// ReferenceChange is a name which is referenced a different number of times,
+// Before is zero for names which were not referenced and After for those
+// which are no longer
+type ReferenceChange struct {
+	Name   string
+	Before int
+	After  int
+}
+
+// IndexDiff holds the differences between two indexes of a configuration.
+// Declarations are matched by address, a declaration is only moved when its
+// file changed since lines shift with every edit
+type IndexDiff struct {
+	Added      []DeclarationChange
+	Removed    []DeclarationChange
+	Moved      []DeclarationChange
+	References []ReferenceChange
+}
+
+// Empty tells whether the indexes hold the same declarations and references
+func (diff IndexDiff) Empty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Moved) == 0 && len(diff.References) == 0
+}
+
+// declarationsByAddress groups the declarations of index by their address
+func declarationsByAddress(index *Index) map[string][]Declaration {
+	declarations := map[string][]Declaration{}
+	for _, declaration := range index.declarations() {
+		address := declaration.Address()
+		declarations[address] = append(declarations[address], declaration)
+	}
+	return declarations
+}
+
+func newDeclarationChange(address string, declaration Declaration) DeclarationChange {
+	return DeclarationChange{
+		Address:  address,
+		Kind:     newHover(address, declaration).Kind,
+		Location: declaration.Position(),
+	}
+}
+
+// DiffIndexes compares the declarations and references of two indexes, the
+// changes are sorted by address and name. Addresses declared several times
+// are matched in the order they were collected. The children of the indexes
+// are not compared
+func DiffIndexes(before *Index, after *Index) IndexDiff {
+	diff := IndexDiff{
+		Added:      []DeclarationChange{},
+		Removed:    []DeclarationChange{},
+		Moved:      []DeclarationChange{},
+		References: []ReferenceChange{},
+	}
+
+	old := declarationsByAddress(before)
+	current := declarationsByAddress(after)
+	for address, declarations := range current {
+		previous := old[address]
+		for i, declaration := range declarations {
+			change := newDeclarationChange(address, declaration)
+			if i >= len(previous) {
+				diff.Added = append(diff.Added, change)
+				continue
+			}
+			if location := previous[i].Position(); location.Filename != change.Location.Filename {
+				change.Previous = location
+				diff.Moved = append(diff.Moved, change)
+			}
+		}
+	}
+	for address, declarations := range old {
+		for i := len(current[address]); i < len(declarations); i++ {
+			diff.Removed = append(diff.Removed, newDeclarationChange(address, declarations[i]))
+		}
+	}
+
+	for name, list := range after.References {
+		if count := len(before.References[name].Locations); count != len(list.Locations) {
+			diff.References = append(diff.References, ReferenceChange{name, count, len(list.Locations)})
+		}
+	}
+	for name, list := range before.References {
+		if _, ok := after.References[name]; !ok && len(list.Locations) > 0 {
+			diff.References = append(diff.References, ReferenceChange{name, len(list.Locations), 0})
+		}
+	}
+
+	for _, changes := range [][]DeclarationChange{diff.Added, diff.Removed, diff.Moved} {
+		sort.SliceStable(changes, func(i, j int) bool {
+			if changes[i].Address != changes[j].Address {
+				return changes[i].Address < changes[j].Address
+			}
+			if changes[i].Location.Filename != changes[j].Location.Filename {
+				return changes[i].Location.Filename < changes[j].Location.Filename
+			}
+			return changes[i].Location.Offset < changes[j].Location.Offset
+		})
+	}
+	sort.Slice(diff.References, func(i, j int) bool {
+		return diff.References[i].Name < diff.References[j].Name
+	})
+
+	return diff
+}
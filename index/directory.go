@@ -0,0 +1,183 @@
+package index
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+// isConfigFile reports whether path is a file terraform loads from a module
+// directory
+func isConfigFile(path string) bool {
+	return strings.HasSuffix(path, ".tf") || strings.HasSuffix(path, ".tf.json")
+}
+
+// IsTerraformFile reports whether path is a configuration or variable
+// definitions file
+func IsTerraformFile(path string) bool {
+	return isConfigFile(path) || isVariablesFile(path)
+}
+
+// CollectDirectory collects every configuration file of a module directory,
+// subdirectories are not collected since terraform does not load them either
+func (index *Index) CollectDirectory(directory string, includeRaw bool) error {
+	entries, err := ioutil.ReadDir(directory)
+	if err != nil {
+		return err
+	}
+
+	paths := []string{}
+	for _, entry := range entries {
+		path := filepath.Join(directory, entry.Name())
+		if !entry.IsDir() && isConfigFile(path) {
+			paths = append(paths, path)
+		}
+	}
+
+	return index.CollectFiles(paths, includeRaw)
+}
+
+// CollectFiles collects the files at paths, parse errors are recorded as
+// diagnostics and do not stop the collection
+func (index *Index) CollectFiles(paths []string, includeRaw bool) error {
+	return index.collectFiles(paths, includeRaw, nil)
+}
+
+func (index *Index) collectFiles(paths []string, includeRaw bool, cache *FileCache) error {
+	for _, path := range paths {
+		if err := index.CollectPathCached(cache, path, includeRaw); err != nil {
+			if _, ok := err.(*os.PathError); ok {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// FollowModules collects the modules called by the index into Children,
+// keyed by module name, and follows their modules in turn. Local modules are
+// read from their source directory, other modules from the directory
+// `terraform init` downloaded them to when .terraform/modules/modules.json is
+// present next to the configuration
+func (index *Index) FollowModules(includeRaw bool) {
+	index.lock()
+	defer index.mutex.Unlock()
+
+	var manifest *moduleManifest
+	if len(index.Modules) > 0 {
+		manifest = loadModuleManifest(filepath.Dir(index.Modules[0].Location.Filename))
+	}
+
+	index.followModules(includeRaw, map[string]bool{}, manifest, "")
+}
+
+// followModules follows the modules, active holds the directories of the
+// modules currently being followed so that cyclic calls terminate and key is
+// the manifest key of the module being followed
+func (index *Index) followModules(includeRaw bool, active map[string]bool, manifest *moduleManifest, key string) {
+	for i := range index.Modules {
+		module := &index.Modules[i]
+		moduleKey := module.Name
+		if key != "" {
+			moduleKey = key + "." + module.Name
+		}
+
+		if module.SourceKind == MODULE_SOURCE_LOCAL {
+			module.Directory = filepath.Join(filepath.Dir(module.Location.Filename), module.Source)
+		} else if directory, ok := manifest.directory(moduleKey); ok {
+			module.Directory = directory
+		} else {
+			continue
+		}
+
+		directory, err := filepath.Abs(module.Directory)
+		if err != nil || active[directory] {
+			continue
+		}
+		if _, exists := index.Children[module.Name]; exists {
+			continue
+		}
+
+		child := NewIndex()
+		if err := child.CollectDirectory(module.Directory, includeRaw); err != nil {
+			index.addCollectedDiagnostic(Diagnostic{
+				Severity:         SEVERITY_ERROR,
+				Code:             CODE_MODULE_NOT_FOUND,
+				Message:          "Cannot read module '" + module.Name + "': " + err.Error(),
+				Location:         module.SourceLocation,
+				EndLocation:      module.SourceLocation,
+				RelatedLocations: []hcltoken.Pos{},
+			})
+			continue
+		}
+
+		active[directory] = true
+		child.followModules(includeRaw, active, manifest, moduleKey)
+		delete(active, directory)
+
+		child.Resolve()
+		index.Children[module.Name] = child
+	}
+}
+
+func (index *Index) sortedChildNames() []string {
+	names := make([]string, 0, len(index.Children))
+	for name := range index.Children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkModuleInterfaces reports module arguments which are not variables of
+// the followed module, variables of it without a default the module block
+// does not set and references to outputs it does not declare
+func (index *Index) checkModuleInterfaces() {
+	for _, module := range index.Modules {
+		child, ok := index.Children[module.Name]
+		if !ok {
+			continue
+		}
+
+		arguments := map[string]bool{}
+		for _, argument := range module.Arguments {
+			arguments[argument.Name] = true
+			if child.findVariable(argument.Name) == nil {
+				diagnostic := index.addDiagnostic(SEVERITY_ERROR, CODE_UNDECLARED_MODULE_INPUT, argument.Location,
+					"Module '%s' has no input variable '%s'", module.Name, argument.Name)
+				diagnostic.EndLocation = endPos(argument.Location, argument.Name)
+			}
+		}
+		for _, variable := range child.Variables {
+			if variable.Required && !arguments[variable.Name] {
+				diagnostic := index.addDiagnostic(SEVERITY_ERROR, CODE_MISSING_MODULE_INPUT, module.Location,
+					"Module '%s' does not set the required input variable '%s'", module.Name, variable.Name)
+				diagnostic.EndLocation = endPos(module.Location, "\""+module.Name+"\"")
+				diagnostic.RelatedLocations = append(diagnostic.RelatedLocations, variable.Location)
+			}
+		}
+	}
+
+	for _, name := range index.sortedReferenceNames() {
+		parts := strings.Split(name, ".")
+		if len(parts) != 3 || parts[0] != "module" {
+			continue
+		}
+
+		child, ok := index.Children[parts[1]]
+		if !ok || child.findOutput(parts[2]) != nil {
+			continue
+		}
+
+		for _, location := range index.References[name].Locations {
+			diagnostic := index.addDiagnostic(SEVERITY_ERROR, CODE_UNDECLARED_MODULE_OUTPUT, location,
+				"Module '%s' has no output '%s'", parts[1], parts[2])
+			diagnostic.EndLocation = endPos(location, name)
+		}
+	}
+}
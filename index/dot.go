@@ -0,0 +1,122 @@
+package index
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// dotShapes are the Graphviz node shapes of every kind of declaration
+var dotShapes = map[string]string{
+	SYMBOL_VARIABLE: "ellipse",
+	SYMBOL_LOCAL:    "note",
+	SYMBOL_RESOURCE: "box",
+	SYMBOL_DATA:     "box",
+	SYMBOL_MODULE:   "component",
+	SYMBOL_OUTPUT:   "cds",
+}
+
+type graphNode struct {
+	Address string
+	Kind    string
+}
+
+// graph returns the declarations of the index sorted by address and the
+// declarations each of them refers to, references to addresses which are
+// not declared are left out
+func (index *Index) graph() ([]graphNode, map[string][]string) {
+	nodes := []graphNode{}
+	declared := map[string]bool{}
+	add := func(address string, kind string) {
+		if !declared[address] {
+			declared[address] = true
+			nodes = append(nodes, graphNode{address, kind})
+		}
+	}
+	for _, variable := range index.Variables {
+		add(variable.Address(), SYMBOL_VARIABLE)
+	}
+	for _, local := range index.Locals {
+		add(local.Address(), SYMBOL_LOCAL)
+	}
+	for _, resource := range index.Resources {
+		add(resource.Address(), SYMBOL_RESOURCE)
+	}
+	for _, data := range index.Data {
+		add(data.Address(), SYMBOL_DATA)
+	}
+	for _, module := range index.Modules {
+		add(module.Address(), SYMBOL_MODULE)
+	}
+	for _, output := range index.Outputs {
+		add(output.Address(), SYMBOL_OUTPUT)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Address < nodes[j].Address
+	})
+
+	edges := map[string][]string{}
+	for _, node := range nodes {
+		seen := map[string]bool{}
+		for _, dependency := range index.dependencies[node.Address] {
+			if declared[dependency.Name] && !seen[dependency.Name] {
+				seen[dependency.Name] = true
+				edges[node.Address] = append(edges[node.Address], dependency.Name)
+			}
+		}
+		sort.Strings(edges[node.Address])
+	}
+
+	return nodes, edges
+}
+
+// directory returns the directory of the files of the index, empty if
+// nothing has been collected
+func (index *Index) directory() string {
+	for _, declared := range index.declaredAddresses() {
+		return filepath.Dir(declared.Location.Filename)
+	}
+
+	return ""
+}
+
+// WriteDot writes the dependency graph of the resolved indexes in the
+// Graphviz DOT language, with an edge from every declaration to the
+// declarations it refers to. Several indexes are drawn as one cluster per
+// module directory
+func WriteDot(writer io.Writer, indexes ...*Index) error {
+	lines := []string{"digraph terraform {", "  rankdir = \"RL\";", "  node [fontname = \"sans-serif\"];"}
+	for i, index := range indexes {
+		indent, prefix := "  ", ""
+		if len(indexes) > 1 {
+			indent, prefix = "    ", index.directory()+":"
+			lines = append(lines, fmt.Sprintf("  subgraph cluster_%d {", i), fmt.Sprintf("    label = %s;", strconv.Quote(index.directory())))
+		}
+
+		nodes, edges := index.graph()
+		for _, node := range nodes {
+			lines = append(lines, fmt.Sprintf("%s%s [label = %s, shape = %s];",
+				indent, strconv.Quote(prefix+node.Address), strconv.Quote(node.Address), dotShapes[node.Kind]))
+		}
+		for _, node := range nodes {
+			for _, dependency := range edges[node.Address] {
+				lines = append(lines, fmt.Sprintf("%s%s -> %s;", indent, strconv.Quote(prefix+node.Address), strconv.Quote(prefix+dependency)))
+			}
+		}
+
+		if len(indexes) > 1 {
+			lines = append(lines, "  }")
+		}
+	}
+	lines = append(lines, "}")
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(writer, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,364 @@
+package index
+
+import (
+	"sort"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+// covers reports whether the text starting at pos covers the one-based line
+// and column in filename
+func covers(pos hcltoken.Pos, text string, filename string, line int, col int) bool {
+	return pos.Filename == filename && pos.Line == line && pos.Column <= col && col <= pos.Column+len(text)
+}
+
+// resolutionAt returns the resolved reference under a position
+func (index *Index) resolutionAt(filename string, line int, col int) (Resolution, bool) {
+	for _, resolution := range index.Resolved {
+		if covers(resolution.Location, resolution.Name, filename, line, col) {
+			return resolution, true
+		}
+	}
+
+	return Resolution{}, false
+}
+
+// findDeclaration returns the first declaration of a referenceable address
+func (index *Index) findDeclaration(address string) Declaration {
+	for _, variable := range index.Variables {
+		if variable.Address() == address {
+			return variable
+		}
+	}
+	for _, local := range index.Locals {
+		if local.Address() == address {
+			return local
+		}
+	}
+	for _, resource := range index.Resources {
+		if resource.Address() == address {
+			return resource
+		}
+	}
+	for _, data := range index.Data {
+		if data.Address() == address {
+			return data
+		}
+	}
+	for _, module := range index.Modules {
+		if module.Address() == address {
+			return module
+		}
+	}
+
+	return nil
+}
+
+// DeclarationAt returns the declaration of the reference under the one-based
+// line and column in filename, Resolve must have been called
+func (index *Index) DeclarationAt(filename string, line int, col int) (Declaration, bool) {
+	resolution, ok := index.resolutionAt(filename, line, col)
+	if !ok {
+		return nil, false
+	}
+
+	declaration := index.findDeclaration(resolution.Name)
+	return declaration, declaration != nil
+}
+
+// AddressAt returns the address of the symbol under the one-based line and
+// column in filename, either a resolved reference or a declaration starting
+// on that line
+func (index *Index) AddressAt(filename string, line int, col int) (string, bool) {
+	if resolution, ok := index.resolutionAt(filename, line, col); ok {
+		return resolution.Name, true
+	}
+
+	for _, declared := range index.declaredAddresses() {
+		location := declared.Location
+		if location.Filename == filename && location.Line == line {
+			return declared.Address, true
+		}
+	}
+
+	return "", false
+}
+
+// ReferencesAt returns the declaration and every reference of the symbol
+// under a position, the declaration comes first
+func (index *Index) ReferencesAt(filename string, line int, col int) []hcltoken.Pos {
+	locations := []hcltoken.Pos{}
+	address, ok := index.AddressAt(filename, line, col)
+	if !ok {
+		return locations
+	}
+
+	if declaration := index.findDeclaration(address); declaration != nil {
+		locations = append(locations, declaration.Position())
+	} else if output := index.findOutput(strings.TrimPrefix(address, "output.")); output != nil {
+		locations = append(locations, output.Location)
+	}
+	return append(locations, index.ReferencesToAddress(address)...)
+}
+
+// Completion is a candidate offered by CompletionsAt, Detail describes the
+// declaration like the type of a variable or the source of a module
+type Completion struct {
+	Label    string
+	Kind     string
+	Detail   string
+	Location hcltoken.Pos
+}
+
+// within reports whether the one-based line and column in filename lies in
+// the range from start to end, a cursor just past the end is still within
+func within(filename string, line int, col int, start hcltoken.Pos, end hcltoken.Pos) bool {
+	if start.Filename != filename || line < start.Line || line > end.Line {
+		return false
+	}
+
+	return (line > start.Line || col >= start.Column) && (line < end.Line || col <= end.Column)
+}
+
+// CompletionsAt returns the symbols which can be referenced at a position and
+// start with prefix, sorted by label. The candidates are looked up like
+// NamesWithPrefix does. The declaration enclosing the position
+// is left out since it cannot refer to itself, while `count.index` and
+// `each.*` are offered within blocks which set count or for_each
+func (index *Index) CompletionsAt(filename string, line int, col int, prefix string) []Completion {
+	completions := []Completion{}
+	add := func(label string, kind string, detail string, location hcltoken.Pos, start hcltoken.Pos, end hcltoken.Pos) {
+		if strings.HasPrefix(label, prefix) && !within(filename, line, col, start, end) {
+			completions = append(completions, Completion{
+				Label:    label,
+				Kind:     kind,
+				Detail:   detail,
+				Location: location,
+			})
+		}
+	}
+	addMeta := func(count *Expression, forEach *Expression, start hcltoken.Pos, end hcltoken.Pos) {
+		if !within(filename, line, col, start, end) {
+			return
+		}
+
+		labels := []string{}
+		if count != nil {
+			labels = append(labels, "count.index")
+		}
+		if forEach != nil {
+			labels = append(labels, "each.key", "each.value")
+		}
+		for _, label := range labels {
+			if strings.HasPrefix(label, prefix) {
+				completions = append(completions, Completion{Label: label, Kind: SYMBOL_META})
+			}
+		}
+	}
+
+	for _, name := range index.namesWithPrefix(prefix) {
+		switch name.Kind {
+		case SYMBOL_VARIABLE:
+			{
+				if name.Declaration >= 0 {
+					variable := index.Variables[name.Declaration]
+					add(name.Name, name.Kind, variable.Type, variable.Location, variable.BlockLocation, variable.EndLocation)
+				}
+				break
+			}
+		case SYMBOL_LOCAL:
+			{
+				if name.Declaration >= 0 {
+					local := index.Locals[name.Declaration]
+					add(name.Name, name.Kind, "", local.Location, local.Location, local.EndLocation)
+				}
+				break
+			}
+		case SYMBOL_RESOURCE:
+			{
+				if name.Declaration >= 0 {
+					resource := index.Resources[name.Declaration]
+					add(name.Name, name.Kind, resource.Type, resource.Location, resource.BlockLocation, resource.EndLocation)
+				}
+				break
+			}
+		case SYMBOL_DATA:
+			{
+				if name.Declaration >= 0 {
+					data := index.Data[name.Declaration]
+					add(name.Name, name.Kind, data.Type, data.Location, data.BlockLocation, data.EndLocation)
+				}
+				break
+			}
+		case SYMBOL_MODULE:
+			{
+				if name.Declaration >= 0 {
+					module := index.Modules[name.Declaration]
+					add(name.Name, name.Kind, module.Source, module.Location, module.BlockLocation, module.EndLocation)
+				}
+				break
+			}
+		}
+	}
+	for _, resource := range index.Resources {
+		addMeta(resource.Count, resource.ForEach, resource.BlockLocation, resource.EndLocation)
+	}
+	for _, module := range index.Modules {
+		addMeta(module.Count, module.ForEach, module.BlockLocation, module.EndLocation)
+	}
+
+	sort.SliceStable(completions, func(i, j int) bool {
+		return completions[i].Label < completions[j].Label
+	})
+	return completions
+}
+
+// Hover describes the declaration of the symbol under a position, Type is the
+// type constraint of variables and the type of resources and data sources
+// while the Description of a module is its source
+type Hover struct {
+	Address     string
+	Kind        string
+	Type        string
+	Default     string
+	Description string
+	Sensitive   bool
+	Location    hcltoken.Pos
+}
+
+// HoverAt returns the declaration of the symbol referenced or declared at a
+// position along with its details
+func (index *Index) HoverAt(filename string, line int, col int) (Hover, bool) {
+	address, ok := index.AddressAt(filename, line, col)
+	if !ok {
+		return Hover{}, false
+	}
+
+	return index.HoverFor(address)
+}
+
+// HoverFor returns the declaration at address, outputs included
+func (index *Index) HoverFor(address string) (Hover, bool) {
+	declaration := index.findDeclaration(address)
+	if declaration == nil {
+		if output := index.findOutput(strings.TrimPrefix(address, "output.")); output != nil {
+			declaration = *output
+		} else {
+			return Hover{}, false
+		}
+	}
+
+	return newHover(address, declaration), true
+}
+
+// Definitions returns every declaration of address in the order they were
+// collected, outputs included. An address is declared more than once when
+// the configuration is invalid or when several modules were indexed together
+func (index *Index) Definitions(address string) []Hover {
+	hovers := []Hover{}
+	for _, declaration := range index.declarations() {
+		if declaration.Address() == address {
+			hovers = append(hovers, newHover(address, declaration))
+		}
+	}
+	return hovers
+}
+
+// declarations returns the declarations which have an address, in the order
+// they were collected
+func (index *Index) declarations() []Declaration {
+	declarations := []Declaration{}
+	for _, variable := range index.Variables {
+		declarations = append(declarations, variable)
+	}
+	for _, local := range index.Locals {
+		declarations = append(declarations, local)
+	}
+	for _, resource := range index.Resources {
+		declarations = append(declarations, resource)
+	}
+	for _, data := range index.Data {
+		declarations = append(declarations, data)
+	}
+	for _, module := range index.Modules {
+		declarations = append(declarations, module)
+	}
+	for _, output := range index.Outputs {
+		declarations = append(declarations, output)
+	}
+	return declarations
+}
+
+func newHover(address string, declaration Declaration) Hover {
+	hover := Hover{
+		Address:  address,
+		Location: declaration.Position(),
+	}
+	switch declaration.(type) {
+	case VariableDeclaration:
+		{
+			variable := declaration.(VariableDeclaration)
+			hover.Kind = SYMBOL_VARIABLE
+			hover.Type = variable.Type
+			hover.Default = variable.Default
+			hover.Description = variable.Description
+			hover.Sensitive = variable.Sensitive
+			break
+		}
+
+	case LocalDeclaration:
+		{
+			hover.Kind = SYMBOL_LOCAL
+			break
+		}
+
+	case ResourceDeclaration:
+		{
+			hover.Kind = SYMBOL_RESOURCE
+			hover.Type = declaration.(ResourceDeclaration).Type
+			break
+		}
+
+	case DataDeclaration:
+		{
+			hover.Kind = SYMBOL_DATA
+			hover.Type = declaration.(DataDeclaration).Type
+			break
+		}
+
+	case ModuleDeclaration:
+		{
+			hover.Kind = SYMBOL_MODULE
+			hover.Description = declaration.(ModuleDeclaration).Source
+			break
+		}
+
+	case OutputDeclaration:
+		{
+			hover.Kind = SYMBOL_OUTPUT
+			break
+		}
+	}
+
+	return hover
+}
+
+// Markdown renders the hover as markdown, as shown by editors
+func (hover Hover) Markdown() string {
+	text := "**" + hover.Address + "**"
+	if hover.Type != "" {
+		text += " `" + hover.Type + "`"
+	}
+	if hover.Sensitive {
+		text += " (sensitive)"
+	}
+	if hover.Description != "" {
+		text += "\n\n" + hover.Description
+	}
+	if hover.Default != "" {
+		text += "\n\nDefault: `" + hover.Default + "`"
+	}
+
+	return text
+}
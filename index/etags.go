@@ -0,0 +1,71 @@
+package index
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// WriteEtags writes the declarations of the indexes as an Emacs TAGS file.
+// Emacs finds a tag by the text of its line up to the tag, so contents must
+// return the contents of the collected files
+func WriteEtags(writer io.Writer, contents func(path string) ([]byte, error), indexes ...*Index) error {
+	files := []string{}
+	tags := map[string][]tag{}
+	for _, index := range indexes {
+		for _, tag := range index.tags() {
+			path := tag.Location.Filename
+			if _, seen := tags[path]; !seen {
+				files = append(files, path)
+			}
+			tags[path] = append(tags[path], tag)
+		}
+	}
+
+	for _, path := range files {
+		source, err := contents(path)
+		if err != nil {
+			return err
+		}
+
+		// offsets of the start of every line
+		lines := strings.SplitAfter(string(source), "\n")
+		offsets := make([]int, len(lines))
+		for i := 1; i < len(lines); i++ {
+			offsets[i] = offsets[i-1] + len(lines[i-1])
+		}
+
+		fileTags := tags[path]
+		sort.SliceStable(fileTags, func(i, j int) bool {
+			return fileTags[i].Location.Offset < fileTags[j].Location.Offset
+		})
+
+		var section bytes.Buffer
+		for _, tag := range fileTags {
+			line := tag.Location.Line - 1
+			if line < 0 || line >= len(lines) {
+				continue
+			}
+
+			text := strings.TrimRight(lines[line], "\r\n")
+			start := tag.Location.Column - 1
+			if start >= 0 && start <= len(text) {
+				if end := strings.Index(text[start:], tag.Name); end >= 0 {
+					text = text[:start+end+len(tag.Name)]
+				}
+			}
+			fmt.Fprintf(&section, "%s\x7f%s\x01%d,%d\n", text, tag.Name, tag.Location.Line, offsets[line])
+		}
+
+		if _, err := fmt.Fprintf(writer, "\x0c\n%s,%d\n", path, section.Len()); err != nil {
+			return err
+		}
+		if _, err := section.WriteTo(writer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
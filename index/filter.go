@@ -0,0 +1,172 @@
+package index
+
+import (
+	"fmt"
+	"path"
+)
+
+const (
+	SECTION_VARIABLES      = "variables"
+	SECTION_RESOURCES      = "resources"
+	SECTION_DATA           = "data"
+	SECTION_MODULES        = "modules"
+	SECTION_OUTPUTS        = "outputs"
+	SECTION_LOCALS         = "locals"
+	SECTION_SETTINGS       = "settings"
+	SECTION_IMPORTS        = "imports"
+	SECTION_CHECKS         = "checks"
+	SECTION_REMOVED        = "removed"
+	SECTION_ASSIGNMENTS    = "assignments"
+	SECTION_OVERRIDES      = "overrides"
+	SECTION_DIAGNOSTICS    = "diagnostics"
+	SECTION_REFERENCES     = "references"
+	SECTION_FUNCTION_CALLS = "function-calls"
+	SECTION_RESOLVED       = "resolved"
+	SECTION_USAGE_COUNTS   = "usage-counts"
+	SECTION_CHILDREN       = "children"
+)
+
+// SECTIONS are the parts of an index a Filter selects from, named after the
+// fields of Index
+var SECTIONS = []string{
+	SECTION_VARIABLES, SECTION_RESOURCES, SECTION_DATA, SECTION_MODULES, SECTION_OUTPUTS,
+	SECTION_LOCALS, SECTION_SETTINGS, SECTION_IMPORTS, SECTION_CHECKS, SECTION_REMOVED,
+	SECTION_ASSIGNMENTS, SECTION_OVERRIDES, SECTION_DIAGNOSTICS, SECTION_REFERENCES,
+	SECTION_FUNCTION_CALLS, SECTION_RESOLVED, SECTION_USAGE_COUNTS, SECTION_CHILDREN,
+}
+
+// Filter selects the parts of an index consumers need. Sections lists the
+// sections kept, every section when empty. ResourceTypes are path.Match
+// patterns like `aws_*`, resources and data sources whose type matches none
+// of them are dropped, unless there is none
+type Filter struct {
+	Sections      []string
+	ResourceTypes []string
+}
+
+// NewFilter returns a Filter after checking the sections are known and the
+// patterns are valid
+func NewFilter(sections []string, resourceTypes []string) (Filter, error) {
+	known := map[string]bool{}
+	for _, section := range SECTIONS {
+		known[section] = true
+	}
+	for _, section := range sections {
+		if !known[section] {
+			return Filter{}, fmt.Errorf("unknown section '%s'", section)
+		}
+	}
+	for _, pattern := range resourceTypes {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return Filter{}, fmt.Errorf("invalid resource type pattern '%s': %w", pattern, err)
+		}
+	}
+
+	return Filter{sections, resourceTypes}, nil
+}
+
+func (filter Filter) selects(section string) bool {
+	if len(filter.Sections) == 0 {
+		return true
+	}
+	for _, selected := range filter.Sections {
+		if selected == section {
+			return true
+		}
+	}
+	return false
+}
+
+func (filter Filter) matchesType(resourceType string) bool {
+	if len(filter.ResourceTypes) == 0 {
+		return true
+	}
+	for _, pattern := range filter.ResourceTypes {
+		if matched, _ := path.Match(pattern, resourceType); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Filtered returns a snapshot of the index holding only what filter selects,
+// the sections left out are empty. The children kept are filtered as well
+func (index *Index) Filtered(filter Filter) *Index {
+	filtered := index.Snapshot()
+
+	resources := []ResourceDeclaration{}
+	if filter.selects(SECTION_RESOURCES) {
+		for _, resource := range filtered.Resources {
+			if filter.matchesType(resource.Type) {
+				resources = append(resources, resource)
+			}
+		}
+	}
+	filtered.Resources = resources
+
+	data := []DataDeclaration{}
+	if filter.selects(SECTION_DATA) {
+		for _, item := range filtered.Data {
+			if filter.matchesType(item.Type) {
+				data = append(data, item)
+			}
+		}
+	}
+	filtered.Data = data
+
+	if !filter.selects(SECTION_VARIABLES) {
+		filtered.Variables = []VariableDeclaration{}
+	}
+	if !filter.selects(SECTION_MODULES) {
+		filtered.Modules = []ModuleDeclaration{}
+	}
+	if !filter.selects(SECTION_OUTPUTS) {
+		filtered.Outputs = []OutputDeclaration{}
+	}
+	if !filter.selects(SECTION_LOCALS) {
+		filtered.Locals = []LocalDeclaration{}
+	}
+	if !filter.selects(SECTION_SETTINGS) {
+		filtered.Settings = []SettingsDeclaration{}
+	}
+	if !filter.selects(SECTION_IMPORTS) {
+		filtered.Imports = []ImportDeclaration{}
+	}
+	if !filter.selects(SECTION_CHECKS) {
+		filtered.Checks = []CheckDeclaration{}
+	}
+	if !filter.selects(SECTION_REMOVED) {
+		filtered.Removed = []RemovedDeclaration{}
+	}
+	if !filter.selects(SECTION_ASSIGNMENTS) {
+		filtered.Assignments = []VariableAssignment{}
+	}
+	if !filter.selects(SECTION_OVERRIDES) {
+		filtered.Overrides = []Override{}
+	}
+	if !filter.selects(SECTION_DIAGNOSTICS) {
+		filtered.Diagnostics = []Diagnostic{}
+	}
+	if !filter.selects(SECTION_REFERENCES) {
+		filtered.References = map[string]ReferenceList{}
+	}
+	if !filter.selects(SECTION_FUNCTION_CALLS) {
+		filtered.FunctionCalls = map[string]FunctionCallList{}
+	}
+	if !filter.selects(SECTION_RESOLVED) {
+		filtered.Resolved = []Resolution{}
+	}
+	if !filter.selects(SECTION_USAGE_COUNTS) {
+		filtered.UsageCounts = map[string]Usage{}
+	}
+
+	children := map[string]*Index{}
+	if filter.selects(SECTION_CHILDREN) {
+		for name, child := range filtered.Children {
+			children[name] = child.Filtered(filter)
+		}
+	}
+	filtered.Children = children
+
+	return filtered
+}
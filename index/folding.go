@@ -0,0 +1,117 @@
+package index
+
+import (
+	"sort"
+	"strings"
+
+	hclast "github.com/hashicorp/hcl/hcl/ast"
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+const (
+	FOLDING_BLOCK   = "block"
+	FOLDING_HEREDOC = "heredoc"
+)
+
+// FoldingRange spans the one-based lines of a block, from its type to its
+// closing brace, or of a heredoc, from its header to its closing anchor
+type FoldingRange struct {
+	Kind      string
+	StartLine int
+	EndLine   int
+}
+
+func (index *Index) addFoldingRange(kind string, start hcltoken.Pos, end hcltoken.Pos) {
+	if end.Line <= start.Line {
+		return
+	}
+
+	index.folds[start.Filename] = append(index.folds[start.Filename], FoldingRange{
+		Kind:      kind,
+		StartLine: start.Line,
+		EndLine:   end.Line,
+	})
+}
+
+// FoldingRanges returns the folding ranges of a file ordered by their start
+// line, nested ranges follow the range enclosing them. JSON files have no
+// folding ranges since editors fold JSON on their own
+func (index *Index) FoldingRanges(path string) []FoldingRange {
+	ranges := append([]FoldingRange{}, index.folds[path]...)
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].StartLine != ranges[j].StartLine {
+			return ranges[i].StartLine < ranges[j].StartLine
+		}
+		return ranges[i].EndLine > ranges[j].EndLine
+	})
+
+	return ranges
+}
+
+// addFoldingRanges records the blocks of an hcl/v2 body and its templates
+// spanning several lines, which can only be heredocs
+func (c *hcl2Collector) addFoldingRanges(body *hclsyntax.Body) {
+	hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl2.Diagnostics {
+		switch node.(type) {
+		case *hclsyntax.Block:
+			{
+				block := node.(*hclsyntax.Block)
+				c.index.addFoldingRange(FOLDING_BLOCK, c.pos(block.TypeRange.Start), c.pos(block.CloseBraceRange.Start))
+				break
+			}
+
+		case *hclsyntax.TemplateExpr:
+			{
+				template := node.(*hclsyntax.TemplateExpr)
+				c.index.addFoldingRange(FOLDING_HEREDOC, c.pos(template.SrcRange.Start), c.pos(template.SrcRange.End))
+				break
+			}
+		}
+
+		return nil
+	})
+}
+
+// addASTFoldingRanges records the blocks and heredocs of an HCL1 object list
+func (index *Index) addASTFoldingRanges(list *hclast.ObjectList, path string) {
+	for _, item := range list.Items {
+		if object, ok := getObject(item.Val); ok {
+			if !item.Assign.IsValid() {
+				end := object.Rbrace
+				end.Filename = path
+				index.addFoldingRange(FOLDING_BLOCK, getPos(item.Keys[0].Token, path), end)
+			}
+			index.addASTFoldingRanges(object.List, path)
+		}
+
+		index.addValueFoldingRanges(item.Val, path)
+	}
+}
+
+func (index *Index) addValueFoldingRanges(node hclast.Node, path string) {
+	switch node.(type) {
+	case *hclast.ListType:
+		{
+			for _, element := range node.(*hclast.ListType).List {
+				if object, ok := getObject(element); ok {
+					index.addASTFoldingRanges(object.List, path)
+				}
+				index.addValueFoldingRanges(element, path)
+			}
+			break
+		}
+
+	case *hclast.LiteralType:
+		{
+			token := node.(*hclast.LiteralType).Token
+			if token.Type == hcltoken.HEREDOC {
+				// the heredoc token includes the newline after its anchor
+				start := getPos(token, path)
+				index.addFoldingRange(FOLDING_HEREDOC, start, endPos(start, strings.TrimRight(token.Text, "\n")))
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package index
+
+import (
+	"sort"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hil"
+	hilast "github.com/hashicorp/hil/ast"
+)
+
+// FunctionCallList records every call of a function, Provider is the
+// namespace of provider-defined functions like `provider::aws::arn_parse`
+// and empty for builtin functions
+type FunctionCallList struct {
+	Name      string
+	Provider  string
+	Locations []hcltoken.Pos
+}
+
+// getFunctionProvider returns the provider of a provider-defined function
+func getFunctionProvider(name string) string {
+	parts := strings.Split(name, "::")
+	if len(parts) != 3 || parts[0] != "provider" {
+		return ""
+	}
+
+	return parts[1]
+}
+
+func (index *Index) addFunctionCall(name string, pos hcltoken.Pos) {
+	list := index.FunctionCalls[name]
+	list.Name = name
+	list.Provider = getFunctionProvider(name)
+	list.Locations = append(list.Locations, pos)
+	index.FunctionCalls[name] = list
+}
+
+// ProviderFunctionCalls returns the calls of provider-defined functions
+// grouped by provider
+func (index *Index) ProviderFunctionCalls() map[string][]FunctionCallList {
+	calls := map[string][]FunctionCallList{}
+	for _, name := range index.sortedFunctionNames() {
+		list := index.FunctionCalls[name]
+		if list.Provider != "" {
+			calls[list.Provider] = append(calls[list.Provider], list)
+		}
+	}
+
+	return calls
+}
+
+func (index *Index) sortedFunctionNames() []string {
+	names := make([]string, 0, len(index.FunctionCalls))
+	for name := range index.FunctionCalls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (index *Index) addSyntaxFunctionCalls(node hclsyntax.Node, path string) {
+	hclsyntax.VisitAll(node, func(current hclsyntax.Node) hcl2.Diagnostics {
+		if call, ok := current.(*hclsyntax.FunctionCallExpr); ok {
+			index.addFunctionCall(call.Name, toTokenPos(call.NameRange.Start, path))
+		}
+		return nil
+	})
+}
+
+// addLiteralFunctionCalls records the calls within an HCL1 literal, falling
+// back to the hcl/v2 template parser like parseReferences does
+func (index *Index) addLiteralFunctionCalls(text string, pos hcltoken.Pos) {
+	root, err := hil.ParseWithPosition(text, toHilPos(pos))
+	if err != nil {
+		start := hcl2.Pos{Line: pos.Line, Column: pos.Column, Byte: pos.Offset}
+		template, diagnostics := hclsyntax.ParseTemplate([]byte(text), pos.Filename, start)
+		if !diagnostics.HasErrors() {
+			index.addSyntaxFunctionCalls(template, pos.Filename)
+		}
+		return
+	}
+
+	root.Accept(func(node hilast.Node) hilast.Node {
+		if call, ok := node.(*hilast.Call); ok {
+			index.addFunctionCall(call.Func, textPos(text, pos, call.Pos()))
+		}
+		return node
+	})
+}
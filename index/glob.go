@@ -0,0 +1,128 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+func hasGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// globRoot returns the directory before the first component of pattern
+// containing a wildcard, which is where the expansion starts walking
+func globRoot(pattern string) string {
+	components := strings.Split(pattern, "/")
+	for i, component := range components {
+		if hasGlob(component) {
+			if i == 0 {
+				return "."
+			}
+			return strings.Join(components[:i], "/")
+		}
+	}
+
+	return pattern
+}
+
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^" + globExpression(strings.TrimPrefix(pattern, "./")) + "$")
+}
+
+// ExpandGlobs expands glob patterns like `**/*.tf` into the files matching
+// them, without relying on the shell. Patterns are applied in order and a
+// pattern starting with `!` removes the files matched so far which match the
+// rest of it. Arguments without wildcards are kept as they are, symbolic
+// links to directories are only walked when followSymlinks is set
+func ExpandGlobs(patterns []string, followSymlinks bool) ([]string, error) {
+	return expandGlobs(patterns, func(root string, expression *regexp.Regexp) ([]string, error) {
+		matches := []string{}
+		err := walk(filepath.FromSlash(root), followSymlinks, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && expression.MatchString(strings.TrimPrefix(filepath.ToSlash(path), "./")) {
+				matches = append(matches, path)
+			}
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		return matches, nil
+	})
+}
+
+// MatchGlobs expands glob patterns like ExpandGlobs but matches them against
+// a list of files instead of walking the file system
+func MatchGlobs(patterns []string, files []string) ([]string, error) {
+	return expandGlobs(patterns, func(root string, expression *regexp.Regexp) ([]string, error) {
+		matches := []string{}
+		for _, path := range files {
+			if expression.MatchString(strings.TrimPrefix(filepath.ToSlash(path), "./")) {
+				matches = append(matches, path)
+			}
+		}
+
+		return matches, nil
+	})
+}
+
+// expandGlobs applies the patterns in order, list returns the files below
+// root matching expression
+func expandGlobs(patterns []string, list func(root string, expression *regexp.Regexp) ([]string, error)) ([]string, error) {
+	paths := []string{}
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		slashPattern := filepath.ToSlash(pattern)
+		if strings.HasPrefix(slashPattern, "!") {
+			expression, err := compileGlob(slashPattern[1:])
+			if err != nil {
+				return nil, err
+			}
+
+			kept := []string{}
+			for _, path := range paths {
+				if expression.MatchString(strings.TrimPrefix(filepath.ToSlash(path), "./")) {
+					delete(seen, path)
+				} else {
+					kept = append(kept, path)
+				}
+			}
+			paths = kept
+			continue
+		}
+
+		if !hasGlob(slashPattern) {
+			if !seen[pattern] {
+				seen[pattern] = true
+				paths = append(paths, pattern)
+			}
+			continue
+		}
+
+		expression, err := compileGlob(slashPattern)
+		if err != nil {
+			return nil, err
+		}
+
+		matches, err := list(globRoot(slashPattern), expression)
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Strings(matches)
+		for _, path := range matches {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,809 @@
+package index
+
+import (
+	"sort"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// hcl2Collector collects a single file parsed with hcl/v2 (Terraform 0.12+
+// syntax) into the same declarations the HCL1 collector produces
+type hcl2Collector struct {
+	index    *Index
+	path     string
+	contents []byte
+}
+
+func (index *Index) CollectHCL2(contents []byte, path string) error {
+	index.lock()
+	defer index.mutex.Unlock()
+	return index.collectHCL2(contents, path)
+}
+
+func (index *Index) collectHCL2(contents []byte, path string) error {
+	file, diagnostics := hclsyntax.ParseConfig(contents, path, hcl2.Pos{Line: 1, Column: 1})
+	if diagnostics.HasErrors() {
+		index.addHCL2Diagnostics(diagnostics, path)
+		return diagnostics
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil
+	}
+
+	collector := &hcl2Collector{
+		index:    index,
+		path:     path,
+		contents: contents,
+	}
+	collector.collectBody(body)
+	collector.addSyntaxTokens(body)
+	collector.addFoldingRanges(body)
+	return nil
+}
+
+func (index *Index) addHCL2Diagnostics(diagnostics hcl2.Diagnostics, path string) {
+	for _, hclDiagnostic := range diagnostics {
+		diagnostic := Diagnostic{
+			Severity:         SEVERITY_ERROR,
+			Code:             CODE_PARSE_ERROR,
+			Message:          hclDiagnostic.Summary,
+			RelatedLocations: []hcltoken.Pos{},
+		}
+		if hclDiagnostic.Severity == hcl2.DiagWarning {
+			diagnostic.Severity = SEVERITY_WARNING
+		}
+		if hclDiagnostic.Detail != "" {
+			diagnostic.Message += ": " + hclDiagnostic.Detail
+		}
+		if hclDiagnostic.Subject != nil {
+			diagnostic.Location = toTokenPos(hclDiagnostic.Subject.Start, path)
+			diagnostic.EndLocation = toTokenPos(hclDiagnostic.Subject.End, path)
+		}
+		index.addCollectedDiagnostic(diagnostic)
+	}
+}
+
+func toTokenPos(pos hcl2.Pos, path string) hcltoken.Pos {
+	return hcltoken.Pos{
+		Filename: path,
+		Offset:   pos.Byte,
+		Line:     pos.Line,
+		Column:   pos.Column,
+	}
+}
+
+func (c *hcl2Collector) pos(pos hcl2.Pos) hcltoken.Pos {
+	return toTokenPos(pos, c.path)
+}
+
+func (c *hcl2Collector) addLink(kind string, text string, r hcl2.Range) {
+	c.index.addLink(kind, text, c.pos(r.Start), c.pos(r.End))
+}
+
+func (c *hcl2Collector) rangeText(r hcl2.Range) string {
+	if r.Start.Byte < 0 || r.End.Byte > len(c.contents) || r.Start.Byte > r.End.Byte {
+		return ""
+	}
+
+	return string(c.contents[r.Start.Byte:r.End.Byte])
+}
+
+// exprText returns the source text of expr, quoted strings are returned
+// without their quotes to match the HCL1 collector
+func (c *hcl2Collector) exprText(expr hclsyntax.Expression) string {
+	text := c.rangeText(expr.Range())
+	switch expr.(type) {
+	case *hclsyntax.TemplateExpr, *hclsyntax.TemplateWrapExpr:
+		if len(text) >= 2 && strings.HasPrefix(text, "\"") && strings.HasSuffix(text, "\"") {
+			return text[1 : len(text)-1]
+		}
+	}
+
+	return text
+}
+
+func exprString(expr hcl2.Expression) string {
+	value, diagnostics := expr.Value(nil)
+	if diagnostics.HasErrors() || value.IsNull() || !value.IsKnown() || value.Type() != cty.String {
+		return ""
+	}
+
+	return value.AsString()
+}
+
+func exprBool(expr hcl2.Expression, fallback bool) bool {
+	value, diagnostics := expr.Value(nil)
+	if diagnostics.HasErrors() || value.IsNull() || !value.IsKnown() || value.Type() != cty.Bool {
+		return fallback
+	}
+
+	return value.True()
+}
+
+func traversalName(traversal hcl2.Traversal) string {
+	parts := []string{}
+	for _, step := range traversal {
+		switch step.(type) {
+		case hcl2.TraverseRoot:
+			parts = append(parts, step.(hcl2.TraverseRoot).Name)
+		case hcl2.TraverseAttr:
+			parts = append(parts, step.(hcl2.TraverseAttr).Name)
+		default:
+			return strings.Join(parts, ".")
+		}
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// exprNames returns the names in a list like `[aws_instance.web, "tags"]`
+func exprNames(expr hclsyntax.Expression) []string {
+	names := []string{}
+	elements := []hclsyntax.Expression{expr}
+	if tuple, ok := expr.(*hclsyntax.TupleConsExpr); ok {
+		elements = tuple.Exprs
+	}
+
+	for _, element := range elements {
+		if traversal, ok := element.(*hclsyntax.ScopeTraversalExpr); ok {
+			names = append(names, traversalName(traversal.Traversal))
+		} else if text := exprString(element); text != "" {
+			names = append(names, text)
+		}
+	}
+
+	return names
+}
+
+func sortedAttributes(body *hclsyntax.Body) []*hclsyntax.Attribute {
+	attributes := make([]*hclsyntax.Attribute, 0, len(body.Attributes))
+	for _, attribute := range body.Attributes {
+		attributes = append(attributes, attribute)
+	}
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].NameRange.Start.Byte < attributes[j].NameRange.Start.Byte
+	})
+
+	return attributes
+}
+
+func findBlocks(body *hclsyntax.Body, blockType string) []*hclsyntax.Block {
+	blocks := []*hclsyntax.Block{}
+	for _, block := range body.Blocks {
+		if block.Type == blockType {
+			blocks = append(blocks, block)
+		}
+	}
+
+	return blocks
+}
+
+func findBlock(body *hclsyntax.Body, blockType string) *hclsyntax.Block {
+	for _, block := range body.Blocks {
+		if block.Type == blockType {
+			return block
+		}
+	}
+
+	return nil
+}
+
+// referenceWalker finds the traversals within a node, skipping those rooted
+// at a name bound by an enclosing for expression, `%{ for }` directive or
+// dynamic block. contents maps the content blocks of the dynamic blocks
+// entered to the scope their iterator is bound in. addresses holds the
+// address attributes entered, whose traversals are skipped as well
+type referenceWalker struct {
+	collector *hcl2Collector
+	getKeys   func(string) []string
+	scopes    []map[string]struct{}
+	contents  map[*hclsyntax.Block]map[string]struct{}
+	addresses map[*hclsyntax.Attribute]bool
+	entered   int
+	found     []reference
+}
+
+func (w *referenceWalker) isLocal(name string) bool {
+	for _, scope := range w.scopes {
+		if _, ok := scope[name]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *referenceWalker) Enter(node hclsyntax.Node) hcl2.Diagnostics {
+	switch node.(type) {
+	case hclsyntax.ChildScope:
+		{
+			w.scopes = append(w.scopes, node.(hclsyntax.ChildScope).LocalNames)
+			break
+		}
+
+	case *hclsyntax.Block:
+		{
+			block := node.(*hclsyntax.Block)
+			if scope, ok := w.contents[block]; ok {
+				w.scopes = append(w.scopes, scope)
+			}
+			if content, iterator, ok := syntaxDynamicContent(block); ok {
+				w.contents[content] = map[string]struct{}{iterator: {}}
+			}
+			if name, ok := addressAttributes[block.Type]; ok {
+				if attribute, ok := block.Body.Attributes[name]; ok {
+					w.addresses[attribute] = true
+				}
+			}
+			break
+		}
+
+	case *hclsyntax.Attribute:
+		{
+			if w.addresses[node.(*hclsyntax.Attribute)] {
+				w.entered++
+			}
+			break
+		}
+
+	case *hclsyntax.ScopeTraversalExpr:
+		{
+			traversal := node.(*hclsyntax.ScopeTraversalExpr)
+			if w.entered > 0 || w.isLocal(traversal.Traversal.RootName()) {
+				break
+			}
+
+			for _, key := range w.getKeys(traversalName(traversal.Traversal)) {
+				w.found = append(w.found, reference{
+					Name:     key,
+					Location: w.collector.pos(traversal.SrcRange.Start),
+				})
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+func (w *referenceWalker) Exit(node hclsyntax.Node) hcl2.Diagnostics {
+	if _, ok := node.(hclsyntax.ChildScope); ok {
+		w.scopes = w.scopes[:len(w.scopes)-1]
+	}
+	if block, ok := node.(*hclsyntax.Block); ok {
+		if _, ok := w.contents[block]; ok {
+			w.scopes = w.scopes[:len(w.scopes)-1]
+		}
+	}
+	if attribute, ok := node.(*hclsyntax.Attribute); ok && w.addresses[attribute] {
+		w.entered--
+	}
+
+	return nil
+}
+
+// findReferences returns the references within node accepted by getKeys
+func (c *hcl2Collector) findReferences(node hclsyntax.Node, getKeys func(string) []string) []reference {
+	walker := &referenceWalker{
+		collector: c,
+		getKeys:   getKeys,
+		scopes:    []map[string]struct{}{},
+		contents:  map[*hclsyntax.Block]map[string]struct{}{},
+		addresses: map[*hclsyntax.Attribute]bool{},
+		found:     []reference{},
+	}
+	hclsyntax.Walk(node, walker)
+
+	return walker.found
+}
+
+func (c *hcl2Collector) addReferences(node hclsyntax.Node, getKeys func(string) []string) {
+	for _, reference := range c.findReferences(node, getKeys) {
+		c.index.addReference(reference.Name, reference.Location)
+	}
+}
+
+func (c *hcl2Collector) addDependencies(address string, node hclsyntax.Node) {
+	c.index.dependencies[address] = append(c.index.dependencies[address], c.findReferences(node, getReferenceKeys)...)
+}
+
+func (c *hcl2Collector) getExpression(attribute *hclsyntax.Attribute) *Expression {
+	if attribute == nil {
+		return nil
+	}
+
+	return &Expression{
+		Text:       c.exprText(attribute.Expr),
+		References: uniqueReferenceNames(c.findReferences(attribute.Expr, getReferenceKeys)),
+		Location:   c.pos(attribute.NameRange.Start),
+	}
+}
+
+func (c *hcl2Collector) collectBody(body *hclsyntax.Body) {
+	c.addReferences(body, getReferenceKeys)
+	c.addFunctionCalls(body)
+	c.checkInterpolationOnly(body)
+
+	for _, block := range body.Blocks {
+		switch block.Type {
+		case "variable":
+			c.handleVariable(block)
+		case "resource":
+			c.handleResource(block, RESOURCE_KIND_MANAGED)
+		case "ephemeral":
+			c.handleResource(block, RESOURCE_KIND_EPHEMERAL)
+		case "data":
+			c.handleData(block)
+		case "module":
+			c.handleModule(block)
+		case "output":
+			c.handleOutput(block)
+		case "locals":
+			c.handleLocals(block)
+		case "terraform":
+			c.handleSettings(block)
+		case "check":
+			c.handleCheck(block)
+		case "import":
+			c.handleImport(block)
+		case "removed":
+			c.handleRemoved(block)
+		}
+
+		c.handleDynamicBlocks(block.Body)
+	}
+}
+
+// addFunctionCalls records the function calls within body, except for the
+// type constraints of variables which only look like calls, like
+// `list(string)`
+func (c *hcl2Collector) addFunctionCalls(body *hclsyntax.Body) {
+	for _, attribute := range sortedAttributes(body) {
+		c.index.addSyntaxFunctionCalls(attribute.Expr, c.path)
+	}
+
+	for _, block := range body.Blocks {
+		if block.Type != "variable" {
+			c.index.addSyntaxFunctionCalls(block, c.path)
+			continue
+		}
+
+		for _, attribute := range sortedAttributes(block.Body) {
+			if attribute.Name != "type" {
+				c.index.addSyntaxFunctionCalls(attribute.Expr, c.path)
+			}
+		}
+		for _, nested := range block.Body.Blocks {
+			c.index.addSyntaxFunctionCalls(nested, c.path)
+		}
+	}
+}
+
+func (c *hcl2Collector) checkInterpolationOnly(body *hclsyntax.Body) {
+	for _, attribute := range sortedAttributes(body) {
+		wrap, ok := attribute.Expr.(*hclsyntax.TemplateWrapExpr)
+		if !ok {
+			continue
+		}
+
+		expression := c.rangeText(wrap.Wrapped.Range())
+		c.index.addCollectedDiagnostic(Diagnostic{
+			Severity:         SEVERITY_INFO,
+			Code:             CODE_INTERPOLATION_ONLY,
+			Message:          "Interpolation-only expressions are deprecated, use " + expression + " instead",
+			Location:         c.pos(wrap.SrcRange.Start),
+			EndLocation:      c.pos(wrap.SrcRange.End),
+			RelatedLocations: []hcltoken.Pos{},
+			Suggestion:       expression,
+		})
+	}
+
+	for _, block := range body.Blocks {
+		c.checkInterpolationOnly(block.Body)
+	}
+}
+
+func (c *hcl2Collector) handleVariable(block *hclsyntax.Block) {
+	if len(block.Labels) < 1 {
+		return
+	}
+
+	variable := VariableDeclaration{
+		Name:          block.Labels[0],
+		Nullable:      true, // terraform defaults nullable to true
+		Required:      true, // until a default is found
+		Validations:   []ValidationDeclaration{},
+		Location:      c.pos(block.LabelRanges[0].Start),
+		BlockLocation: c.pos(block.TypeRange.Start),
+		EndLocation:   c.pos(block.Range().End),
+	}
+
+	if variableType, ok := block.Body.Attributes["type"]; ok {
+		variable.Type = c.exprText(variableType.Expr)
+		variable.TypeConstraint = parseTypeConstraint(variable.Type)
+	}
+	if defaultValue, ok := block.Body.Attributes["default"]; ok {
+		variable.Default = c.exprText(defaultValue.Expr)
+		variable.Required = false
+	}
+	if description, ok := block.Body.Attributes["description"]; ok {
+		variable.Description = exprString(description.Expr)
+	}
+	if sensitive, ok := block.Body.Attributes["sensitive"]; ok {
+		variable.Sensitive = exprBool(sensitive.Expr, false)
+	}
+	if nullable, ok := block.Body.Attributes["nullable"]; ok {
+		variable.Nullable = exprBool(nullable.Expr, true)
+		variable.nullableSet = true
+	}
+	for _, validation := range findBlocks(block.Body, "validation") {
+		variable.Validations = append(variable.Validations, ValidationDeclaration(c.getCondition(validation)))
+	}
+
+	c.index.Variables = append(c.index.Variables, variable)
+}
+
+func (c *hcl2Collector) getDependsOn(body *hclsyntax.Body) []Dependency {
+	dependencies := []Dependency{}
+	attribute, ok := body.Attributes["depends_on"]
+	if !ok {
+		return dependencies
+	}
+
+	tuple, ok := attribute.Expr.(*hclsyntax.TupleConsExpr)
+	if !ok {
+		return dependencies
+	}
+
+	for _, element := range tuple.Exprs {
+		if traversal, ok := element.(*hclsyntax.ScopeTraversalExpr); ok {
+			dependencies = append(dependencies, Dependency{
+				Address:  traversalName(traversal.Traversal),
+				Location: c.pos(traversal.SrcRange.Start),
+			})
+			continue
+		}
+
+		// legacy quoted addresses like "aws_instance.web" are not traversals,
+		// so they are not seen by the reference walk
+		if address := exprString(element); address != "" {
+			location := c.pos(element.Range().Start)
+			location.Offset++
+			location.Column++
+			dependencies = append(dependencies, Dependency{
+				Address:  address,
+				Location: location,
+			})
+			c.index.addReference(address, location)
+		}
+	}
+
+	return dependencies
+}
+
+func (c *hcl2Collector) handleResource(block *hclsyntax.Block, kind string) {
+	if len(block.Labels) < 2 {
+		return
+	}
+
+	resource := ResourceDeclaration{
+		Kind:          kind,
+		Type:          block.Labels[0],
+		Name:          block.Labels[1],
+		Count:         c.getExpression(block.Body.Attributes["count"]),
+		ForEach:       c.getExpression(block.Body.Attributes["for_each"]),
+		DependsOn:     c.getDependsOn(block.Body),
+		Location:      c.pos(block.LabelRanges[1].Start), // return position of name
+		Blocks:        c.getNestedBlocks(block.Body),
+		BlockLocation: c.pos(block.TypeRange.Start),
+		EndLocation:   c.pos(block.Range().End),
+	}
+
+	c.addLink(resourceLinkKind(kind), resource.Type, block.LabelRanges[0])
+	if lifecycle := findBlock(block.Body, "lifecycle"); lifecycle != nil {
+		resource.Lifecycle = c.getLifecycle(lifecycle)
+		c.handleConditions(lifecycle.Body, resource.Address())
+	}
+
+	address := resource.Address()
+	getSelfKeys := func(name string) []string {
+		if !strings.HasPrefix(name, "self.") {
+			return nil
+		}
+		return []string{address}
+	}
+	for _, nested := range append(findBlocks(block.Body, "provisioner"), findBlocks(block.Body, "connection")...) {
+		c.addReferences(nested.Body, getSelfKeys)
+	}
+
+	c.index.Resources = append(c.index.Resources, resource)
+	c.addDependencies(address, block.Body)
+	for _, dependency := range resource.DependsOn {
+		c.index.dependencies[address] = append(c.index.dependencies[address],
+			reference{Name: dependency.Address, Location: dependency.Location})
+	}
+}
+
+func (c *hcl2Collector) getLifecycle(block *hclsyntax.Block) *LifecycleDeclaration {
+	lifecycle := &LifecycleDeclaration{
+		IgnoreChanges: []string{},
+		Location:      c.pos(block.TypeRange.Start),
+	}
+
+	if preventDestroy, ok := block.Body.Attributes["prevent_destroy"]; ok {
+		lifecycle.PreventDestroy = exprBool(preventDestroy.Expr, false)
+		lifecycle.PreventDestroyLocation = c.pos(preventDestroy.NameRange.Start)
+	}
+	if createBeforeDestroy, ok := block.Body.Attributes["create_before_destroy"]; ok {
+		lifecycle.CreateBeforeDestroy = exprBool(createBeforeDestroy.Expr, false)
+		lifecycle.CreateBeforeDestroyLocation = c.pos(createBeforeDestroy.NameRange.Start)
+	}
+	if ignoreChanges, ok := block.Body.Attributes["ignore_changes"]; ok {
+		lifecycle.IgnoreChanges = exprNames(ignoreChanges.Expr)
+		lifecycle.IgnoreChangesLocation = c.pos(ignoreChanges.NameRange.Start)
+	}
+
+	return lifecycle
+}
+
+func (c *hcl2Collector) handleData(block *hclsyntax.Block) {
+	if len(block.Labels) < 2 {
+		return
+	}
+
+	data := DataDeclaration{
+		Type:          block.Labels[0],
+		Name:          block.Labels[1],
+		Location:      c.pos(block.LabelRanges[1].Start), // return position of name
+		Blocks:        c.getNestedBlocks(block.Body),
+		BlockLocation: c.pos(block.TypeRange.Start),
+		EndLocation:   c.pos(block.Range().End),
+	}
+	c.addLink(LINK_DATA_TYPE, data.Type, block.LabelRanges[0])
+	c.index.Data = append(c.index.Data, data)
+	c.addDependencies(data.Address(), block.Body)
+}
+
+func (c *hcl2Collector) handleModule(block *hclsyntax.Block) {
+	if len(block.Labels) < 1 {
+		return
+	}
+
+	module := ModuleDeclaration{
+		Name:          block.Labels[0],
+		SourceKind:    MODULE_SOURCE_UNKNOWN,
+		Count:         c.getExpression(block.Body.Attributes["count"]),
+		ForEach:       c.getExpression(block.Body.Attributes["for_each"]),
+		DependsOn:     c.getDependsOn(block.Body),
+		Arguments:     []ModuleArgument{},
+		Location:      c.pos(block.LabelRanges[0].Start),
+		BlockLocation: c.pos(block.TypeRange.Start),
+		EndLocation:   c.pos(block.Range().End),
+	}
+
+	for _, attribute := range sortedAttributes(block.Body) {
+		if !moduleMetaArguments[attribute.Name] {
+			module.Arguments = append(module.Arguments, ModuleArgument{
+				Name:     attribute.Name,
+				Location: c.pos(attribute.NameRange.Start),
+			})
+		}
+	}
+	if source, ok := block.Body.Attributes["source"]; ok {
+		module.Source = exprString(source.Expr)
+		module.SourceKind = ClassifyModuleSource(module.Source)
+		module.SourceLocation = c.pos(source.NameRange.Start)
+		c.addLink(LINK_MODULE_SOURCE, module.Source, source.Expr.Range())
+	}
+	if version, ok := block.Body.Attributes["version"]; ok {
+		module.Version = exprString(version.Expr)
+	}
+
+	c.index.Modules = append(c.index.Modules, module)
+	c.addDependencies(module.Address(), block.Body)
+}
+
+func (c *hcl2Collector) handleOutput(block *hclsyntax.Block) {
+	if len(block.Labels) < 1 {
+		return
+	}
+
+	output := OutputDeclaration{
+		Name:          block.Labels[0],
+		References:    []string{},
+		DependsOn:     c.getDependsOn(block.Body),
+		Location:      c.pos(block.LabelRanges[0].Start),
+		BlockLocation: c.pos(block.TypeRange.Start),
+		EndLocation:   c.pos(block.Range().End),
+	}
+
+	if value, ok := block.Body.Attributes["value"]; ok {
+		output.Value = c.exprText(value.Expr)
+		output.ValueLocation = c.pos(value.NameRange.Start)
+		output.valueReferences = c.findReferences(value.Expr, getReferenceKeys)
+		output.References = uniqueReferenceNames(output.valueReferences)
+	}
+	c.handleConditions(block.Body, "output."+output.Name)
+
+	c.index.Outputs = append(c.index.Outputs, output)
+	c.addDependencies(output.Address(), block.Body)
+	for _, dependency := range output.DependsOn {
+		c.index.dependencies[output.Address()] = append(c.index.dependencies[output.Address()],
+			reference{Name: dependency.Address, Location: dependency.Location})
+	}
+}
+
+func (c *hcl2Collector) handleLocals(block *hclsyntax.Block) {
+	for _, attribute := range sortedAttributes(block.Body) {
+		local := LocalDeclaration{
+			Name:        attribute.Name,
+			Location:    c.pos(attribute.NameRange.Start),
+			EndLocation: c.pos(attribute.SrcRange.End),
+		}
+		c.index.Locals = append(c.index.Locals, local)
+		c.addDependencies(local.Address(), attribute.Expr)
+	}
+}
+
+func (c *hcl2Collector) getCondition(block *hclsyntax.Block) ConditionDeclaration {
+	condition := ConditionDeclaration{
+		References: []string{},
+		Location:   c.pos(block.TypeRange.Start),
+	}
+
+	if conditionAttribute, ok := block.Body.Attributes["condition"]; ok {
+		condition.Condition = c.exprText(conditionAttribute.Expr)
+		condition.References = uniqueReferenceNames(c.findReferences(conditionAttribute.Expr, getReferenceKeys))
+	}
+	if errorMessage, ok := block.Body.Attributes["error_message"]; ok {
+		condition.ErrorMessage = c.exprText(errorMessage.Expr)
+	}
+
+	return condition
+}
+
+func (c *hcl2Collector) handleConditions(body *hclsyntax.Body, address string) {
+	for _, kind := range []string{"precondition", "postcondition"} {
+		for _, block := range findBlocks(body, kind) {
+			c.index.Checks = append(c.index.Checks, CheckDeclaration{
+				Kind:       kind,
+				Name:       address,
+				Conditions: []ConditionDeclaration{c.getCondition(block)},
+				Location:   c.pos(block.TypeRange.Start),
+			})
+		}
+	}
+}
+
+func (c *hcl2Collector) handleCheck(block *hclsyntax.Block) {
+	if len(block.Labels) < 1 {
+		return
+	}
+
+	check := CheckDeclaration{
+		Kind:       "check",
+		Name:       block.Labels[0],
+		Conditions: []ConditionDeclaration{},
+		Location:   c.pos(block.LabelRanges[0].Start),
+	}
+	for _, assert := range findBlocks(block.Body, "assert") {
+		check.Conditions = append(check.Conditions, c.getCondition(assert))
+	}
+
+	c.index.Checks = append(c.index.Checks, check)
+}
+
+func (c *hcl2Collector) handleImport(block *hclsyntax.Block) {
+	declaration := ImportDeclaration{
+		References: []string{},
+		Location:   c.pos(block.TypeRange.Start),
+	}
+
+	if to, ok := block.Body.Attributes["to"]; ok {
+		declaration.To = c.exprText(to.Expr)
+		declaration.ToLocation = c.pos(to.NameRange.Start)
+	}
+	if id, ok := block.Body.Attributes["id"]; ok {
+		declaration.ID = c.exprText(id.Expr)
+		declaration.References = uniqueReferenceNames(c.findReferences(id.Expr, getReferenceKeys))
+	}
+
+	c.index.Imports = append(c.index.Imports, declaration)
+}
+
+func (c *hcl2Collector) handleRemoved(block *hclsyntax.Block) {
+	removed := RemovedDeclaration{
+		Destroy:  true, // terraform destroys removed objects unless told otherwise
+		Location: c.pos(block.TypeRange.Start),
+	}
+
+	if from, ok := block.Body.Attributes["from"]; ok {
+		removed.From = c.exprText(from.Expr)
+		removed.FromLocation = c.pos(from.NameRange.Start)
+	}
+	if lifecycle := findBlock(block.Body, "lifecycle"); lifecycle != nil {
+		if destroy, ok := lifecycle.Body.Attributes["destroy"]; ok {
+			removed.Destroy = exprBool(destroy.Expr, true)
+		}
+	}
+
+	c.index.Removed = append(c.index.Removed, removed)
+}
+
+// syntaxDynamicContent returns the content block of a dynamic block and the
+// name of its iterator
+func syntaxDynamicContent(block *hclsyntax.Block) (*hclsyntax.Block, string, bool) {
+	if block.Type != "dynamic" || len(block.Labels) == 0 {
+		return nil, "", false
+	}
+
+	iterator := block.Labels[0]
+	if iteratorAttribute, ok := block.Body.Attributes["iterator"]; ok {
+		if traversal, ok := iteratorAttribute.Expr.(*hclsyntax.ScopeTraversalExpr); ok {
+			iterator = traversalName(traversal.Traversal)
+		}
+	}
+
+	content := findBlock(block.Body, "content")
+	return content, iterator, content != nil
+}
+
+// handleDynamicBlocks records the iterator references in the content of every
+// dynamic block nested within body, which every other walk skips
+func (c *hcl2Collector) handleDynamicBlocks(body *hclsyntax.Body) {
+	for _, block := range body.Blocks {
+		if content, iterator, ok := syntaxDynamicContent(block); ok {
+			for _, reference := range c.findReferences(content.Body, getIteratorKeys(iterator)) {
+				c.index.addReferenceOfKind(reference.Name, REFERENCE_KIND_ITERATOR, reference.Location)
+			}
+		}
+
+		c.handleDynamicBlocks(block.Body)
+	}
+}
+
+func (c *hcl2Collector) handleSettings(block *hclsyntax.Block) {
+	settings := SettingsDeclaration{
+		RequiredProviders: []ProviderRequirement{},
+		Location:          c.pos(block.TypeRange.Start),
+	}
+
+	if version, ok := block.Body.Attributes["required_version"]; ok {
+		settings.RequiredVersion = exprString(version.Expr)
+		settings.RequiredVersionLocation = c.pos(version.NameRange.Start)
+	}
+
+	if providers := findBlock(block.Body, "required_providers"); providers != nil {
+		for _, attribute := range sortedAttributes(providers.Body) {
+			provider := ProviderRequirement{
+				Name:     attribute.Name,
+				Location: c.pos(attribute.NameRange.Start),
+			}
+
+			// required_providers accepts both `aws = "~> 1.0"` and
+			// `aws = { source = "...", version = "..." }`
+			if object, ok := attribute.Expr.(*hclsyntax.ObjectConsExpr); ok {
+				for _, item := range object.Items {
+					switch hcl2.ExprAsKeyword(item.KeyExpr) {
+					case "source":
+						provider.Source = exprString(item.ValueExpr)
+					case "version":
+						provider.Version = exprString(item.ValueExpr)
+					}
+				}
+			} else {
+				provider.Version = exprString(attribute.Expr)
+			}
+
+			settings.RequiredProviders = append(settings.RequiredProviders, provider)
+		}
+	}
+
+	c.index.Settings = append(c.index.Settings, settings)
+}
@@ -0,0 +1,136 @@
+package index
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const IGNORE_FILE = ".terraformindexignore"
+
+// IgnoreList holds gitignore-style patterns, the last pattern matching a
+// path decides whether it is ignored so `!` patterns re-include paths
+type IgnoreList struct {
+	patterns []ignorePattern
+}
+
+type ignorePattern struct {
+	expression    *regexp.Regexp
+	negate        bool
+	directoryOnly bool
+}
+
+func NewIgnoreList() *IgnoreList {
+	return &IgnoreList{patterns: []ignorePattern{}}
+}
+
+// LoadIgnoreFile adds the patterns of the .terraformindexignore file in
+// directory, a missing file is not an error
+func (list *IgnoreList) LoadIgnoreFile(directory string) error {
+	contents, err := ioutil.ReadFile(filepath.Join(directory, IGNORE_FILE))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(string(contents), "\n") {
+		list.Add(line)
+	}
+	return nil
+}
+
+// Add adds a single pattern, blank lines and comments are skipped
+func (list *IgnoreList) Add(pattern string) {
+	pattern = strings.TrimRight(pattern, " \r")
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return
+	}
+
+	ignore := ignorePattern{}
+	if strings.HasPrefix(pattern, "!") {
+		ignore.negate = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		ignore.directoryOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+
+	// patterns containing a slash are relative to the root, others match at
+	// any depth
+	prefix := "^(?:.*/)?"
+	if strings.Contains(pattern, "/") {
+		prefix = "^"
+		pattern = strings.TrimPrefix(pattern, "/")
+	}
+
+	expression, err := regexp.Compile(prefix + globExpression(pattern) + "(/.*)?$")
+	if err != nil {
+		return
+	}
+	ignore.expression = expression
+	list.patterns = append(list.patterns, ignore)
+}
+
+// globExpression translates a glob with `*`, `?` and `**` to a regular
+// expression
+func globExpression(pattern string) string {
+	var expression strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch char := pattern[i]; char {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				expression.WriteString("(?:.*/)?")
+				i += 2
+			} else if strings.HasPrefix(pattern[i:], "**") {
+				expression.WriteString(".*")
+				i++
+			} else {
+				expression.WriteString("[^/]*")
+			}
+		case '?':
+			expression.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				expression.WriteString(regexp.QuoteMeta(string(char)))
+				break
+			}
+			expression.WriteString(pattern[i : i+end+1])
+			i += end
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+
+	return expression.String()
+}
+
+// Match reports whether path, relative to the directory the patterns apply
+// to, is ignored
+func (list *IgnoreList) Match(path string, isDir bool) bool {
+	if list == nil {
+		return false
+	}
+
+	path = filepath.ToSlash(path)
+	ignored := false
+	for _, pattern := range list.patterns {
+		match := pattern.expression.FindStringSubmatch(path)
+		if match == nil {
+			continue
+		}
+
+		// a directory pattern matches a file only through a parent directory
+		if pattern.directoryOnly && !isDir && match[1] == "" {
+			continue
+		}
+		ignored = !pattern.negate
+	}
+
+	return ignored
+}
@@ -1,85 +1,289 @@
 package index
 
 import (
+	"bytes"
 	"strings"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/hashicorp/hcl"
 	hclast "github.com/hashicorp/hcl/hcl/ast"
-	hclparser "github.com/hashicorp/hcl/hcl/parser"
+	hclprinter "github.com/hashicorp/hcl/hcl/printer"
 	hcltoken "github.com/hashicorp/hcl/hcl/token"
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hil"
 	hilast "github.com/hashicorp/hil/ast"
 	hilparser "github.com/hashicorp/hil/parser"
 )
 
+type ConditionDeclaration struct {
+	Condition    string
+	ErrorMessage string
+	References   []string
+	Location     hcltoken.Pos
+}
+
+type ValidationDeclaration ConditionDeclaration
+
 type VariableDeclaration struct {
-	Name     string
+	Name           string
+	Type           string
+	TypeConstraint *TypeConstraint
+	Default        string
+	Required       bool
+	Description    string
+	Sensitive      bool
+	Nullable       bool
+	Validations    []ValidationDeclaration
+	Location       hcltoken.Pos
+	BlockLocation  hcltoken.Pos
+	EndLocation    hcltoken.Pos
+
+	// nullableSet tells Nullable was given rather than defaulted, so an
+	// override can set it either way
+	nullableSet bool
+}
+
+type Expression struct {
+	Text       string
+	References []string
+	Location   hcltoken.Pos
+}
+
+type Dependency struct {
+	Address  string
 	Location hcltoken.Pos
 }
 
+type LifecycleDeclaration struct {
+	PreventDestroy              bool
+	PreventDestroyLocation      hcltoken.Pos
+	CreateBeforeDestroy         bool
+	CreateBeforeDestroyLocation hcltoken.Pos
+	IgnoreChanges               []string
+	IgnoreChangesLocation       hcltoken.Pos
+	Location                    hcltoken.Pos
+}
+
+const (
+	RESOURCE_KIND_MANAGED   = "managed"
+	RESOURCE_KIND_EPHEMERAL = "ephemeral"
+)
+
 type ResourceDeclaration struct {
-	Type     string
-	Name     string
-	Location hcltoken.Pos
+	Kind          string
+	Type          string
+	Name          string
+	Count         *Expression
+	ForEach       *Expression
+	DependsOn     []Dependency
+	Lifecycle     *LifecycleDeclaration
+	Location      hcltoken.Pos
+	Blocks        []NestedBlock
+	BlockLocation hcltoken.Pos
+	EndLocation   hcltoken.Pos
+}
+
+type DataDeclaration struct {
+	Type          string
+	Name          string
+	Location      hcltoken.Pos
+	Blocks        []NestedBlock
+	BlockLocation hcltoken.Pos
+	EndLocation   hcltoken.Pos
+}
+
+type ModuleDeclaration struct {
+	Name           string
+	Source         string
+	SourceKind     string
+	SourceLocation hcltoken.Pos
+	Version        string
+	Count          *Expression
+	ForEach        *Expression
+	DependsOn      []Dependency
+	Arguments      []ModuleArgument
+	Directory      string
+	Location       hcltoken.Pos
+	BlockLocation  hcltoken.Pos
+	EndLocation    hcltoken.Pos
 }
 
 type OutputDeclaration struct {
+	Name          string
+	Value         string
+	ValueLocation hcltoken.Pos
+	References    []string
+	DependsOn     []Dependency
+	Location      hcltoken.Pos
+	BlockLocation hcltoken.Pos
+	EndLocation   hcltoken.Pos
+
+	valueReferences []reference
+}
+
+type LocalDeclaration struct {
+	Name        string
+	Location    hcltoken.Pos
+	EndLocation hcltoken.Pos
+}
+
+type ProviderRequirement struct {
 	Name     string
+	Source   string
+	Version  string
 	Location hcltoken.Pos
 }
 
+type SettingsDeclaration struct {
+	RequiredVersion         string
+	RequiredVersionLocation hcltoken.Pos
+	RequiredProviders       []ProviderRequirement
+	Location                hcltoken.Pos
+}
+
+type ImportDeclaration struct {
+	To         string
+	ToLocation hcltoken.Pos
+	ID         string
+	References []string
+	Location   hcltoken.Pos
+}
+
+type RemovedDeclaration struct {
+	From         string
+	FromLocation hcltoken.Pos
+	Destroy      bool
+	Location     hcltoken.Pos
+}
+
+type CheckDeclaration struct {
+	Kind       string
+	Name       string
+	Conditions []ConditionDeclaration
+	Location   hcltoken.Pos
+}
+
 type ReferenceList struct {
 	Name      string
+	Kind      string
 	Locations []hcltoken.Pos
 }
 
-type Error struct {
-	Message  string
-	Location hcltoken.Pos
-}
-
+// Index holds what was collected from the files of a module. Collecting,
+// updating, resolving and analyzing lock the index, so they may be called
+// from several goroutines at once. Queries only read the index and must not
+// run while it changes, query a Snapshot instead while the index is updated
 type Index struct {
-	Version    string
-	Errors     []Error
-	Variables  []VariableDeclaration
-	Resources  []ResourceDeclaration
-	Outputs    []OutputDeclaration
-	References map[string]ReferenceList
-	RawAst     *hclast.File
+	Version       string
+	Diagnostics   []Diagnostic
+	Variables     []VariableDeclaration
+	Resources     []ResourceDeclaration
+	Data          []DataDeclaration
+	Modules       []ModuleDeclaration
+	Outputs       []OutputDeclaration
+	Locals        []LocalDeclaration
+	Settings      []SettingsDeclaration
+	Imports       []ImportDeclaration
+	Checks        []CheckDeclaration
+	Removed       []RemovedDeclaration
+	Assignments   []VariableAssignment
+	Overrides     []Override
+	Children      map[string]*Index
+	References    map[string]ReferenceList
+	FunctionCalls map[string]FunctionCallList
+	Resolved      []Resolution
+	UsageCounts   map[string]Usage
+	RawAst        *hclast.File
+
+	collectedDiagnostics []Diagnostic
+	dependencies         map[string][]reference
+	pendingOverrides     []*Index
+	tokens               map[string][]SemanticToken
+	links                map[string][]DocumentLink
+	folds                map[string][]FoldingRange
+	stamps               map[string]FileStamp
+	names                []symbolName
+	mutex                *sync.Mutex
+	shared               bool
 }
 
-const INDEX_VERSION = "1.0.0"
+const INDEX_VERSION = "2.0.0"
 
 func NewIndex() *Index {
 	index := new(Index)
 	index.Version = INDEX_VERSION
-	index.Errors = []Error{}
+	index.Diagnostics = []Diagnostic{}
 	index.Variables = []VariableDeclaration{}
 	index.Resources = []ResourceDeclaration{}
+	index.Data = []DataDeclaration{}
+	index.Modules = []ModuleDeclaration{}
 	index.Outputs = []OutputDeclaration{}
+	index.Locals = []LocalDeclaration{}
+	index.Settings = []SettingsDeclaration{}
+	index.Imports = []ImportDeclaration{}
+	index.Checks = []CheckDeclaration{}
+	index.Removed = []RemovedDeclaration{}
+	index.Assignments = []VariableAssignment{}
+	index.Overrides = []Override{}
+	index.Children = map[string]*Index{}
 	index.References = map[string]ReferenceList{}
+	index.FunctionCalls = map[string]FunctionCallList{}
+	index.Resolved = []Resolution{}
+	index.UsageCounts = map[string]Usage{}
 	index.RawAst = nil
+	index.collectedDiagnostics = []Diagnostic{}
+	index.dependencies = map[string][]reference{}
+	index.pendingOverrides = []*Index{}
+	index.tokens = map[string][]SemanticToken{}
+	index.links = map[string][]DocumentLink{}
+	index.folds = map[string][]FoldingRange{}
+	index.stamps = map[string]FileStamp{}
+	index.mutex = &sync.Mutex{}
 	return index
 }
 
 func (index *Index) Collect(astFile *hclast.File, path string, includeRaw bool) error {
-	hclast.Walk(astFile.Node, func(current hclast.Node) (hclast.Node, bool) {
-		switch current.(type) {
-		case *hclast.ObjectList:
-			{
-				index.handleObjectList(current.(*hclast.ObjectList), path)
-				break
-			}
+	index.lock()
+	defer index.mutex.Unlock()
+	return index.collect(astFile, path, includeRaw)
+}
 
-		case *hclast.LiteralType:
-			{
-				index.handleLiteral(current.(*hclast.LiteralType), path)
-				break
+func (index *Index) collect(astFile *hclast.File, path string, includeRaw bool) error {
+	// only the items of the file declare anything, nested ones like the
+	// `data` attribute of a config map are values. Dynamic blocks are nested
+	// within the declarations
+	if list, ok := astFile.Node.(*hclast.ObjectList); ok {
+		index.handleObjectList(list, path)
+	}
+	hclast.Walk(astFile.Node, func(current hclast.Node) (hclast.Node, bool) {
+		if item, ok := current.(*hclast.ObjectItem); ok {
+			if len(item.Keys) > 0 && item.Keys[0].Token.Type == hcltoken.IDENT && item.Keys[0].Token.Text == "dynamic" {
+				index.handleDynamic(item, path)
 			}
+			index.checkInterpolationOnly(item, path)
 		}
 
 		return current, true
 	})
+	constraints := blockAttributeLiterals(astFile.Node, "variable", "type")
+	addresses := map[*hclast.LiteralType]bool{}
+	for blockType, name := range addressAttributes {
+		for literal := range blockAttributeLiterals(astFile.Node, blockType, name) {
+			addresses[literal] = true
+		}
+	}
+	walkLiterals(astFile.Node, nil, func(literal *hclast.LiteralType, bound []string) {
+		if !addresses[literal] {
+			index.handleLiteral(literal, path, bound, constraints[literal])
+		}
+	})
+	if list, ok := astFile.Node.(*hclast.ObjectList); ok {
+		index.addASTTokens(list, path)
+		index.addASTFoldingRanges(list, path)
+	}
+	index.addIndexTokens(path)
 
 	if includeRaw {
 		index.RawAst = astFile
@@ -87,27 +291,78 @@ func (index *Index) Collect(astFile *hclast.File, path string, includeRaw bool)
 	return nil
 }
 
+// CollectString collects a file using the hcl/v2 (Terraform 0.12+) syntax,
+// falling back to HCL1 for legacy files hcl/v2 cannot parse. The raw AST is
+// an HCL1 AST, so includeRaw always uses the HCL1 collector. Variable
+// definitions files are collected as assignments instead, override files are
+// held back until ApplyOverrides merges them into their base declarations
 func (index *Index) CollectString(contents []byte, path string, includeRaw bool) error {
-	astFile, err := hcl.ParseBytes(contents)
-	if err != nil {
-		index.Errors = append(index.Errors, makeError(err, path))
-		return err
+	index.lock()
+	defer index.mutex.Unlock()
+	return index.addString(contents, path, includeRaw)
+}
+
+// addString is CollectString for callers holding the mutex
+func (index *Index) addString(contents []byte, path string, includeRaw bool) error {
+	if isOverrideFile(path) {
+		override := NewIndex()
+		index.pendingOverrides = append(index.pendingOverrides, override)
+		return override.collectString(contents, path, includeRaw)
 	}
 
-	return index.Collect(astFile, path, includeRaw)
+	return index.collectString(contents, path, includeRaw)
 }
 
-func makeError(err error, path string) Error {
-	if posError, ok := err.(*hclparser.PosError); ok {
-		return Error{
-			Message:  posError.Err.Error(),
-			Location: posError.Pos,
+// CollectFile indexes a single file on its own. Override files are collected
+// as they are instead of being merged and only the diagnostics found while
+// collecting the file are reported
+func CollectFile(contents []byte, path string, includeRaw bool) (*Index, error) {
+	index := NewIndex()
+	err := index.collectString(contents, path, includeRaw)
+	return index, err
+}
+
+func (index *Index) collectString(contents []byte, path string, includeRaw bool) error {
+	if err := tooLarge(contents, path); err != nil {
+		index.skipFile(err)
+		return err
+	}
+
+	if isVariablesFile(path) {
+		return index.collectVariables(contents, path)
+	}
+
+	if !includeRaw {
+		if isJSONConfig(path, contents) {
+			return index.collectJSON(contents, path)
+		}
+
+		if _, diagnostics := hclsyntax.ParseConfig(contents, path, hcl2.Pos{Line: 1, Column: 1}); !diagnostics.HasErrors() {
+			return index.collectHCL2(contents, path)
+		}
+
+		if _, err := hcl.ParseBytes(contents); err != nil {
+			return index.collectHCL2(contents, path)
 		}
 	}
 
-	return Error{
-		Message: err.Error(),
+	return index.collectHCL1(contents, path, includeRaw)
+}
+
+func (index *Index) CollectHCL1(contents []byte, path string, includeRaw bool) error {
+	index.lock()
+	defer index.mutex.Unlock()
+	return index.collectHCL1(contents, path, includeRaw)
+}
+
+func (index *Index) collectHCL1(contents []byte, path string, includeRaw bool) error {
+	astFile, err := hcl.ParseBytes(contents)
+	if err != nil {
+		index.addCollectedDiagnostic(makeDiagnostic(err, path))
+		return err
 	}
+
+	return index.collect(astFile, path, includeRaw)
 }
 
 func getText(t hcltoken.Token) string {
@@ -120,6 +375,130 @@ func getPos(t hcltoken.Token, path string) hcltoken.Pos {
 	return location
 }
 
+func getObject(node hclast.Node) (*hclast.ObjectType, bool) {
+	object, ok := node.(*hclast.ObjectType)
+	return object, ok
+}
+
+func findItem(object *hclast.ObjectType, key string) *hclast.ObjectItem {
+	for _, item := range object.List.Items {
+		if len(item.Keys) > 0 && getText(item.Keys[0].Token) == key {
+			return item
+		}
+	}
+
+	return nil
+}
+
+func findItems(object *hclast.ObjectType, key string) []*hclast.ObjectItem {
+	items := []*hclast.ObjectItem{}
+	for _, item := range object.List.Items {
+		if len(item.Keys) > 0 && getText(item.Keys[0].Token) == key {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func getLiteralText(node hclast.Node) string {
+	if literal, ok := node.(*hclast.LiteralType); ok {
+		return getText(literal.Token)
+	}
+
+	return ""
+}
+
+func getLiteralList(node hclast.Node) []string {
+	values := []string{}
+	switch node.(type) {
+	case *hclast.ListType:
+		{
+			for _, element := range node.(*hclast.ListType).List {
+				values = append(values, getLiteralText(element))
+			}
+			break
+		}
+
+	case *hclast.LiteralType:
+		{
+			values = append(values, getLiteralText(node))
+			break
+		}
+	}
+
+	return values
+}
+
+func getLiteralBool(node hclast.Node, fallback bool) bool {
+	switch getLiteralText(node) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	return fallback
+}
+
+func getExpressionText(node hclast.Node) string {
+	if literal, ok := node.(*hclast.LiteralType); ok {
+		return getText(literal.Token)
+	}
+
+	var buffer bytes.Buffer
+	if err := hclprinter.Fprint(&buffer, node); err != nil {
+		return ""
+	}
+	return buffer.String()
+}
+
+func getExpression(item *hclast.ObjectItem, path string) *Expression {
+	if item == nil {
+		return nil
+	}
+
+	return &Expression{
+		Text:       getExpressionText(item.Val),
+		References: nodeReferences(item.Val, path),
+		Location:   getPos(item.Keys[0].Token, path),
+	}
+}
+
+func (index *Index) handleDependsOn(object *hclast.ObjectType, path string) []Dependency {
+	dependencies := []Dependency{}
+	item := findItem(object, "depends_on")
+	if item == nil {
+		return dependencies
+	}
+
+	list, ok := item.Val.(*hclast.ListType)
+	if !ok {
+		return dependencies
+	}
+
+	for _, element := range list.List {
+		literal, ok := element.(*hclast.LiteralType)
+		if !ok {
+			continue
+		}
+
+		location := getPos(literal.Token, path)
+		if strings.HasPrefix(literal.Token.Text, "\"") {
+			location = literalSubPos(literal.Token.Text, literal.Token.Pos, 1, path)
+		}
+
+		dependency := Dependency{
+			Address:  getText(literal.Token),
+			Location: location,
+		}
+		dependencies = append(dependencies, dependency)
+		index.addReference(dependency.Address, dependency.Location)
+	}
+
+	return dependencies
+}
+
 func (index *Index) handleObjectList(objectList *hclast.ObjectList, path string) {
 	for _, item := range objectList.Items {
 		firstToken := item.Keys[0].Token
@@ -130,38 +509,539 @@ func (index *Index) handleObjectList(objectList *hclast.ObjectList, path string)
 		switch firstToken.Text {
 		case "variable":
 			{
-				variable := VariableDeclaration{
-					Name:     getText(item.Keys[1].Token),
-					Location: getPos(item.Keys[1].Token, path),
-				}
-				index.Variables = append(index.Variables, variable)
+				index.handleVariable(item, path)
 				break
 			}
 
 		case "resource":
 			{
-				resource := ResourceDeclaration{
-					Name:     getText(item.Keys[2].Token),
-					Type:     getText(item.Keys[1].Token),
-					Location: getPos(item.Keys[2].Token, path), // return position of name
+				index.handleResource(item, RESOURCE_KIND_MANAGED, path)
+				break
+			}
+
+		case "ephemeral":
+			{
+				index.handleResource(item, RESOURCE_KIND_EPHEMERAL, path)
+				break
+			}
+
+		case "data":
+			{
+				if len(item.Keys) < 3 {
+					break
+				}
+
+				data := DataDeclaration{
+					Name:          getText(item.Keys[2].Token),
+					Type:          getText(item.Keys[1].Token),
+					Location:      getPos(item.Keys[2].Token, path), // return position of name
+					Blocks:        []NestedBlock{},
+					BlockLocation: getPos(item.Keys[0].Token, path),
+					EndLocation:   getEndPos(item.Val, path),
+				}
+				if object, ok := getObject(item.Val); ok {
+					data.Blocks = getNestedBlocks(object, path)
 				}
-				index.Resources = append(index.Resources, resource)
+				index.addKeyLink(LINK_DATA_TYPE, item.Keys[1].Token, path)
+				index.Data = append(index.Data, data)
+				index.addDependencies(data.Address(), item.Val, path)
+				break
+			}
+
+		case "module":
+			{
+				index.handleModule(item, path)
 				break
 			}
 
 		case "output":
 			{
-				output := OutputDeclaration{
-					Name:     getText(item.Keys[1].Token),
-					Location: getPos(item.Keys[1].Token, path),
+				index.handleOutput(item, path)
+				break
+			}
+
+		case "locals":
+			{
+				object, ok := item.Val.(*hclast.ObjectType)
+				if !ok {
+					break
+				}
+
+				for _, localItem := range object.List.Items {
+					local := LocalDeclaration{
+						Name:        getText(localItem.Keys[0].Token),
+						Location:    getPos(localItem.Keys[0].Token, path),
+						EndLocation: getEndPos(localItem.Val, path),
+					}
+					index.Locals = append(index.Locals, local)
+					index.addDependencies(local.Address(), localItem.Val, path)
 				}
-				index.Outputs = append(index.Outputs, output)
+				break
+			}
+
+		case "terraform":
+			{
+				index.handleSettings(item, path)
+				break
+			}
+
+		case "check":
+			{
+				index.handleCheck(item, path)
+				break
+			}
+
+		case "import":
+			{
+				index.handleImport(item, path)
+				break
+			}
+
+		case "removed":
+			{
+				index.handleRemoved(item, path)
 				break
 			}
 		}
 	}
 }
 
+func (index *Index) handleVariable(item *hclast.ObjectItem, path string) {
+	variable := VariableDeclaration{
+		Name:          getText(item.Keys[1].Token),
+		Nullable:      true, // terraform defaults nullable to true
+		Required:      true, // until a default is found
+		Validations:   []ValidationDeclaration{},
+		Location:      getPos(item.Keys[1].Token, path),
+		BlockLocation: getPos(item.Keys[0].Token, path),
+		EndLocation:   getEndPos(item.Val, path),
+	}
+
+	if object, ok := getObject(item.Val); ok {
+		if variableType := findItem(object, "type"); variableType != nil {
+			variable.Type = getExpressionText(variableType.Val)
+			variable.TypeConstraint = parseTypeConstraint(variable.Type)
+		}
+		if defaultValue := findItem(object, "default"); defaultValue != nil {
+			variable.Default = getExpressionText(defaultValue.Val)
+			variable.Required = false
+		}
+		if description := findItem(object, "description"); description != nil {
+			variable.Description = getLiteralText(description.Val)
+		}
+		if sensitive := findItem(object, "sensitive"); sensitive != nil {
+			variable.Sensitive = getLiteralBool(sensitive.Val, false)
+		}
+		if nullable := findItem(object, "nullable"); nullable != nil {
+			variable.Nullable = getLiteralBool(nullable.Val, true)
+			variable.nullableSet = true
+		}
+		for _, validationItem := range findItems(object, "validation") {
+			validation := ValidationDeclaration(getCondition(validationItem, path))
+			variable.Validations = append(variable.Validations, validation)
+		}
+	}
+
+	index.Variables = append(index.Variables, variable)
+}
+
+func (index *Index) handleResource(item *hclast.ObjectItem, kind string, path string) {
+	if len(item.Keys) < 3 {
+		return
+	}
+
+	resource := ResourceDeclaration{
+		Kind:          kind,
+		DependsOn:     []Dependency{},
+		Name:          getText(item.Keys[2].Token),
+		Type:          getText(item.Keys[1].Token),
+		Location:      getPos(item.Keys[2].Token, path), // return position of name
+		Blocks:        []NestedBlock{},
+		BlockLocation: getPos(item.Keys[0].Token, path),
+		EndLocation:   getEndPos(item.Val, path),
+	}
+
+	index.addKeyLink(resourceLinkKind(kind), item.Keys[1].Token, path)
+	if object, ok := getObject(item.Val); ok {
+		resource.Blocks = getNestedBlocks(object, path)
+		resource.Count = getExpression(findItem(object, "count"), path)
+		resource.ForEach = getExpression(findItem(object, "for_each"), path)
+		resource.DependsOn = index.handleDependsOn(object, path)
+
+		address := resource.Address()
+		for _, block := range append(findItems(object, "provisioner"), findItems(object, "connection")...) {
+			index.handleSelfReferences(block.Val, address, path)
+		}
+		if lifecycle := findItem(object, "lifecycle"); lifecycle != nil {
+			resource.Lifecycle = getLifecycle(lifecycle, path)
+			if lifecycleObject, ok := getObject(lifecycle.Val); ok {
+				index.handleConditions(lifecycleObject, address, path)
+			}
+		}
+	}
+
+	index.Resources = append(index.Resources, resource)
+	index.addDependencies(resource.Address(), item.Val, path)
+	for _, dependency := range resource.DependsOn {
+		index.dependencies[resource.Address()] = append(index.dependencies[resource.Address()],
+			reference{Name: dependency.Address, Location: dependency.Location})
+	}
+}
+
+// handleSelfReferences records `self.*` references as references to the
+// enclosing resource
+func (index *Index) handleSelfReferences(node hclast.Node, address string, path string) {
+	getSelfKeys := func(name string) []string {
+		if !strings.HasPrefix(name, "self.") {
+			return nil
+		}
+		return []string{address}
+	}
+
+	index.addNodeReferences(node, path, getSelfKeys)
+}
+
+func getLifecycle(item *hclast.ObjectItem, path string) *LifecycleDeclaration {
+	lifecycle := &LifecycleDeclaration{
+		IgnoreChanges: []string{},
+		Location:      getPos(item.Keys[0].Token, path),
+	}
+
+	object, ok := getObject(item.Val)
+	if !ok {
+		return lifecycle
+	}
+
+	if preventDestroy := findItem(object, "prevent_destroy"); preventDestroy != nil {
+		lifecycle.PreventDestroy = getLiteralBool(preventDestroy.Val, false)
+		lifecycle.PreventDestroyLocation = getPos(preventDestroy.Keys[0].Token, path)
+	}
+	if createBeforeDestroy := findItem(object, "create_before_destroy"); createBeforeDestroy != nil {
+		lifecycle.CreateBeforeDestroy = getLiteralBool(createBeforeDestroy.Val, false)
+		lifecycle.CreateBeforeDestroyLocation = getPos(createBeforeDestroy.Keys[0].Token, path)
+	}
+	if ignoreChanges := findItem(object, "ignore_changes"); ignoreChanges != nil {
+		lifecycle.IgnoreChanges = getLiteralList(ignoreChanges.Val)
+		lifecycle.IgnoreChangesLocation = getPos(ignoreChanges.Keys[0].Token, path)
+	}
+
+	return lifecycle
+}
+
+func (index *Index) handleModule(item *hclast.ObjectItem, path string) {
+	if len(item.Keys) < 2 {
+		return
+	}
+
+	module := ModuleDeclaration{
+		Name:          getText(item.Keys[1].Token),
+		SourceKind:    MODULE_SOURCE_UNKNOWN,
+		DependsOn:     []Dependency{},
+		Arguments:     []ModuleArgument{},
+		Location:      getPos(item.Keys[1].Token, path),
+		BlockLocation: getPos(item.Keys[0].Token, path),
+		EndLocation:   getEndPos(item.Val, path),
+	}
+
+	if object, ok := getObject(item.Val); ok {
+		if source := findItem(object, "source"); source != nil {
+			module.Source = getLiteralText(source.Val)
+			module.SourceKind = ClassifyModuleSource(module.Source)
+			module.SourceLocation = getPos(source.Keys[0].Token, path)
+			if literal, ok := source.Val.(*hclast.LiteralType); ok {
+				index.addKeyLink(LINK_MODULE_SOURCE, literal.Token, path)
+			}
+		}
+		if version := findItem(object, "version"); version != nil {
+			module.Version = getLiteralText(version.Val)
+		}
+		module.Count = getExpression(findItem(object, "count"), path)
+		module.ForEach = getExpression(findItem(object, "for_each"), path)
+		module.DependsOn = index.handleDependsOn(object, path)
+
+		for _, argument := range object.List.Items {
+			name := getText(argument.Keys[0].Token)
+			if !moduleMetaArguments[name] {
+				module.Arguments = append(module.Arguments, ModuleArgument{
+					Name:     name,
+					Location: getPos(argument.Keys[0].Token, path),
+				})
+			}
+		}
+	}
+
+	index.Modules = append(index.Modules, module)
+	index.addDependencies(module.Address(), item.Val, path)
+}
+
+func (index *Index) handleOutput(item *hclast.ObjectItem, path string) {
+	output := OutputDeclaration{
+		Name:          getText(item.Keys[1].Token),
+		References:    []string{},
+		DependsOn:     []Dependency{},
+		Location:      getPos(item.Keys[1].Token, path),
+		BlockLocation: getPos(item.Keys[0].Token, path),
+		EndLocation:   getEndPos(item.Val, path),
+	}
+
+	if object, ok := getObject(item.Val); ok {
+		if value := findItem(object, "value"); value != nil {
+			output.Value = getExpressionText(value.Val)
+			output.ValueLocation = getPos(value.Keys[0].Token, path)
+			output.valueReferences = findNodeReferences(value.Val, path)
+			output.References = uniqueReferenceNames(output.valueReferences)
+		}
+		output.DependsOn = index.handleDependsOn(object, path)
+		index.handleConditions(object, "output."+output.Name, path)
+	}
+
+	index.Outputs = append(index.Outputs, output)
+	index.addDependencies(output.Address(), item.Val, path)
+	for _, dependency := range output.DependsOn {
+		index.dependencies[output.Address()] = append(index.dependencies[output.Address()],
+			reference{Name: dependency.Address, Location: dependency.Location})
+	}
+}
+
+func getCondition(item *hclast.ObjectItem, path string) ConditionDeclaration {
+	condition := ConditionDeclaration{
+		References: []string{},
+		Location:   getPos(item.Keys[0].Token, path),
+	}
+
+	object, ok := getObject(item.Val)
+	if !ok {
+		return condition
+	}
+
+	if conditionItem := findItem(object, "condition"); conditionItem != nil {
+		condition.Condition = getExpressionText(conditionItem.Val)
+		condition.References = nodeReferences(conditionItem.Val, path)
+	}
+	if errorMessage := findItem(object, "error_message"); errorMessage != nil {
+		condition.ErrorMessage = getLiteralText(errorMessage.Val)
+	}
+
+	return condition
+}
+
+// handleConditions collects the precondition and postcondition blocks of the
+// declaration named by address
+func (index *Index) handleConditions(object *hclast.ObjectType, address string, path string) {
+	for _, kind := range []string{"precondition", "postcondition"} {
+		for _, item := range findItems(object, kind) {
+			index.Checks = append(index.Checks, CheckDeclaration{
+				Kind:       kind,
+				Name:       address,
+				Conditions: []ConditionDeclaration{getCondition(item, path)},
+				Location:   getPos(item.Keys[0].Token, path),
+			})
+		}
+	}
+}
+
+func (index *Index) handleCheck(item *hclast.ObjectItem, path string) {
+	if len(item.Keys) < 2 {
+		return
+	}
+
+	check := CheckDeclaration{
+		Kind:       "check",
+		Name:       getText(item.Keys[1].Token),
+		Conditions: []ConditionDeclaration{},
+		Location:   getPos(item.Keys[1].Token, path),
+	}
+
+	if object, ok := getObject(item.Val); ok {
+		for _, assert := range findItems(object, "assert") {
+			check.Conditions = append(check.Conditions, getCondition(assert, path))
+		}
+	}
+
+	index.Checks = append(index.Checks, check)
+}
+
+func (index *Index) handleImport(item *hclast.ObjectItem, path string) {
+	object, ok := getObject(item.Val)
+	if !ok {
+		return
+	}
+
+	declaration := ImportDeclaration{
+		References: []string{},
+		Location:   getPos(item.Keys[0].Token, path),
+	}
+	if to := findItem(object, "to"); to != nil {
+		declaration.To = getExpressionText(to.Val)
+		declaration.ToLocation = getPos(to.Keys[0].Token, path)
+	}
+	if id := findItem(object, "id"); id != nil {
+		declaration.ID = getExpressionText(id.Val)
+		declaration.References = nodeReferences(id.Val, path)
+	}
+
+	index.Imports = append(index.Imports, declaration)
+}
+
+func (index *Index) handleRemoved(item *hclast.ObjectItem, path string) {
+	object, ok := getObject(item.Val)
+	if !ok {
+		return
+	}
+
+	removed := RemovedDeclaration{
+		Destroy:  true, // terraform destroys removed objects unless told otherwise
+		Location: getPos(item.Keys[0].Token, path),
+	}
+	if from := findItem(object, "from"); from != nil {
+		removed.From = getExpressionText(from.Val)
+		removed.FromLocation = getPos(from.Keys[0].Token, path)
+	}
+	if lifecycle := findItem(object, "lifecycle"); lifecycle != nil {
+		if lifecycleObject, ok := getObject(lifecycle.Val); ok {
+			if destroy := findItem(lifecycleObject, "destroy"); destroy != nil {
+				removed.Destroy = getLiteralBool(destroy.Val, true)
+			}
+		}
+	}
+
+	index.Removed = append(index.Removed, removed)
+}
+
+// dynamicContent returns the content of a dynamic block and the name of its
+// iterator
+func dynamicContent(item *hclast.ObjectItem) (*hclast.ObjectItem, string, bool) {
+	object, ok := getObject(item.Val)
+	if !ok || len(item.Keys) < 2 || getText(item.Keys[0].Token) != "dynamic" {
+		return nil, "", false
+	}
+
+	iterator := getText(item.Keys[1].Token)
+	if iteratorItem := findItem(object, "iterator"); iteratorItem != nil {
+		iterator = getLiteralText(iteratorItem.Val)
+	}
+
+	content := findItem(object, "content")
+	return content, iterator, content != nil
+}
+
+// walkLiterals calls visit with every literal within node and the iterators
+// of the dynamic blocks whose content encloses it
+func walkLiterals(node hclast.Node, bound []string, visit func(*hclast.LiteralType, []string)) {
+	switch node.(type) {
+	case *hclast.ObjectList:
+		{
+			for _, item := range node.(*hclast.ObjectList).Items {
+				walkLiterals(item, bound, visit)
+			}
+			break
+		}
+
+	case *hclast.ObjectItem:
+		{
+			item := node.(*hclast.ObjectItem)
+			if content, iterator, ok := dynamicContent(item); ok {
+				object, _ := getObject(item.Val)
+				for _, nested := range object.List.Items {
+					if nested == content {
+						walkLiterals(nested, append(bound[:len(bound):len(bound)], iterator), visit)
+					} else {
+						walkLiterals(nested, bound, visit)
+					}
+				}
+				break
+			}
+			walkLiterals(item.Val, bound, visit)
+			break
+		}
+
+	case *hclast.ObjectType:
+		{
+			walkLiterals(node.(*hclast.ObjectType).List, bound, visit)
+			break
+		}
+
+	case *hclast.ListType:
+		{
+			for _, element := range node.(*hclast.ListType).List {
+				walkLiterals(element, bound, visit)
+			}
+			break
+		}
+
+	case *hclast.LiteralType:
+		{
+			visit(node.(*hclast.LiteralType), bound)
+			break
+		}
+	}
+}
+
+func (index *Index) handleDynamic(item *hclast.ObjectItem, path string) {
+	content, iterator, ok := dynamicContent(item)
+	if !ok {
+		return
+	}
+
+	// for_each and var.* references in content are picked up by the regular
+	// walk, which skips the iterator since it needs the enclosing block as
+	// context
+	walkLiterals(content.Val, nil, func(literal *hclast.LiteralType, bound []string) {
+		text, pos := literalTemplate(literal.Token, path)
+		references, err := parseReferences(text, pos, getUnboundKeys(bound, getIteratorKeys(iterator)))
+		if err == nil {
+			for _, reference := range references {
+				index.addReferenceOfKind(reference.Name, REFERENCE_KIND_ITERATOR, reference.Location)
+			}
+		}
+	})
+}
+
+func (index *Index) handleSettings(item *hclast.ObjectItem, path string) {
+	object, ok := getObject(item.Val)
+	if !ok {
+		return
+	}
+
+	settings := SettingsDeclaration{
+		RequiredProviders: []ProviderRequirement{},
+		Location:          getPos(item.Keys[0].Token, path),
+	}
+
+	if version := findItem(object, "required_version"); version != nil {
+		settings.RequiredVersion = getLiteralText(version.Val)
+		settings.RequiredVersionLocation = getPos(version.Keys[0].Token, path)
+	}
+
+	if providers := findItem(object, "required_providers"); providers != nil {
+		if providersObject, ok := getObject(providers.Val); ok {
+			for _, providerItem := range providersObject.List.Items {
+				provider := ProviderRequirement{
+					Name:     getText(providerItem.Keys[0].Token),
+					Location: getPos(providerItem.Keys[0].Token, path),
+				}
+
+				// required_providers accepts both `aws = "~> 1.0"` and
+				// `aws = { source = "...", version = "..." }`
+				if providerObject, ok := getObject(providerItem.Val); ok {
+					if source := findItem(providerObject, "source"); source != nil {
+						provider.Source = getLiteralText(source.Val)
+					}
+					if version := findItem(providerObject, "version"); version != nil {
+						provider.Version = getLiteralText(version.Val)
+					}
+				} else {
+					provider.Version = getLiteralText(providerItem.Val)
+				}
+
+				settings.RequiredProviders = append(settings.RequiredProviders, provider)
+			}
+		}
+	}
+
+	index.Settings = append(index.Settings, settings)
+}
+
 func literalSubPos(text string, pos hcltoken.Pos, start int, path string) hcltoken.Pos {
 	for index, char := range text {
 		if index == start {
@@ -182,60 +1062,219 @@ func literalSubPos(text string, pos hcltoken.Pos, start int, path string) hcltok
 	return pos
 }
 
-func toHilPos(pos hcltoken.Pos) hilast.Pos {
-	return hilast.Pos{
-		Column:   pos.Column,
-		Line:     pos.Line,
-		Filename: pos.Filename,
+// textPos converts a hil position within text, which starts at start, into
+// a position which also carries the byte offset that hil does not track
+func textPos(text string, start hcltoken.Pos, target hilast.Pos) hcltoken.Pos {
+	pos := start
+	for _, char := range text {
+		if pos.Line > target.Line || (pos.Line == target.Line && pos.Column >= target.Column) {
+			break
+		}
+
+		if char == '\n' {
+			pos.Column = 1
+			pos.Line++
+		} else {
+			pos.Column++
+		}
+
+		pos.Offset += utf8.RuneLen(char)
+	}
+
+	return pos
+}
+
+// literalTemplate returns the template text of a literal and its position,
+// for heredocs this is only the body so the `<<EOF` header and the closing
+// anchor are never parsed as part of the template
+func literalTemplate(token hcltoken.Token, path string) (string, hcltoken.Pos) {
+	text := token.Text
+	pos := getPos(token, path)
+	if token.Type != hcltoken.HEREDOC {
+		return text, pos
+	}
+
+	start := strings.Index(text, "\n") + 1
+	end := strings.LastIndex(text, "\n") + 1
+	if start == 0 || end < start {
+		return text, pos
 	}
+
+	return text[start:end], literalSubPos(text, pos, start, path)
 }
 
-func toHclPos(pos hilast.Pos) hcltoken.Pos {
-	return hcltoken.Pos{
+func toHilPos(pos hcltoken.Pos) hilast.Pos {
+	return hilast.Pos{
 		Column:   pos.Column,
 		Line:     pos.Line,
 		Filename: pos.Filename,
-		Offset:   0,
 	}
 }
 
 func (index *Index) addReference(name string, pos hcltoken.Pos) {
+	index.addReferenceOfKind(name, getReferenceKind(name), pos)
+}
+
+func (index *Index) addReferenceOfKind(name string, kind string, pos hcltoken.Pos) {
 	list := index.References[name]
+	list.Name = name
+	list.Kind = kind
 	list.Locations = append(list.Locations, pos)
 	index.References[name] = list
 }
 
-func (index *Index) handleLiteral(literal *hclast.LiteralType, path string) {
-	root, err := hil.ParseWithPosition(literal.Token.Text, toHilPos(literal.Token.Pos))
+type reference struct {
+	Name     string
+	Location hcltoken.Pos
+}
+
+func parseReferences(text string, pos hcltoken.Pos, getKeys func(string) []string) ([]reference, error) {
+	root, err := hil.ParseWithPosition(text, toHilPos(pos))
 	if err != nil {
-		if parseError, ok := err.(*hilparser.ParseError); ok {
-			index.Errors = append(index.Errors, Error{
-				Message:  parseError.Message,
-				Location: toHclPos(parseError.Pos),
-			})
-		} else {
-			index.Errors = append(index.Errors, Error{
-				Message:  err.Error(),
-				Location: literal.Token.Pos,
-			})
+		if references, ok := parseTemplateReferences(text, pos, getKeys); ok {
+			return references, nil
 		}
-		return
+		return nil, err
 	}
 
+	references := []reference{}
 	root.Accept(func(node hilast.Node) hilast.Node {
 		switch node.(type) {
 		case *hilast.VariableAccess:
 			{
 				variable := node.(*hilast.VariableAccess)
-				// for now ONLY index variables:
-				if !strings.HasPrefix(variable.Name, "var.") {
-					break
+				for _, key := range getKeys(variable.Name) {
+					references = append(references, reference{
+						Name:     key,
+						Location: textPos(text, pos, variable.Pos()),
+					})
 				}
-
-				index.addReference(variable.Name, toHclPos(variable.Pos()))
 				break
 			}
 		}
 		return node
 	})
+
+	return references, nil
+}
+
+// parseTemplateReferences parses text with the hcl/v2 template parser, this
+// catches the interpolations written in 0.12+ expression syntax, like
+// conditionals over lists or null, which HIL cannot parse
+func parseTemplateReferences(text string, pos hcltoken.Pos, getKeys func(string) []string) ([]reference, bool) {
+	start := hcl2.Pos{Line: pos.Line, Column: pos.Column, Byte: pos.Offset}
+	template, diagnostics := hclsyntax.ParseTemplate([]byte(text), pos.Filename, start)
+	if diagnostics.HasErrors() {
+		return nil, false
+	}
+
+	collector := &hcl2Collector{path: pos.Filename}
+	return collector.findReferences(template, getKeys), true
+}
+
+func findNodeReferences(node hclast.Node, path string) []reference {
+	found := []reference{}
+	walkLiterals(node, nil, func(literal *hclast.LiteralType, bound []string) {
+		text, pos := literalTemplate(literal.Token, path)
+		references, err := parseReferences(text, pos, getUnboundKeys(bound, getReferenceKeys))
+		if err == nil {
+			found = append(found, references...)
+		}
+	})
+
+	return found
+}
+
+func nodeReferences(node hclast.Node, path string) []string {
+	return uniqueReferenceNames(findNodeReferences(node, path))
+}
+
+// addDependencies records the references within node as dependencies of the
+// declaration at address
+func (index *Index) addDependencies(address string, node hclast.Node, path string) {
+	index.dependencies[address] = append(index.dependencies[address], findNodeReferences(node, path)...)
+}
+
+func uniqueReferenceNames(references []reference) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, reference := range references {
+		if !seen[reference.Name] {
+			seen[reference.Name] = true
+			names = append(names, reference.Name)
+		}
+	}
+
+	return names
+}
+
+// addNodeReferences records the references within node accepted by getKeys,
+// parse errors are left to the main walk to report
+func (index *Index) addNodeReferences(node hclast.Node, path string, getKeys func(string) []string) {
+	walkLiterals(node, nil, func(literal *hclast.LiteralType, bound []string) {
+		text, pos := literalTemplate(literal.Token, path)
+		references, err := parseReferences(text, pos, getUnboundKeys(bound, getKeys))
+		if err == nil {
+			for _, reference := range references {
+				index.addReference(reference.Name, reference.Location)
+			}
+		}
+	})
+}
+
+// blockAttributeLiterals returns the literals of the attribute name of the
+// top level blocks of blockType in node, like the type constraints of the
+// variables
+func blockAttributeLiterals(node hclast.Node, blockType string, name string) map[*hclast.LiteralType]bool {
+	literals := map[*hclast.LiteralType]bool{}
+	list, ok := node.(*hclast.ObjectList)
+	if !ok {
+		return literals
+	}
+
+	for _, item := range list.Items {
+		if len(item.Keys) == 0 || getText(item.Keys[0].Token) != blockType {
+			continue
+		}
+		if object, ok := getObject(item.Val); ok {
+			if attribute := findItem(object, name); attribute != nil {
+				walkLiterals(attribute.Val, nil, func(literal *hclast.LiteralType, bound []string) {
+					literals[literal] = true
+				})
+			}
+		}
+	}
+
+	return literals
+}
+
+// handleLiteral records the references and function calls of a literal,
+// bound are the iterators of the dynamic blocks enclosing it. The type
+// constraints of variables only look like calls, constraint leaves them out
+func (index *Index) handleLiteral(literal *hclast.LiteralType, path string, bound []string, constraint bool) {
+	text, pos := literalTemplate(literal.Token, path)
+	references, err := parseReferences(text, pos, getUnboundKeys(bound, getReferenceKeys))
+	if err != nil {
+		diagnostic := Diagnostic{
+			Severity:         SEVERITY_ERROR,
+			Code:             CODE_INTERPOLATION_ERROR,
+			Message:          err.Error(),
+			Location:         pos,
+			RelatedLocations: []hcltoken.Pos{},
+		}
+		if parseError, ok := err.(*hilparser.ParseError); ok {
+			diagnostic.Message = parseError.Message
+			diagnostic.Location = textPos(text, pos, parseError.Pos)
+		}
+		diagnostic.EndLocation = diagnostic.Location
+		index.addCollectedDiagnostic(diagnostic)
+		return
+	}
+
+	for _, reference := range references {
+		index.addReference(reference.Name, reference.Location)
+	}
+	if !constraint {
+		index.addLiteralFunctionCalls(text, pos)
+	}
 }
@@ -0,0 +1,707 @@
+package index
+
+import (
+	"sort"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	hcljson "github.com/hashicorp/hcl/v2/json"
+)
+
+// jsonCollector collects a single file in the JSON configuration syntax
+// (*.tf.json). JSON bodies carry no block structure of their own, so every
+// block is decoded through a schema and references are found by parsing the
+// string templates within it. bound holds the locations of the references to
+// the iterators of dynamic blocks and of the traversals within address
+// attributes, which are skipped by the other walks
+type jsonCollector struct {
+	index    *Index
+	path     string
+	contents []byte
+	bound    map[hcltoken.Pos]bool
+}
+
+var jsonRootSchema = &hcl2.BodySchema{
+	Blocks: []hcl2.BlockHeaderSchema{
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "resource", LabelNames: []string{"type", "name"}},
+		{Type: "ephemeral", LabelNames: []string{"type", "name"}},
+		{Type: "data", LabelNames: []string{"type", "name"}},
+		{Type: "module", LabelNames: []string{"name"}},
+		{Type: "output", LabelNames: []string{"name"}},
+		{Type: "locals"},
+		{Type: "terraform"},
+		{Type: "check", LabelNames: []string{"name"}},
+		{Type: "import"},
+		{Type: "removed"},
+	},
+}
+
+var jsonConditionSchemas = []hcl2.BlockHeaderSchema{
+	{Type: "precondition"},
+	{Type: "postcondition"},
+}
+
+// isJSONConfig reports whether a file should be read with the JSON
+// configuration syntax, either by its extension or by its contents
+func isJSONConfig(path string, contents []byte) bool {
+	if strings.HasSuffix(path, ".json") {
+		return true
+	}
+
+	return strings.HasPrefix(strings.TrimSpace(string(contents)), "{")
+}
+
+func (index *Index) CollectJSON(contents []byte, path string) error {
+	index.lock()
+	defer index.mutex.Unlock()
+	return index.collectJSON(contents, path)
+}
+
+func (index *Index) collectJSON(contents []byte, path string) error {
+	file, diagnostics := hcljson.Parse(contents, path)
+	if diagnostics.HasErrors() {
+		index.addHCL2Diagnostics(diagnostics, path)
+		return diagnostics
+	}
+
+	collector := &jsonCollector{
+		index:    index,
+		path:     path,
+		contents: contents,
+		bound:    map[hcltoken.Pos]bool{},
+	}
+	collector.collectBody(file.Body)
+	return nil
+}
+
+func (c *jsonCollector) pos(pos hcl2.Pos) hcltoken.Pos {
+	return toTokenPos(pos, c.path)
+}
+
+// blockEnd returns the position just past the object of a block, nested
+// blocks are not recorded for JSON since telling them from object attributes
+// needs the provider schema
+func (c *jsonCollector) blockEnd(block *hcl2.Block) hcltoken.Pos {
+	return c.pos(block.Body.MissingItemRange().End)
+}
+
+func (c *jsonCollector) addLink(kind string, text string, r hcl2.Range) {
+	c.index.addLink(kind, text, c.pos(r.Start), c.pos(r.End))
+}
+
+// exprText returns the source text of expr, strings are returned without
+// their quotes to match the other collectors
+func (c *jsonCollector) exprText(expr hcl2.Expression) string {
+	r := expr.Range()
+	if r.Start.Byte < 0 || r.End.Byte > len(c.contents) || r.Start.Byte > r.End.Byte {
+		return ""
+	}
+
+	text := string(c.contents[r.Start.Byte:r.End.Byte])
+	if len(text) >= 2 && strings.HasPrefix(text, "\"") && strings.HasSuffix(text, "\"") {
+		return text[1 : len(text)-1]
+	}
+
+	return text
+}
+
+func (c *jsonCollector) content(body hcl2.Body, attributes []string, blocks ...hcl2.BlockHeaderSchema) *hcl2.BodyContent {
+	schema := &hcl2.BodySchema{Blocks: blocks}
+	for _, name := range attributes {
+		schema.Attributes = append(schema.Attributes, hcl2.AttributeSchema{Name: name})
+	}
+
+	content, _, _ := body.PartialContent(schema)
+	return content
+}
+
+func jsonAttributes(body hcl2.Body) []*hcl2.Attribute {
+	attributes, _ := body.JustAttributes()
+	return sortedHCL2Attributes(attributes)
+}
+
+func sortedHCL2Attributes(attributeMap hcl2.Attributes) []*hcl2.Attribute {
+	attributes := make([]*hcl2.Attribute, 0, len(attributeMap))
+	for _, attribute := range attributeMap {
+		attributes = append(attributes, attribute)
+	}
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].NameRange.Start.Byte < attributes[j].NameRange.Start.Byte
+	})
+
+	return attributes
+}
+
+func jsonBlocks(content *hcl2.BodyContent, blockType string) []*hcl2.Block {
+	blocks := []*hcl2.Block{}
+	for _, block := range content.Blocks {
+		if block.Type == blockType {
+			blocks = append(blocks, block)
+		}
+	}
+
+	return blocks
+}
+
+func (c *jsonCollector) findExprReferences(expr hcl2.Expression, getKeys func(string) []string) []reference {
+	found := []reference{}
+	for _, traversal := range expr.Variables() {
+		location := c.pos(traversal.SourceRange().Start)
+		if c.bound[location] {
+			continue
+		}
+
+		for _, key := range getKeys(traversalName(traversal)) {
+			found = append(found, reference{
+				Name:     key,
+				Location: location,
+			})
+		}
+	}
+
+	return found
+}
+
+// findReferences returns the references within every value of body, nested
+// blocks are seen as object values so they are included as well
+func (c *jsonCollector) findReferences(body hcl2.Body, getKeys func(string) []string) []reference {
+	found := []reference{}
+	for _, attribute := range jsonAttributes(body) {
+		found = append(found, c.findExprReferences(attribute.Expr, getKeys)...)
+	}
+
+	return found
+}
+
+// templates parses the strings within a JSON value as templates. The escape
+// sequences of JSON strings are valid in templates as well, so the text
+// between the quotes is parsed as is which keeps the positions exact
+func (c *jsonCollector) templates(expr hcl2.Expression) []hclsyntax.Expression {
+	templates := []hclsyntax.Expression{}
+	if items, diagnostics := hcl2.ExprMap(expr); !diagnostics.HasErrors() {
+		for _, item := range items {
+			templates = append(templates, c.templates(item.Value)...)
+		}
+		return templates
+	}
+	if elements, diagnostics := hcl2.ExprList(expr); !diagnostics.HasErrors() {
+		for _, element := range elements {
+			templates = append(templates, c.templates(element)...)
+		}
+		return templates
+	}
+
+	r := expr.Range()
+	text := r.SliceBytes(c.contents)
+	if len(text) < 2 || text[0] != '"' {
+		return templates
+	}
+
+	start := hcl2.Pos{Line: r.Start.Line, Column: r.Start.Column + 1, Byte: r.Start.Byte + 1}
+	template, diagnostics := hclsyntax.ParseTemplate(text[1:len(text)-1], c.path, start)
+	if diagnostics.HasErrors() {
+		// escaped quotes within an interpolation, like "${lookup(m, \"k\")}",
+		// only parse once unescaped, at the cost of exact positions
+		value := exprString(expr)
+		template, diagnostics = hclsyntax.ParseTemplate([]byte(value), c.path, start)
+	}
+	if !diagnostics.HasErrors() {
+		templates = append(templates, template)
+	}
+
+	return templates
+}
+
+func withinRanges(ranges []hcl2.Range, pos hcl2.Pos) bool {
+	for _, r := range ranges {
+		if r.ContainsOffset(pos.Byte) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *jsonCollector) addReferences(body hcl2.Body, getKeys func(string) []string) {
+	for _, reference := range c.findReferences(body, getKeys) {
+		c.index.addReference(reference.Name, reference.Location)
+	}
+}
+
+func (c *jsonCollector) addDependencies(address string, body hcl2.Body) {
+	c.index.dependencies[address] = append(c.index.dependencies[address], c.findReferences(body, getReferenceKeys)...)
+}
+
+func (c *jsonCollector) getExpression(attribute *hcl2.Attribute) *Expression {
+	if attribute == nil {
+		return nil
+	}
+
+	return &Expression{
+		Text:       c.exprText(attribute.Expr),
+		References: uniqueReferenceNames(c.findExprReferences(attribute.Expr, getReferenceKeys)),
+		Location:   c.pos(attribute.NameRange.Start),
+	}
+}
+
+func (c *jsonCollector) collectBody(body hcl2.Body) {
+	content, _, _ := body.PartialContent(jsonRootSchema)
+
+	// iterators are only bound within the content of their dynamic block,
+	// which the walk of the whole body cannot tell, so their references are
+	// found first
+	for _, block := range content.Blocks {
+		switch block.Type {
+		case "resource", "ephemeral", "data":
+			for _, reference := range c.findIteratorReferences(c.dynamicBlocks(block.Body)) {
+				c.bound[reference.Location] = true
+				c.index.addReferenceOfKind(reference.Name, REFERENCE_KIND_ITERATOR, reference.Location)
+			}
+		case "import", "removed":
+			name := addressAttributes[block.Type]
+			if address, ok := c.content(block.Body, []string{name}).Attributes[name]; ok {
+				for _, reference := range c.findExprReferences(address.Expr, getReferenceKeys) {
+					c.bound[reference.Location] = true
+				}
+			}
+		}
+	}
+
+	c.addReferences(body, getReferenceKeys)
+
+	// the type constraints of variables only look like calls
+	constraints := []hcl2.Range{}
+	for _, block := range jsonBlocks(content, "variable") {
+		if variableType, ok := c.content(block.Body, []string{"type"}).Attributes["type"]; ok {
+			constraints = append(constraints, variableType.Expr.Range())
+		}
+	}
+	for _, attribute := range jsonAttributes(body) {
+		for _, template := range c.templates(attribute.Expr) {
+			if !withinRanges(constraints, template.Range().Start) {
+				c.index.addSyntaxFunctionCalls(template, c.path)
+			}
+		}
+	}
+
+	for _, block := range content.Blocks {
+		switch block.Type {
+		case "variable":
+			c.handleVariable(block)
+		case "resource":
+			c.handleResource(block, RESOURCE_KIND_MANAGED)
+		case "ephemeral":
+			c.handleResource(block, RESOURCE_KIND_EPHEMERAL)
+		case "data":
+			c.handleData(block)
+		case "module":
+			c.handleModule(block)
+		case "output":
+			c.handleOutput(block)
+		case "locals":
+			c.handleLocals(block)
+		case "terraform":
+			c.handleSettings(block)
+		case "check":
+			c.handleCheck(block)
+		case "import":
+			c.handleImport(block)
+		case "removed":
+			c.handleRemoved(block)
+		}
+	}
+}
+
+func (c *jsonCollector) handleVariable(block *hcl2.Block) {
+	content := c.content(block.Body, []string{"type", "default", "description", "sensitive", "nullable"},
+		hcl2.BlockHeaderSchema{Type: "validation"})
+
+	variable := VariableDeclaration{
+		Name:          block.Labels[0],
+		Nullable:      true, // terraform defaults nullable to true
+		Required:      true, // until a default is found
+		Validations:   []ValidationDeclaration{},
+		Location:      c.pos(block.LabelRanges[0].Start),
+		BlockLocation: c.pos(block.LabelRanges[0].Start),
+		EndLocation:   c.blockEnd(block),
+	}
+
+	if variableType, ok := content.Attributes["type"]; ok {
+		// JSON gives the type constraint as a string in native syntax
+		variable.Type = exprString(variableType.Expr)
+		variable.TypeConstraint = parseTypeConstraint(variable.Type)
+	}
+	if defaultValue, ok := content.Attributes["default"]; ok {
+		variable.Default = c.exprText(defaultValue.Expr)
+		variable.Required = false
+	}
+	if description, ok := content.Attributes["description"]; ok {
+		variable.Description = exprString(description.Expr)
+	}
+	if sensitive, ok := content.Attributes["sensitive"]; ok {
+		variable.Sensitive = exprBool(sensitive.Expr, false)
+	}
+	if nullable, ok := content.Attributes["nullable"]; ok {
+		variable.Nullable = exprBool(nullable.Expr, true)
+		variable.nullableSet = true
+	}
+	for _, validation := range content.Blocks {
+		variable.Validations = append(variable.Validations, ValidationDeclaration(c.getCondition(validation)))
+	}
+
+	c.index.Variables = append(c.index.Variables, variable)
+}
+
+// getDependsOn reads depends_on, which JSON gives as a list of address
+// strings rather than traversals, so the addresses are added as references
+func (c *jsonCollector) getDependsOn(attribute *hcl2.Attribute) []Dependency {
+	dependencies := []Dependency{}
+	if attribute == nil {
+		return dependencies
+	}
+
+	elements, diagnostics := hcl2.ExprList(attribute.Expr)
+	if diagnostics.HasErrors() {
+		return dependencies
+	}
+
+	for _, element := range elements {
+		if address := exprString(element); address != "" {
+			location := c.pos(element.Range().Start)
+			location.Offset++
+			location.Column++
+			dependencies = append(dependencies, Dependency{
+				Address:  address,
+				Location: location,
+			})
+			c.index.addReference(address, location)
+		}
+	}
+
+	return dependencies
+}
+
+func (c *jsonCollector) handleResource(block *hcl2.Block, kind string) {
+	content := c.content(block.Body, []string{"count", "for_each", "depends_on"},
+		hcl2.BlockHeaderSchema{Type: "lifecycle"},
+		hcl2.BlockHeaderSchema{Type: "provisioner", LabelNames: []string{"type"}},
+		hcl2.BlockHeaderSchema{Type: "connection"})
+
+	resource := ResourceDeclaration{
+		Kind:          kind,
+		Type:          block.Labels[0],
+		Name:          block.Labels[1],
+		Count:         c.getExpression(content.Attributes["count"]),
+		ForEach:       c.getExpression(content.Attributes["for_each"]),
+		DependsOn:     c.getDependsOn(content.Attributes["depends_on"]),
+		Location:      c.pos(block.LabelRanges[1].Start), // return position of name
+		Blocks:        []NestedBlock{},
+		BlockLocation: c.pos(block.LabelRanges[1].Start),
+		EndLocation:   c.blockEnd(block),
+	}
+
+	c.addLink(resourceLinkKind(kind), resource.Type, block.LabelRanges[0])
+	if lifecycles := jsonBlocks(content, "lifecycle"); len(lifecycles) > 0 {
+		resource.Lifecycle = c.getLifecycle(lifecycles[0])
+		c.handleConditions(lifecycles[0].Body, resource.Address())
+	}
+
+	address := resource.Address()
+	getSelfKeys := func(name string) []string {
+		if !strings.HasPrefix(name, "self.") {
+			return nil
+		}
+		return []string{address}
+	}
+	for _, nested := range append(jsonBlocks(content, "provisioner"), jsonBlocks(content, "connection")...) {
+		c.addReferences(nested.Body, getSelfKeys)
+	}
+
+	c.index.Resources = append(c.index.Resources, resource)
+	c.addDependencies(address, block.Body)
+	for _, dependency := range resource.DependsOn {
+		c.index.dependencies[address] = append(c.index.dependencies[address],
+			reference{Name: dependency.Address, Location: dependency.Location})
+	}
+}
+
+func (c *jsonCollector) getLifecycle(block *hcl2.Block) *LifecycleDeclaration {
+	content := c.content(block.Body, []string{"prevent_destroy", "create_before_destroy", "ignore_changes"})
+	lifecycle := &LifecycleDeclaration{
+		IgnoreChanges: []string{},
+		Location:      c.pos(block.TypeRange.Start),
+	}
+
+	if preventDestroy, ok := content.Attributes["prevent_destroy"]; ok {
+		lifecycle.PreventDestroy = exprBool(preventDestroy.Expr, false)
+		lifecycle.PreventDestroyLocation = c.pos(preventDestroy.NameRange.Start)
+	}
+	if createBeforeDestroy, ok := content.Attributes["create_before_destroy"]; ok {
+		lifecycle.CreateBeforeDestroy = exprBool(createBeforeDestroy.Expr, false)
+		lifecycle.CreateBeforeDestroyLocation = c.pos(createBeforeDestroy.NameRange.Start)
+	}
+	if ignoreChanges, ok := content.Attributes["ignore_changes"]; ok {
+		if elements, diagnostics := hcl2.ExprList(ignoreChanges.Expr); !diagnostics.HasErrors() {
+			for _, element := range elements {
+				if name := exprString(element); name != "" {
+					lifecycle.IgnoreChanges = append(lifecycle.IgnoreChanges, name)
+				}
+			}
+		} else if name := exprString(ignoreChanges.Expr); name != "" {
+			lifecycle.IgnoreChanges = append(lifecycle.IgnoreChanges, name) // e.g. "all"
+		}
+		lifecycle.IgnoreChangesLocation = c.pos(ignoreChanges.NameRange.Start)
+	}
+
+	return lifecycle
+}
+
+func (c *jsonCollector) handleData(block *hcl2.Block) {
+	data := DataDeclaration{
+		Type:          block.Labels[0],
+		Name:          block.Labels[1],
+		Location:      c.pos(block.LabelRanges[1].Start), // return position of name
+		Blocks:        []NestedBlock{},
+		BlockLocation: c.pos(block.LabelRanges[1].Start),
+		EndLocation:   c.blockEnd(block),
+	}
+	c.addLink(LINK_DATA_TYPE, data.Type, block.LabelRanges[0])
+
+	c.index.Data = append(c.index.Data, data)
+	c.addDependencies(data.Address(), block.Body)
+}
+
+func (c *jsonCollector) handleModule(block *hcl2.Block) {
+	content := c.content(block.Body, []string{"source", "version", "count", "for_each", "depends_on"})
+
+	module := ModuleDeclaration{
+		Name:          block.Labels[0],
+		SourceKind:    MODULE_SOURCE_UNKNOWN,
+		Count:         c.getExpression(content.Attributes["count"]),
+		ForEach:       c.getExpression(content.Attributes["for_each"]),
+		DependsOn:     c.getDependsOn(content.Attributes["depends_on"]),
+		Arguments:     []ModuleArgument{},
+		Location:      c.pos(block.LabelRanges[0].Start),
+		BlockLocation: c.pos(block.LabelRanges[0].Start),
+		EndLocation:   c.blockEnd(block),
+	}
+
+	for _, attribute := range jsonAttributes(block.Body) {
+		if !moduleMetaArguments[attribute.Name] {
+			module.Arguments = append(module.Arguments, ModuleArgument{
+				Name:     attribute.Name,
+				Location: c.pos(attribute.NameRange.Start),
+			})
+		}
+	}
+	if source, ok := content.Attributes["source"]; ok {
+		module.Source = exprString(source.Expr)
+		module.SourceKind = ClassifyModuleSource(module.Source)
+		module.SourceLocation = c.pos(source.NameRange.Start)
+		c.addLink(LINK_MODULE_SOURCE, module.Source, source.Expr.Range())
+	}
+	if version, ok := content.Attributes["version"]; ok {
+		module.Version = exprString(version.Expr)
+	}
+
+	c.index.Modules = append(c.index.Modules, module)
+	c.addDependencies(module.Address(), block.Body)
+}
+
+func (c *jsonCollector) handleOutput(block *hcl2.Block) {
+	content := c.content(block.Body, []string{"value", "depends_on"})
+
+	output := OutputDeclaration{
+		Name:          block.Labels[0],
+		References:    []string{},
+		DependsOn:     c.getDependsOn(content.Attributes["depends_on"]),
+		Location:      c.pos(block.LabelRanges[0].Start),
+		BlockLocation: c.pos(block.LabelRanges[0].Start),
+		EndLocation:   c.blockEnd(block),
+	}
+
+	if value, ok := content.Attributes["value"]; ok {
+		output.Value = c.exprText(value.Expr)
+		output.ValueLocation = c.pos(value.NameRange.Start)
+		output.valueReferences = c.findExprReferences(value.Expr, getReferenceKeys)
+		output.References = uniqueReferenceNames(output.valueReferences)
+	}
+	c.handleConditions(block.Body, "output."+output.Name)
+
+	c.index.Outputs = append(c.index.Outputs, output)
+	c.addDependencies(output.Address(), block.Body)
+	for _, dependency := range output.DependsOn {
+		c.index.dependencies[output.Address()] = append(c.index.dependencies[output.Address()],
+			reference{Name: dependency.Address, Location: dependency.Location})
+	}
+}
+
+func (c *jsonCollector) handleLocals(block *hcl2.Block) {
+	for _, attribute := range jsonAttributes(block.Body) {
+		local := LocalDeclaration{
+			Name:        attribute.Name,
+			Location:    c.pos(attribute.NameRange.Start),
+			EndLocation: c.pos(attribute.Range.End),
+		}
+		c.index.Locals = append(c.index.Locals, local)
+		c.index.dependencies[local.Address()] = append(c.index.dependencies[local.Address()],
+			c.findExprReferences(attribute.Expr, getReferenceKeys)...)
+	}
+}
+
+func (c *jsonCollector) getCondition(block *hcl2.Block) ConditionDeclaration {
+	content := c.content(block.Body, []string{"condition", "error_message"})
+	condition := ConditionDeclaration{
+		References: []string{},
+		Location:   c.pos(block.TypeRange.Start),
+	}
+
+	if conditionAttribute, ok := content.Attributes["condition"]; ok {
+		condition.Condition = c.exprText(conditionAttribute.Expr)
+		condition.References = uniqueReferenceNames(c.findExprReferences(conditionAttribute.Expr, getReferenceKeys))
+	}
+	if errorMessage, ok := content.Attributes["error_message"]; ok {
+		condition.ErrorMessage = c.exprText(errorMessage.Expr)
+	}
+
+	return condition
+}
+
+func (c *jsonCollector) handleConditions(body hcl2.Body, address string) {
+	content := c.content(body, nil, jsonConditionSchemas...)
+	for _, block := range content.Blocks {
+		c.index.Checks = append(c.index.Checks, CheckDeclaration{
+			Kind:       block.Type,
+			Name:       address,
+			Conditions: []ConditionDeclaration{c.getCondition(block)},
+			Location:   c.pos(block.TypeRange.Start),
+		})
+	}
+}
+
+func (c *jsonCollector) handleCheck(block *hcl2.Block) {
+	content := c.content(block.Body, nil, hcl2.BlockHeaderSchema{Type: "assert"})
+
+	check := CheckDeclaration{
+		Kind:       "check",
+		Name:       block.Labels[0],
+		Conditions: []ConditionDeclaration{},
+		Location:   c.pos(block.LabelRanges[0].Start),
+	}
+	for _, assert := range content.Blocks {
+		check.Conditions = append(check.Conditions, c.getCondition(assert))
+	}
+
+	c.index.Checks = append(c.index.Checks, check)
+}
+
+func (c *jsonCollector) handleImport(block *hcl2.Block) {
+	content := c.content(block.Body, []string{"to", "id"})
+	declaration := ImportDeclaration{
+		References: []string{},
+		Location:   c.pos(block.TypeRange.Start),
+	}
+
+	if to, ok := content.Attributes["to"]; ok {
+		declaration.To = c.exprText(to.Expr)
+		declaration.ToLocation = c.pos(to.NameRange.Start)
+	}
+	if id, ok := content.Attributes["id"]; ok {
+		declaration.ID = c.exprText(id.Expr)
+		declaration.References = uniqueReferenceNames(c.findExprReferences(id.Expr, getReferenceKeys))
+	}
+
+	c.index.Imports = append(c.index.Imports, declaration)
+}
+
+func (c *jsonCollector) handleRemoved(block *hcl2.Block) {
+	content := c.content(block.Body, []string{"from"}, hcl2.BlockHeaderSchema{Type: "lifecycle"})
+	removed := RemovedDeclaration{
+		Destroy:  true, // terraform destroys removed objects unless told otherwise
+		Location: c.pos(block.TypeRange.Start),
+	}
+
+	if from, ok := content.Attributes["from"]; ok {
+		removed.From = c.exprText(from.Expr)
+		removed.FromLocation = c.pos(from.NameRange.Start)
+	}
+	for _, lifecycle := range content.Blocks {
+		lifecycleContent := c.content(lifecycle.Body, []string{"destroy"})
+		if destroy, ok := lifecycleContent.Attributes["destroy"]; ok {
+			removed.Destroy = exprBool(destroy.Expr, true)
+		}
+	}
+
+	c.index.Removed = append(c.index.Removed, removed)
+}
+
+func (c *jsonCollector) dynamicBlocks(body hcl2.Body) []*hcl2.Block {
+	return c.content(body, nil, hcl2.BlockHeaderSchema{Type: "dynamic", LabelNames: []string{"name"}}).Blocks
+}
+
+// findIteratorReferences returns the iterator references in the content of
+// the given dynamic blocks and of the dynamic blocks nested within them
+func (c *jsonCollector) findIteratorReferences(blocks []*hcl2.Block) []reference {
+	found := []reference{}
+	for _, block := range blocks {
+		content := c.content(block.Body, []string{"iterator"}, hcl2.BlockHeaderSchema{Type: "content"})
+
+		iterator := block.Labels[0]
+		if iteratorAttribute, ok := content.Attributes["iterator"]; ok {
+			if name := exprString(iteratorAttribute.Expr); name != "" {
+				iterator = name
+			}
+		}
+
+		for _, contentBlock := range content.Blocks {
+			found = append(found, c.findReferences(contentBlock.Body, getIteratorKeys(iterator))...)
+			found = append(found, c.findIteratorReferences(c.dynamicBlocks(contentBlock.Body))...)
+		}
+	}
+
+	return found
+}
+
+func (c *jsonCollector) handleSettings(block *hcl2.Block) {
+	content := c.content(block.Body, []string{"required_version"}, hcl2.BlockHeaderSchema{Type: "required_providers"})
+	settings := SettingsDeclaration{
+		RequiredProviders: []ProviderRequirement{},
+		Location:          c.pos(block.TypeRange.Start),
+	}
+
+	if version, ok := content.Attributes["required_version"]; ok {
+		settings.RequiredVersion = exprString(version.Expr)
+		settings.RequiredVersionLocation = c.pos(version.NameRange.Start)
+	}
+
+	for _, providers := range content.Blocks {
+		for _, attribute := range jsonAttributes(providers.Body) {
+			provider := ProviderRequirement{
+				Name:     attribute.Name,
+				Location: c.pos(attribute.NameRange.Start),
+			}
+
+			// required_providers accepts both `"aws": "~> 1.0"` and
+			// `"aws": { "source": "...", "version": "..." }`
+			if items, diagnostics := hcl2.ExprMap(attribute.Expr); !diagnostics.HasErrors() {
+				for _, item := range items {
+					switch exprString(item.Key) {
+					case "source":
+						provider.Source = exprString(item.Value)
+					case "version":
+						provider.Version = exprString(item.Value)
+					}
+				}
+			} else {
+				provider.Version = exprString(attribute.Expr)
+			}
+
+			settings.RequiredProviders = append(settings.RequiredProviders, provider)
+		}
+	}
+
+	c.index.Settings = append(c.index.Settings, settings)
+}
@@ -0,0 +1,93 @@
+package index
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+const DEFAULT_MAX_FILE_SIZE = 16 * 1024 * 1024
+
+// MaxFileSize is the size in bytes of the largest file which is collected,
+// larger files like state dumps or minified JSON pointed at by accident are
+// reported with a file-too-large diagnostic instead of being parsed. Zero
+// lifts the limit
+var MaxFileSize int64 = DEFAULT_MAX_FILE_SIZE
+
+// FileTooLargeError is returned for files larger than MaxFileSize, Size is -1
+// when the file was read from a stream which was not read to its end
+type FileTooLargeError struct {
+	Path  string
+	Size  int64
+	Limit int64
+}
+
+func (err *FileTooLargeError) Error() string {
+	if err.Size < 0 {
+		return fmt.Sprintf("file is larger than the limit of %d bytes", err.Limit)
+	}
+	return fmt.Sprintf("file is %d bytes, larger than the limit of %d bytes", err.Size, err.Limit)
+}
+
+// ReadFile reads the file at path like ioutil.ReadFile, files larger than
+// MaxFileSize fail with a *FileTooLargeError without being read
+func ReadFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err == nil && MaxFileSize > 0 && info.Size() > MaxFileSize {
+		return nil, &FileTooLargeError{path, info.Size(), MaxFileSize}
+	}
+	return ReadLimited(file, path)
+}
+
+// ReadLimited reads reader to its end unless it holds more than MaxFileSize
+// bytes, it then stops reading and fails with a *FileTooLargeError for path
+func ReadLimited(reader io.Reader, path string) ([]byte, error) {
+	if MaxFileSize <= 0 {
+		return ioutil.ReadAll(reader)
+	}
+
+	contents, err := ioutil.ReadAll(io.LimitReader(reader, MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(contents)) > MaxFileSize {
+		return nil, &FileTooLargeError{path, -1, MaxFileSize}
+	}
+	return contents, nil
+}
+
+// tooLarge returns the error for contents larger than MaxFileSize, or nil
+func tooLarge(contents []byte, path string) *FileTooLargeError {
+	if MaxFileSize > 0 && int64(len(contents)) > MaxFileSize {
+		return &FileTooLargeError{path, int64(len(contents)), MaxFileSize}
+	}
+	return nil
+}
+
+// SkipFile reports a file which is too large to be collected with a
+// diagnostic at its start, which is kept like a parse error
+func (index *Index) SkipFile(err *FileTooLargeError) {
+	index.lock()
+	defer index.mutex.Unlock()
+	index.skipFile(err)
+}
+
+func (index *Index) skipFile(err *FileTooLargeError) {
+	location := hcltoken.Pos{Filename: err.Path, Line: 1, Column: 1}
+	index.addCollectedDiagnostic(Diagnostic{
+		Severity:         SEVERITY_ERROR,
+		Code:             CODE_FILE_TOO_LARGE,
+		Message:          err.Error(),
+		Location:         location,
+		EndLocation:      location,
+		RelatedLocations: []hcltoken.Pos{},
+	})
+}
@@ -0,0 +1,184 @@
+package index
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+const (
+	LINK_MODULE_SOURCE = "module-source"
+	LINK_RESOURCE_TYPE = "resource-type"
+	LINK_DATA_TYPE     = "data-type"
+	LINK_EPHEMERAL     = "ephemeral-type"
+)
+
+const REGISTRY_URL = "https://registry.terraform.io"
+
+// DocumentLink links the text from Location up to EndLocation, a module
+// source or a resource type, to Target
+type DocumentLink struct {
+	Kind        string
+	Text        string
+	Target      string
+	Location    hcltoken.Pos
+	EndLocation hcltoken.Pos
+}
+
+func (index *Index) addLink(kind string, text string, location hcltoken.Pos, endLocation hcltoken.Pos) {
+	index.links[location.Filename] = append(index.links[location.Filename], DocumentLink{
+		Kind:        kind,
+		Text:        text,
+		Location:    location,
+		EndLocation: endLocation,
+	})
+}
+
+// addKeyLink records the link of an HCL1 token, the text without its quotes
+// is the link text
+func (index *Index) addKeyLink(kind string, token hcltoken.Token, path string) {
+	location := getPos(token, path)
+	index.addLink(kind, getText(token), location, endPos(location, token.Text))
+}
+
+func resourceLinkKind(kind string) string {
+	if kind == RESOURCE_KIND_EPHEMERAL {
+		return LINK_EPHEMERAL
+	}
+
+	return LINK_RESOURCE_TYPE
+}
+
+// DocumentLinks returns the links of a file which have a target: module
+// sources link to their directory, registry page or repository and resource
+// types to the documentation of their provider
+func (index *Index) DocumentLinks(path string) []DocumentLink {
+	links := []DocumentLink{}
+	for _, link := range index.links[path] {
+		var target string
+		if link.Kind == LINK_MODULE_SOURCE {
+			target = moduleSourceURL(link.Text, filepath.Dir(path))
+		} else {
+			target = index.providerDocsURL(link.Kind, link.Text)
+		}
+
+		if target != "" {
+			link.Target = target
+			links = append(links, link)
+		}
+	}
+
+	return links
+}
+
+// stripSubdirectory removes the `//subdir` suffix selecting a directory
+// within a module package
+func stripSubdirectory(source string) string {
+	start := 0
+	if scheme := strings.Index(source, "://"); scheme >= 0 {
+		start = scheme + 3
+	}
+	if subdirectory := strings.Index(source[start:], "//"); subdirectory >= 0 {
+		return source[:start+subdirectory]
+	}
+
+	return source
+}
+
+// FileURI returns the file URL of a path, relative paths are made absolute
+func FileURI(path string) string {
+	if absolute, err := filepath.Abs(path); err == nil {
+		path = absolute
+	}
+
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// moduleSourceURL returns the URL showing a module source, empty for sources
+// which cannot be browsed like S3 buckets or private registries
+func moduleSourceURL(source string, directory string) string {
+	switch ClassifyModuleSource(source) {
+	case MODULE_SOURCE_LOCAL:
+		return FileURI(filepath.Join(directory, source))
+
+	case MODULE_SOURCE_REGISTRY:
+		parts := strings.Split(stripSubdirectory(source), "/")
+		if len(parts) == 4 && parts[0] == "registry.terraform.io" {
+			parts = parts[1:]
+		}
+		if len(parts) != 3 {
+			return ""
+		}
+		return REGISTRY_URL + "/modules/" + strings.Join(parts, "/")
+
+	case MODULE_SOURCE_GIT:
+		source = strings.TrimPrefix(source, "git::")
+		if query := strings.Index(source, "?"); query >= 0 {
+			source = source[:query]
+		}
+		source = stripSubdirectory(source)
+		if strings.HasPrefix(source, "git@") {
+			source = "https://" + strings.Replace(strings.TrimPrefix(source, "git@"), ":", "/", 1)
+		} else if !strings.Contains(source, "://") {
+			source = "https://" + source
+		}
+		if !strings.HasPrefix(source, "https://") && !strings.HasPrefix(source, "http://") {
+			return ""
+		}
+		return strings.TrimSuffix(source, ".git")
+
+	case MODULE_SOURCE_ARCHIVE:
+		return source
+	}
+
+	return ""
+}
+
+// providerNamespace returns the registry namespace of a provider from the
+// required_providers blocks, providers which are not required explicitly
+// come from the hashicorp namespace as in terraform
+func (index *Index) providerNamespace(provider string) (string, bool) {
+	for _, settings := range index.Settings {
+		for _, requirement := range settings.RequiredProviders {
+			if requirement.Name != provider || requirement.Source == "" {
+				continue
+			}
+
+			parts := strings.Split(requirement.Source, "/")
+			if len(parts) == 3 && parts[0] == "registry.terraform.io" {
+				parts = parts[1:]
+			}
+			if len(parts) != 2 {
+				return "", false
+			}
+			return parts[0], true
+		}
+	}
+
+	return "hashicorp", true
+}
+
+// providerDocsURL returns the registry documentation of a resource type,
+// like `aws_instance`, whose provider is the prefix before the first `_`
+func (index *Index) providerDocsURL(kind string, resourceType string) string {
+	separator := strings.Index(resourceType, "_")
+	if separator <= 0 {
+		return ""
+	}
+
+	provider := resourceType[:separator]
+	namespace, ok := index.providerNamespace(provider)
+	if !ok || provider == "terraform" {
+		return ""
+	}
+
+	section := "resources"
+	if kind == LINK_DATA_TYPE {
+		section = "data-sources"
+	} else if kind == LINK_EPHEMERAL {
+		section = "ephemeral-resources"
+	}
+	return REGISTRY_URL + "/providers/" + namespace + "/" + provider + "/latest/docs/" + section + "/" + resourceType[separator+1:]
+}
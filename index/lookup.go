@@ -0,0 +1,81 @@
+package index
+
+import (
+	"sort"
+	"strings"
+)
+
+// symbolName is an entry of the lookup table, a referenceable declaration
+// with the position of it in the list of its kind or a referenced name
+// with the kind of the reference and no declaration
+type symbolName struct {
+	Name        string
+	Kind        string
+	Declaration int
+}
+
+// symbolNames returns the declared and referenced names sorted by name, the
+// declarations in the order CompletionsAt offers them. The table is built by
+// the first query after the index changed
+func (index *Index) symbolNames() []symbolName {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	if index.names != nil {
+		return index.names
+	}
+
+	names := []symbolName{}
+	for i, variable := range index.Variables {
+		names = append(names, symbolName{variable.Address(), SYMBOL_VARIABLE, i})
+	}
+	for i, local := range index.Locals {
+		names = append(names, symbolName{local.Address(), SYMBOL_LOCAL, i})
+	}
+	for i, resource := range index.Resources {
+		names = append(names, symbolName{resource.Address(), SYMBOL_RESOURCE, i})
+	}
+	for i, data := range index.Data {
+		names = append(names, symbolName{data.Address(), SYMBOL_DATA, i})
+	}
+	for i, module := range index.Modules {
+		names = append(names, symbolName{module.Address(), SYMBOL_MODULE, i})
+	}
+	for name, list := range index.References {
+		names = append(names, symbolName{name, list.Kind, -1})
+	}
+
+	sort.SliceStable(names, func(i, j int) bool {
+		return names[i].Name < names[j].Name
+	})
+	index.names = names
+	return names
+}
+
+// namesWithPrefix returns the entries of the lookup table starting with
+// prefix
+func (index *Index) namesWithPrefix(prefix string) []symbolName {
+	names := index.symbolNames()
+	start := sort.Search(len(names), func(i int) bool {
+		return names[i].Name >= prefix
+	})
+	end := start
+	for end < len(names) && strings.HasPrefix(names[end].Name, prefix) {
+		end++
+	}
+
+	return names[start:end]
+}
+
+// NamesWithPrefix returns the declared or referenced names starting with
+// prefix like `var.net`, sorted and without duplicates. The names are looked
+// up with a binary search in a table built once after the index changed
+func (index *Index) NamesWithPrefix(prefix string) []string {
+	result := []string{}
+	for _, name := range index.namesWithPrefix(prefix) {
+		if len(result) == 0 || result[len(result)-1] != name.Name {
+			result = append(result, name.Name)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,190 @@
+package index
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+const LSIF_VERSION = "0.4.3"
+
+// lsifWriter emits the vertices and edges of an LSIF dump, one JSON object
+// per line, documents are emitted when first used and their ranges are
+// attached by contains edges once everything has been written
+type lsifWriter struct {
+	encoder   *json.Encoder
+	id        int
+	err       error
+	documents map[string]int
+	paths     []string
+	ranges    map[int][]int
+}
+
+func (writer *lsifWriter) emit(element map[string]interface{}) int {
+	writer.id++
+	element["id"] = writer.id
+	if writer.err == nil {
+		writer.err = writer.encoder.Encode(element)
+	}
+
+	return writer.id
+}
+
+func (writer *lsifWriter) vertex(label string, properties map[string]interface{}) int {
+	if properties == nil {
+		properties = map[string]interface{}{}
+	}
+	properties["type"] = "vertex"
+	properties["label"] = label
+	return writer.emit(properties)
+}
+
+func (writer *lsifWriter) edge(label string, outV int, inV int) int {
+	return writer.emit(map[string]interface{}{"type": "edge", "label": label, "outV": outV, "inV": inV})
+}
+
+func (writer *lsifWriter) items(outV int, inVs []int, document int, property string) int {
+	element := map[string]interface{}{"type": "edge", "label": "item", "outV": outV, "inVs": inVs, "document": document}
+	if property != "" {
+		element["property"] = property
+	}
+	return writer.emit(element)
+}
+
+func (writer *lsifWriter) document(path string) int {
+	if id, ok := writer.documents[path]; ok {
+		return id
+	}
+
+	id := writer.vertex("document", map[string]interface{}{"uri": FileURI(path), "languageId": "terraform"})
+	writer.documents[path] = id
+	writer.paths = append(writer.paths, path)
+	return id
+}
+
+// lsifPosition converts a one-based position to the zero-based positions of
+// LSIF
+func lsifPosition(pos hcltoken.Pos) map[string]int {
+	line, character := pos.Line-1, pos.Column-1
+	if line < 0 {
+		line = 0
+	}
+	if character < 0 {
+		character = 0
+	}
+	return map[string]int{"line": line, "character": character}
+}
+
+// rangeOf emits the range of the text from start to end and links it to a
+// result set
+func (writer *lsifWriter) rangeOf(start hcltoken.Pos, end hcltoken.Pos, resultSet int) int {
+	document := writer.document(start.Filename)
+	id := writer.vertex("range", map[string]interface{}{"start": lsifPosition(start), "end": lsifPosition(end)})
+	writer.ranges[document] = append(writer.ranges[document], id)
+	writer.edge("next", id, resultSet)
+	return id
+}
+
+// groupByDocument groups range ids by the document they are in, in the order
+// the documents were first used
+func (writer *lsifWriter) groupByDocument(ranges []int, locations []hcltoken.Pos) ([]int, map[int][]int) {
+	documents := []int{}
+	grouped := map[int][]int{}
+	for i, id := range ranges {
+		document := writer.document(locations[i].Filename)
+		if _, ok := grouped[document]; !ok {
+			documents = append(documents, document)
+		}
+		grouped[document] = append(grouped[document], id)
+	}
+
+	return documents, grouped
+}
+
+// WriteLSIF writes the declarations of the resolved indexes as an LSIF dump
+// answering definition, references and hover requests. Every referenceable
+// declaration gets a result set shared by its name in the declaration and in
+// every reference to it
+func WriteLSIF(writer io.Writer, projectRoot string, indexes ...*Index) error {
+	lsif := &lsifWriter{
+		encoder:   json.NewEncoder(writer),
+		documents: map[string]int{},
+		paths:     []string{},
+		ranges:    map[int][]int{},
+	}
+
+	lsif.vertex("metaData", map[string]interface{}{
+		"version":          LSIF_VERSION,
+		"projectRoot":      FileURI(projectRoot),
+		"positionEncoding": "utf-16",
+		"toolInfo":         map[string]string{"name": "terraform-index", "version": INDEX_VERSION},
+	})
+	project := lsif.vertex("project", map[string]interface{}{"kind": "terraform"})
+
+	for _, index := range indexes {
+		addresses := []string{}
+		for address := range index.Declarations() {
+			addresses = append(addresses, address)
+		}
+		sort.Strings(addresses)
+
+		for _, address := range addresses {
+			declaration := index.findDeclaration(address)
+			if declaration == nil {
+				continue
+			}
+
+			resultSet := lsif.vertex("resultSet", nil)
+
+			prefix := address[:strings.LastIndex(address, ".")+1]
+			name := address[len(prefix):]
+			location := declaration.Position()
+			start := shiftPos(location, nameOffset(declaration, location))
+			definition := lsif.rangeOf(start, shiftPos(start, len(name)), resultSet)
+
+			references := []int{}
+			locations := index.ReferencesToAddress(address)
+			for _, reference := range locations {
+				references = append(references, lsif.rangeOf(reference, shiftPos(reference, len(address)), resultSet))
+			}
+
+			definitionResult := lsif.vertex("definitionResult", nil)
+			lsif.edge("textDocument/definition", resultSet, definitionResult)
+			lsif.items(definitionResult, []int{definition}, lsif.document(location.Filename), "")
+
+			referenceResult := lsif.vertex("referenceResult", nil)
+			lsif.edge("textDocument/references", resultSet, referenceResult)
+			lsif.items(referenceResult, []int{definition}, lsif.document(location.Filename), "definitions")
+			documents, grouped := lsif.groupByDocument(references, locations)
+			for _, document := range documents {
+				lsif.items(referenceResult, grouped[document], document, "references")
+			}
+
+			if hover, ok := index.HoverFor(address); ok {
+				hoverResult := lsif.vertex("hoverResult", map[string]interface{}{
+					"result": map[string]interface{}{
+						"contents": map[string]string{"kind": "markdown", "value": hover.Markdown()},
+					},
+				})
+				lsif.edge("textDocument/hover", resultSet, hoverResult)
+			}
+		}
+	}
+
+	documents := []int{}
+	for _, path := range lsif.paths {
+		document := lsif.documents[path]
+		documents = append(documents, document)
+		if ranges := lsif.ranges[document]; len(ranges) > 0 {
+			lsif.emit(map[string]interface{}{"type": "edge", "label": "contains", "outV": document, "inVs": ranges})
+		}
+	}
+	if len(documents) > 0 {
+		lsif.emit(map[string]interface{}{"type": "edge", "label": "contains", "outV": project, "inVs": documents})
+	}
+
+	return lsif.err
+}
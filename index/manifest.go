@@ -0,0 +1,52 @@
+package index
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// moduleManifest is the .terraform/modules/modules.json file written by
+// `terraform init`, Dir is relative to the root module directory
+type moduleManifest struct {
+	Modules []struct {
+		Key     string
+		Source  string
+		Version string
+		Dir     string
+	}
+
+	root string
+}
+
+// loadModuleManifest reads the module manifest of the root module in
+// directory, it returns nil when the modules have not been installed
+func loadModuleManifest(directory string) *moduleManifest {
+	contents, err := ioutil.ReadFile(filepath.Join(directory, ".terraform", "modules", "modules.json"))
+	if err != nil {
+		return nil
+	}
+
+	manifest := &moduleManifest{root: directory}
+	if err := json.Unmarshal(contents, manifest); err != nil {
+		return nil
+	}
+
+	return manifest
+}
+
+// directory returns the directory a module was installed to, key is the
+// dotted path of module names from the root module, like `vpc.subnets`
+func (manifest *moduleManifest) directory(key string) (string, bool) {
+	if manifest == nil {
+		return "", false
+	}
+
+	for _, module := range manifest.Modules {
+		if module.Key == key {
+			return filepath.Join(manifest.root, module.Dir), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,50 @@
+package index
+
+// Merge adds everything other holds to the index, like the indexes of the
+// directories of a workspace collected by separate jobs. Files held by both
+// indexes are taken from other, so merging the same index twice changes
+// nothing. The diagnostics of both are kept, Resolve has to be called again
+// before querying the index and Analyze to report the problems spanning
+// both. Children with the same name are merged, the index shares nothing
+// with other afterwards
+func (index *Index) Merge(other *Index) {
+	if other == index {
+		return
+	}
+
+	other.mutex.Lock()
+	defer other.mutex.Unlock()
+	index.lock()
+	defer index.mutex.Unlock()
+
+	paths := map[string]bool{}
+	for _, path := range other.Files() {
+		paths[path] = true
+	}
+	for _, assignment := range other.Assignments {
+		paths[assignment.Location.Filename] = true
+	}
+	for path := range other.stamps {
+		paths[path] = true
+	}
+	for path := range paths {
+		index.removeFile(path)
+	}
+
+	// merge reports the collected diagnostics only, other may be analyzed
+	diagnostics := append(append([]Diagnostic{}, index.Diagnostics...), other.Diagnostics...)
+	index.merge(other)
+	index.Diagnostics = diagnostics
+	index.pendingOverrides = append(index.pendingOverrides, other.pendingOverrides...)
+	for path, stamp := range other.stamps {
+		index.stamps[path] = stamp
+	}
+
+	for name, child := range other.Children {
+		if existing, ok := index.Children[name]; ok {
+			existing.Merge(child)
+		} else {
+			index.Children[name] = child.Snapshot()
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package index
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// mermaidShapes are the opening and closing brackets of the Mermaid node
+// shape of every kind of declaration
+var mermaidShapes = map[string][2]string{
+	SYMBOL_VARIABLE: {"([", "])"},
+	SYMBOL_LOCAL:    {"{{", "}}"},
+	SYMBOL_RESOURCE: {"[", "]"},
+	SYMBOL_DATA:     {"[(", ")]"},
+	SYMBOL_MODULE:   {"[[", "]]"},
+	SYMBOL_OUTPUT:   {">", "]"},
+}
+
+func mermaidLabel(text string) string {
+	return "\"" + strings.Replace(text, "\"", "#quot;", -1) + "\""
+}
+
+// WriteMermaid writes the dependency graph of the resolved indexes as a
+// Mermaid flowchart, which GitHub and GitLab render within markdown. Like
+// WriteDot several indexes are drawn as one subgraph per module directory
+func WriteMermaid(writer io.Writer, indexes ...*Index) error {
+	lines := []string{"flowchart RL"}
+	count := 0
+	for i, index := range indexes {
+		indent := "  "
+		if len(indexes) > 1 {
+			indent = "    "
+			lines = append(lines, fmt.Sprintf("  subgraph module%d [%s]", i, mermaidLabel(index.directory())))
+		}
+
+		// addresses are not valid node ids, every node is numbered instead
+		ids := map[string]string{}
+		nodes, edges := index.graph()
+		for _, node := range nodes {
+			ids[node.Address] = fmt.Sprintf("n%d", count)
+			count++
+			shape := mermaidShapes[node.Kind]
+			lines = append(lines, indent+ids[node.Address]+shape[0]+mermaidLabel(node.Address)+shape[1])
+		}
+		for _, node := range nodes {
+			for _, dependency := range edges[node.Address] {
+				lines = append(lines, indent+ids[node.Address]+" --> "+ids[dependency])
+			}
+		}
+
+		if len(indexes) > 1 {
+			lines = append(lines, "  end")
+		}
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(writer, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
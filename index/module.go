@@ -0,0 +1,69 @@
+package index
+
+import (
+	"regexp"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+const (
+	MODULE_SOURCE_LOCAL    = "local"
+	MODULE_SOURCE_REGISTRY = "registry"
+	MODULE_SOURCE_GIT      = "git"
+	MODULE_SOURCE_HG       = "mercurial"
+	MODULE_SOURCE_S3       = "s3"
+	MODULE_SOURCE_GCS      = "gcs"
+	MODULE_SOURCE_ARCHIVE  = "archive"
+	MODULE_SOURCE_UNKNOWN  = "unknown"
+)
+
+// moduleMetaArguments are the arguments of a module block which are not
+// input variables of the module
+var moduleMetaArguments = map[string]bool{
+	"source":     true,
+	"version":    true,
+	"count":      true,
+	"for_each":   true,
+	"depends_on": true,
+	"providers":  true,
+}
+
+type ModuleArgument struct {
+	Name     string
+	Location hcltoken.Pos
+}
+
+var registrySourcePattern = regexp.MustCompile(`^([0-9A-Za-z.\-]+/)?[0-9A-Za-z\-_]+/[0-9A-Za-z\-_]+/[0-9a-z]+(//.*)?$`)
+
+func ClassifyModuleSource(source string) string {
+	switch {
+	case strings.HasPrefix(source, "./"), strings.HasPrefix(source, "../"):
+		return MODULE_SOURCE_LOCAL
+
+	case strings.HasPrefix(source, "git::"),
+		strings.HasPrefix(source, "git@"),
+		strings.HasPrefix(source, "github.com/"),
+		strings.HasPrefix(source, "bitbucket.org/"):
+		return MODULE_SOURCE_GIT
+
+	case strings.HasPrefix(source, "hg::"):
+		return MODULE_SOURCE_HG
+
+	case strings.HasPrefix(source, "s3::"),
+		strings.Contains(source, ".amazonaws.com/"):
+		return MODULE_SOURCE_S3
+
+	case strings.HasPrefix(source, "gcs::"),
+		strings.HasPrefix(source, "www.googleapis.com/storage/"):
+		return MODULE_SOURCE_GCS
+
+	case strings.HasPrefix(source, "http://"), strings.HasPrefix(source, "https://"):
+		return MODULE_SOURCE_ARCHIVE
+
+	case registrySourcePattern.MatchString(source):
+		return MODULE_SOURCE_REGISTRY
+	}
+
+	return MODULE_SOURCE_UNKNOWN
+}
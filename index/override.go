@@ -0,0 +1,320 @@
+package index
+
+import (
+	"path/filepath"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+// Override links a declaration in an override file to the base declaration
+// it was merged into
+type Override struct {
+	Address      string
+	Location     hcltoken.Pos
+	BaseLocation hcltoken.Pos
+}
+
+// isOverrideFile reports whether path is an override file, like override.tf
+// or example_override.tf.json
+func isOverrideFile(path string) bool {
+	name := strings.TrimSuffix(filepath.Base(path), ".json")
+	return name == "override.tf" || strings.HasSuffix(name, "_override.tf")
+}
+
+// ApplyOverrides merges the declarations of the collected override files into
+// their base declarations. Terraform processes override files after all
+// other files so this is done once everything has been collected, Resolve
+// calls it before resolving references
+func (index *Index) ApplyOverrides() {
+	index.lock()
+	defer index.mutex.Unlock()
+	index.applyOverrides()
+}
+
+func (index *Index) applyOverrides() {
+	for _, override := range index.pendingOverrides {
+		index.applyOverride(override)
+	}
+	index.pendingOverrides = []*Index{}
+}
+
+func (index *Index) addOverride(address string, location hcltoken.Pos, base hcltoken.Pos) {
+	index.Overrides = append(index.Overrides, Override{
+		Address:      address,
+		Location:     location,
+		BaseLocation: base,
+	})
+}
+
+func (index *Index) missingOverrideBase(address string, location hcltoken.Pos) {
+	index.addCollectedDiagnostic(Diagnostic{
+		Severity:         SEVERITY_ERROR,
+		Code:             CODE_MISSING_OVERRIDE_BASE,
+		Message:          "Missing base declaration to override for '" + address + "'",
+		Location:         location,
+		EndLocation:      location,
+		RelatedLocations: []hcltoken.Pos{},
+	})
+}
+
+func (index *Index) applyOverride(override *Index) {
+	for _, name := range override.sortedReferenceNames() {
+		for _, location := range override.References[name].Locations {
+			index.addReference(name, location)
+		}
+	}
+	for _, name := range override.sortedFunctionNames() {
+		for _, location := range override.FunctionCalls[name].Locations {
+			index.addFunctionCall(name, location)
+		}
+	}
+	for path, tokens := range override.tokens {
+		index.tokens[path] = append(index.tokens[path], tokens...)
+	}
+	for path, links := range override.links {
+		index.links[path] = append(index.links[path], links...)
+	}
+	for path, folds := range override.folds {
+		index.folds[path] = append(index.folds[path], folds...)
+	}
+	for address, references := range override.dependencies {
+		index.dependencies[address] = append(index.dependencies[address], references...)
+	}
+
+	for _, variable := range override.Variables {
+		base := index.findVariable(variable.Name)
+		if base == nil {
+			index.missingOverrideBase(variable.Address(), variable.Location)
+			continue
+		}
+
+		if variable.Type != "" {
+			base.Type = variable.Type
+			base.TypeConstraint = variable.TypeConstraint
+		}
+		if !variable.Required {
+			base.Default = variable.Default
+			base.Required = false
+		}
+		if variable.Description != "" {
+			base.Description = variable.Description
+		}
+		if variable.Sensitive {
+			base.Sensitive = variable.Sensitive
+		}
+		if variable.nullableSet {
+			base.Nullable = variable.Nullable
+			base.nullableSet = true
+		}
+		if len(variable.Validations) > 0 {
+			base.Validations = variable.Validations
+		}
+		index.addOverride(variable.Address(), variable.Location, base.Location)
+	}
+
+	for _, local := range override.Locals {
+		base := index.findLocal(local.Name)
+		if base == nil {
+			index.missingOverrideBase(local.Address(), local.Location)
+			continue
+		}
+
+		index.addOverride(local.Address(), local.Location, base.Location)
+	}
+
+	for _, resource := range override.Resources {
+		base := index.findResource(resource.Address())
+		if base == nil {
+			index.missingOverrideBase(resource.Address(), resource.Location)
+			continue
+		}
+
+		if resource.Count != nil {
+			base.Count = resource.Count
+		}
+		if resource.ForEach != nil {
+			base.ForEach = resource.ForEach
+		}
+		if len(resource.DependsOn) > 0 {
+			base.DependsOn = resource.DependsOn
+		}
+		if resource.Lifecycle != nil {
+			base.Lifecycle = resource.Lifecycle
+		}
+		index.addOverride(resource.Address(), resource.Location, base.Location)
+	}
+
+	for _, data := range override.Data {
+		base := index.findData(data.Address())
+		if base == nil {
+			index.missingOverrideBase(data.Address(), data.Location)
+			continue
+		}
+
+		index.addOverride(data.Address(), data.Location, base.Location)
+	}
+
+	for _, module := range override.Modules {
+		base := index.findModule(module.Name)
+		if base == nil {
+			index.missingOverrideBase(module.Address(), module.Location)
+			continue
+		}
+
+		if module.Source != "" {
+			base.Source = module.Source
+			base.SourceKind = module.SourceKind
+			base.SourceLocation = module.SourceLocation
+		}
+		if module.Version != "" {
+			base.Version = module.Version
+		}
+		if module.Count != nil {
+			base.Count = module.Count
+		}
+		if module.ForEach != nil {
+			base.ForEach = module.ForEach
+		}
+		if len(module.DependsOn) > 0 {
+			base.DependsOn = module.DependsOn
+		}
+		for _, argument := range module.Arguments {
+			base.Arguments = mergeModuleArgument(base.Arguments, argument)
+		}
+		index.addOverride(module.Address(), module.Location, base.Location)
+	}
+
+	for _, output := range override.Outputs {
+		base := index.findOutput(output.Name)
+		if base == nil {
+			index.missingOverrideBase(output.Address(), output.Location)
+			continue
+		}
+
+		if output.Value != "" {
+			base.Value = output.Value
+			base.ValueLocation = output.ValueLocation
+			base.References = output.References
+			base.valueReferences = output.valueReferences
+		}
+		if len(output.DependsOn) > 0 {
+			base.DependsOn = output.DependsOn
+		}
+		index.addOverride(output.Address(), output.Location, base.Location)
+	}
+
+	for _, settings := range override.Settings {
+		if len(index.Settings) == 0 {
+			index.Settings = append(index.Settings, settings)
+			continue
+		}
+
+		base := &index.Settings[0]
+		if settings.RequiredVersion != "" {
+			base.RequiredVersion = settings.RequiredVersion
+			base.RequiredVersionLocation = settings.RequiredVersionLocation
+		}
+		for _, provider := range settings.RequiredProviders {
+			base.RequiredProviders = mergeProviderRequirement(base.RequiredProviders, provider)
+		}
+	}
+
+	// checks, imports and removed blocks are not addressable so they are
+	// simply added to the configuration
+	index.Checks = append(index.Checks, override.Checks...)
+	index.Imports = append(index.Imports, override.Imports...)
+	index.Removed = append(index.Removed, override.Removed...)
+	for _, diagnostic := range override.collectedDiagnostics {
+		index.addCollectedDiagnostic(diagnostic)
+	}
+}
+
+// mergeProviderRequirement returns a copy of providers where provider replaces
+// the requirement of the same name or is added. providers is never written
+// to, the declarations merged from the cache share it with its entries
+func mergeProviderRequirement(providers []ProviderRequirement, provider ProviderRequirement) []ProviderRequirement {
+	merged := append([]ProviderRequirement{}, providers...)
+	for i := range merged {
+		if merged[i].Name == provider.Name {
+			merged[i] = provider
+			return merged
+		}
+	}
+
+	return append(merged, provider)
+}
+
+// mergeModuleArgument returns a copy of arguments where argument replaces the
+// argument of the same name or is added, like mergeProviderRequirement
+func mergeModuleArgument(arguments []ModuleArgument, argument ModuleArgument) []ModuleArgument {
+	merged := append([]ModuleArgument{}, arguments...)
+	for i := range merged {
+		if merged[i].Name == argument.Name {
+			merged[i] = argument
+			return merged
+		}
+	}
+
+	return append(merged, argument)
+}
+
+func (index *Index) findVariable(name string) *VariableDeclaration {
+	for i := range index.Variables {
+		if index.Variables[i].Name == name {
+			return &index.Variables[i]
+		}
+	}
+
+	return nil
+}
+
+func (index *Index) findLocal(name string) *LocalDeclaration {
+	for i := range index.Locals {
+		if index.Locals[i].Name == name {
+			return &index.Locals[i]
+		}
+	}
+
+	return nil
+}
+
+func (index *Index) findResource(address string) *ResourceDeclaration {
+	for i := range index.Resources {
+		if index.Resources[i].Address() == address {
+			return &index.Resources[i]
+		}
+	}
+
+	return nil
+}
+
+func (index *Index) findData(address string) *DataDeclaration {
+	for i := range index.Data {
+		if index.Data[i].Address() == address {
+			return &index.Data[i]
+		}
+	}
+
+	return nil
+}
+
+func (index *Index) findModule(name string) *ModuleDeclaration {
+	for i := range index.Modules {
+		if index.Modules[i].Name == name {
+			return &index.Modules[i]
+		}
+	}
+
+	return nil
+}
+
+func (index *Index) findOutput(name string) *OutputDeclaration {
+	for i := range index.Outputs {
+		if index.Outputs[i].Name == name {
+			return &index.Outputs[i]
+		}
+	}
+
+	return nil
+}
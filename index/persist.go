@@ -0,0 +1,426 @@
+package index
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+var ErrVersionMismatch = errors.New("index was saved by another version")
+
+// FileStamp identifies the state of a file by its modification time, in
+// nanoseconds since the epoch, and size
+type FileStamp struct {
+	ModTime int64
+	Size    int64
+}
+
+func StampFile(path string) (FileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileStamp{}, err
+	}
+
+	return FileStamp{info.ModTime().UnixNano(), info.Size()}, nil
+}
+
+// Save writes the index to path along with INDEX_VERSION and the stamps of
+// the files collected by Refresh, the file is replaced atomically
+func (index *Index) Save(path string) error {
+	index.lock()
+	defer index.mutex.Unlock()
+
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	encoder := gob.NewEncoder(file)
+	if err := encoder.Encode(INDEX_VERSION); err != nil {
+		file.Close()
+		return err
+	}
+	if err := encoder.Encode(index); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// LoadIndex reads an index written by Save, indexes saved by another version
+// fail with ErrVersionMismatch
+func LoadIndex(path string) (*Index, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	decoder := gob.NewDecoder(file)
+	version := ""
+	if err := decoder.Decode(&version); err != nil {
+		return nil, err
+	}
+	if version != INDEX_VERSION {
+		return nil, fmt.Errorf("%w: %s, expected %s", ErrVersionMismatch, version, INDEX_VERSION)
+	}
+
+	index := NewIndex()
+	if err := decoder.Decode(index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// ReadIndexFile reads an index printed by the index command, either as JSON
+// or with -format binary, which must then hold a single index
+func ReadIndexFile(path string) (*Index, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if trimmed := bytes.TrimSpace(contents); len(trimmed) > 0 && trimmed[0] == '{' {
+		index := NewIndex()
+		if err := json.Unmarshal(contents, index); err != nil {
+			return nil, err
+		}
+		return index, nil
+	}
+
+	indexes, err := ReadBinary(bytes.NewReader(contents))
+	if err != nil {
+		return nil, err
+	}
+	if len(indexes) != 1 {
+		return nil, fmt.Errorf("%s holds %d indexes, expected 1", path, len(indexes))
+	}
+	return indexes[0], nil
+}
+
+// UnmarshalJSON decodes an index printed as JSON, the state which is not
+// printed starts out empty as in NewIndex, so the index and its children can
+// be queried and updated. The raw AST printed with -raw-ast is skipped, its
+// nodes are interfaces JSON cannot be decoded into
+func (index *Index) UnmarshalJSON(data []byte) error {
+	mutex := index.mutex
+	*index = *NewIndex()
+	if mutex != nil {
+		index.mutex = mutex
+	}
+	decoded := struct {
+		*exportedIndex
+		RawAst json.RawMessage
+	}{exportedIndex: (*exportedIndex)(index)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	emptySlices(reflect.ValueOf(index).Elem())
+	return nil
+}
+
+// Refresh collects the files of paths which were not collected by an earlier
+// Refresh or changed since, according to their stamps, replacing what they
+// held before. Files collected earlier which are missing from paths, most
+// likely deleted, are removed. Returns the collected and removed paths,
+// Resolve and Analyze have to be called again if any. Override files are
+// merged into the declarations they override, so once overrides are
+// involved every file is collected again
+func (index *Index) Refresh(paths []string, includeRaw bool) ([]string, error) {
+	index.lock()
+	defer index.mutex.Unlock()
+
+	stamps := map[string]FileStamp{}
+	changed := []string{}
+	rebuild := len(index.Overrides) > 0 || len(index.pendingOverrides) > 0
+	for _, path := range paths {
+		stamp, err := StampFile(path)
+		if err != nil {
+			return nil, err
+		}
+		stamps[path] = stamp
+
+		if known, ok := index.stamps[path]; !ok || known != stamp {
+			changed = append(changed, path)
+			rebuild = rebuild || isOverrideFile(path)
+		}
+	}
+	removed := []string{}
+	for path := range index.stamps {
+		if _, ok := stamps[path]; !ok {
+			removed = append(removed, path)
+			rebuild = rebuild || isOverrideFile(path)
+		}
+	}
+	sort.Strings(removed)
+	if len(changed) == 0 && len(removed) == 0 {
+		return changed, nil
+	}
+
+	if rebuild {
+		mutex := index.mutex
+		*index = *NewIndex()
+		index.mutex = mutex
+		changed = paths
+	}
+	for _, path := range removed {
+		index.removeFile(path)
+	}
+	for _, path := range changed {
+		contents, err := ReadFile(path)
+		if _, ok := err.(*FileTooLargeError); !ok && err != nil {
+			return nil, err
+		}
+
+		// parse errors and files too large are kept as diagnostics of the
+		// file
+		index.removeFile(path)
+		if tooLarge, ok := err.(*FileTooLargeError); ok {
+			index.skipFile(tooLarge)
+		} else {
+			index.addString(contents, path, includeRaw)
+		}
+		index.stamps[path] = stamps[path]
+	}
+
+	if !rebuild {
+		index.sortByFiles(paths)
+	}
+	return append(changed, removed...), nil
+}
+
+// sortByFiles orders what was collected by the position of its file in
+// paths, as if the files had been collected in that order
+func (index *Index) sortByFiles(paths []string) {
+	order := map[string]int{}
+	for i, path := range paths {
+		order[path] = i
+	}
+	before := func(first hcltoken.Pos, second hcltoken.Pos) bool {
+		return order[first.Filename] < order[second.Filename]
+	}
+
+	sort.SliceStable(index.Variables, func(i, j int) bool {
+		return before(index.Variables[i].Location, index.Variables[j].Location)
+	})
+	sort.SliceStable(index.Resources, func(i, j int) bool {
+		return before(index.Resources[i].Location, index.Resources[j].Location)
+	})
+	sort.SliceStable(index.Data, func(i, j int) bool {
+		return before(index.Data[i].Location, index.Data[j].Location)
+	})
+	sort.SliceStable(index.Modules, func(i, j int) bool {
+		return before(index.Modules[i].Location, index.Modules[j].Location)
+	})
+	sort.SliceStable(index.Outputs, func(i, j int) bool {
+		return before(index.Outputs[i].Location, index.Outputs[j].Location)
+	})
+	sort.SliceStable(index.Locals, func(i, j int) bool {
+		return before(index.Locals[i].Location, index.Locals[j].Location)
+	})
+	sort.SliceStable(index.Settings, func(i, j int) bool {
+		return before(index.Settings[i].Location, index.Settings[j].Location)
+	})
+	sort.SliceStable(index.Imports, func(i, j int) bool {
+		return before(index.Imports[i].Location, index.Imports[j].Location)
+	})
+	sort.SliceStable(index.Checks, func(i, j int) bool {
+		return before(index.Checks[i].Location, index.Checks[j].Location)
+	})
+	sort.SliceStable(index.Removed, func(i, j int) bool {
+		return before(index.Removed[i].Location, index.Removed[j].Location)
+	})
+	sort.SliceStable(index.Assignments, func(i, j int) bool {
+		return before(index.Assignments[i].Location, index.Assignments[j].Location)
+	})
+	sort.SliceStable(index.collectedDiagnostics, func(i, j int) bool {
+		return before(index.collectedDiagnostics[i].Location, index.collectedDiagnostics[j].Location)
+	})
+	for _, list := range index.References {
+		sort.SliceStable(list.Locations, func(i, j int) bool {
+			return before(list.Locations[i], list.Locations[j])
+		})
+	}
+	for _, list := range index.FunctionCalls {
+		sort.SliceStable(list.Locations, func(i, j int) bool {
+			return before(list.Locations[i], list.Locations[j])
+		})
+	}
+	for _, references := range index.dependencies {
+		sort.SliceStable(references, func(i, j int) bool {
+			return before(references[i].Location, references[j].Location)
+		})
+	}
+}
+
+// removeFile removes everything collected from path
+func (index *Index) removeFile(path string) {
+	variables := []VariableDeclaration{}
+	for _, variable := range index.Variables {
+		if variable.Location.Filename != path {
+			variables = append(variables, variable)
+		}
+	}
+	index.Variables = variables
+
+	resources := []ResourceDeclaration{}
+	for _, resource := range index.Resources {
+		if resource.Location.Filename != path {
+			resources = append(resources, resource)
+		}
+	}
+	index.Resources = resources
+
+	data := []DataDeclaration{}
+	for _, item := range index.Data {
+		if item.Location.Filename != path {
+			data = append(data, item)
+		}
+	}
+	index.Data = data
+
+	modules := []ModuleDeclaration{}
+	for _, module := range index.Modules {
+		if module.Location.Filename != path {
+			modules = append(modules, module)
+		}
+	}
+	index.Modules = modules
+
+	outputs := []OutputDeclaration{}
+	for _, output := range index.Outputs {
+		if output.Location.Filename != path {
+			outputs = append(outputs, output)
+		}
+	}
+	index.Outputs = outputs
+
+	locals := []LocalDeclaration{}
+	for _, local := range index.Locals {
+		if local.Location.Filename != path {
+			locals = append(locals, local)
+		}
+	}
+	index.Locals = locals
+
+	settings := []SettingsDeclaration{}
+	for _, setting := range index.Settings {
+		if setting.Location.Filename != path {
+			settings = append(settings, setting)
+		}
+	}
+	index.Settings = settings
+
+	imports := []ImportDeclaration{}
+	for _, declaration := range index.Imports {
+		if declaration.Location.Filename != path {
+			imports = append(imports, declaration)
+		}
+	}
+	index.Imports = imports
+
+	checks := []CheckDeclaration{}
+	for _, check := range index.Checks {
+		if check.Location.Filename != path {
+			checks = append(checks, check)
+		}
+	}
+	index.Checks = checks
+
+	removed := []RemovedDeclaration{}
+	for _, declaration := range index.Removed {
+		if declaration.Location.Filename != path {
+			removed = append(removed, declaration)
+		}
+	}
+	index.Removed = removed
+
+	assignments := []VariableAssignment{}
+	for _, assignment := range index.Assignments {
+		if assignment.Location.Filename != path {
+			assignments = append(assignments, assignment)
+		}
+	}
+	index.Assignments = assignments
+
+	overrides := []Override{}
+	for _, override := range index.Overrides {
+		if override.Location.Filename != path {
+			overrides = append(overrides, override)
+		}
+	}
+	index.Overrides = overrides
+
+	index.Diagnostics = diagnosticsOutside(index.Diagnostics, path)
+	index.collectedDiagnostics = diagnosticsOutside(index.collectedDiagnostics, path)
+
+	for name, list := range index.References {
+		list.Locations = positionsOutside(list.Locations, path)
+		if len(list.Locations) == 0 {
+			delete(index.References, name)
+		} else {
+			index.References[name] = list
+		}
+	}
+	for name, list := range index.FunctionCalls {
+		list.Locations = positionsOutside(list.Locations, path)
+		if len(list.Locations) == 0 {
+			delete(index.FunctionCalls, name)
+		} else {
+			index.FunctionCalls[name] = list
+		}
+	}
+	for address, references := range index.dependencies {
+		kept := []reference{}
+		for _, reference := range references {
+			if reference.Location.Filename != path {
+				kept = append(kept, reference)
+			}
+		}
+		if len(kept) == 0 {
+			delete(index.dependencies, address)
+		} else {
+			index.dependencies[address] = kept
+		}
+	}
+
+	delete(index.tokens, path)
+	delete(index.links, path)
+	delete(index.folds, path)
+	delete(index.stamps, path)
+}
+
+func diagnosticsOutside(diagnostics []Diagnostic, path string) []Diagnostic {
+	kept := []Diagnostic{}
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Location.Filename != path {
+			kept = append(kept, diagnostic)
+		}
+	}
+	return kept
+}
+
+func positionsOutside(positions []hcltoken.Pos, path string) []hcltoken.Pos {
+	kept := []hcltoken.Pos{}
+	for _, position := range positions {
+		if position.Filename != path {
+			kept = append(kept, position)
+		}
+	}
+	return kept
+}
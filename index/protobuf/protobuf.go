@@ -0,0 +1,133 @@
+// Package protobuf encodes and decodes the protocol buffer wire format for
+// the few messages terraform-index reads and writes, SCIP indexes and the
+// gRPC service, without generated code
+package protobuf
+
+import (
+	"errors"
+)
+
+const (
+	WIRE_VARINT = 0
+	WIRE_BYTES  = 2
+)
+
+var ErrTruncated = errors.New("truncated protocol buffer")
+
+// Message appends the fields of a message, zero values are left out like
+// proto3 does
+type Message []byte
+
+func (message Message) Varint(value uint64) Message {
+	for value >= 0x80 {
+		message = append(message, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(message, byte(value))
+}
+
+func (message Message) Tag(field int, wire int) Message {
+	return message.Varint(uint64(field<<3 | wire))
+}
+
+func (message Message) Bytes(field int, value []byte) Message {
+	message = message.Tag(field, WIRE_BYTES).Varint(uint64(len(value)))
+	return append(message, value...)
+}
+
+func (message Message) Text(field int, value string) Message {
+	if value == "" {
+		return message
+	}
+	return message.Bytes(field, []byte(value))
+}
+
+func (message Message) Int(field int, value int) Message {
+	if value == 0 {
+		return message
+	}
+	return message.Tag(field, WIRE_VARINT).Varint(uint64(value))
+}
+
+func (message Message) Bool(field int, value bool) Message {
+	if !value {
+		return message
+	}
+	return message.Int(field, 1)
+}
+
+func (message Message) Packed(field int, values []int) Message {
+	packed := Message{}
+	for _, value := range values {
+		packed = packed.Varint(uint64(value))
+	}
+	return message.Bytes(field, packed)
+}
+
+// Field is a decoded field, Varint holds the value of varint fields and
+// Bytes the contents of length delimited ones
+type Field struct {
+	Number int
+	Wire   int
+	Varint uint64
+	Bytes  []byte
+}
+
+func varint(data []byte) (uint64, int, error) {
+	value := uint64(0)
+	for i := 0; i < len(data) && i < 10; i++ {
+		value |= uint64(data[i]&0x7f) << (7 * uint(i))
+		if data[i] < 0x80 {
+			return value, i + 1, nil
+		}
+	}
+	return 0, 0, ErrTruncated
+}
+
+// Parse decodes the varint and length delimited fields of a message, fixed
+// width fields are not used by any message read
+func Parse(data []byte) ([]Field, error) {
+	fields := []Field{}
+	for len(data) > 0 {
+		tag, n, err := varint(data)
+		if err != nil {
+			return nil, err
+		}
+		data = data[n:]
+
+		field := Field{Number: int(tag >> 3), Wire: int(tag & 7)}
+		switch field.Wire {
+		case WIRE_VARINT:
+			{
+				field.Varint, n, err = varint(data)
+				if err != nil {
+					return nil, err
+				}
+				data = data[n:]
+				break
+			}
+
+		case WIRE_BYTES:
+			{
+				length, n, err := varint(data)
+				if err != nil {
+					return nil, err
+				}
+				data = data[n:]
+				if uint64(len(data)) < length {
+					return nil, ErrTruncated
+				}
+				field.Bytes = data[:length]
+				data = data[length:]
+				break
+			}
+
+		default:
+			return nil, errors.New("unsupported protocol buffer wire type")
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,143 @@
+package index
+
+import (
+	"strings"
+)
+
+const (
+	REFERENCE_KIND_VARIABLE = "variable"
+	REFERENCE_KIND_LOCAL    = "local"
+	REFERENCE_KIND_RESOURCE = "resource"
+	REFERENCE_KIND_DATA     = "data"
+	REFERENCE_KIND_MODULE   = "module"
+	REFERENCE_KIND_BUILTIN  = "builtin"
+	REFERENCE_KIND_ITERATOR = "iterator"
+	REFERENCE_KIND_UNKNOWN  = "unknown"
+)
+
+var builtinReferences = map[string]bool{
+	"path.module":         true,
+	"path.root":           true,
+	"path.cwd":            true,
+	"terraform.workspace": true,
+	"terraform.env":       true,
+	"count.index":         true,
+	"each.key":            true,
+	"each.value":          true,
+}
+
+// getReferenceKind classifies a key by its root, references to names bound
+// by an enclosing scope like the iterator of a dynamic block are told apart
+// by the collectors instead, which record them as REFERENCE_KIND_ITERATOR
+func getReferenceKind(key string) string {
+	if builtinReferences[key] {
+		return REFERENCE_KIND_BUILTIN
+	}
+
+	parts := strings.Split(key, ".")
+	switch parts[0] {
+	case "var":
+		return REFERENCE_KIND_VARIABLE
+
+	case "local":
+		return REFERENCE_KIND_LOCAL
+
+	case "data":
+		return REFERENCE_KIND_DATA
+
+	case "ephemeral":
+		return REFERENCE_KIND_RESOURCE
+
+	case "module":
+		return REFERENCE_KIND_MODULE
+	}
+
+	if len(parts) > 1 && strings.Contains(parts[0], "_") {
+		return REFERENCE_KIND_RESOURCE
+	}
+
+	return REFERENCE_KIND_UNKNOWN
+}
+
+// getReferenceKeys maps an interpolated name like `aws_instance.web.0.id` to
+// the keys it is recorded under in Index.References, returns nil if the name
+// is not indexed
+func getReferenceKeys(name string) []string {
+	parts := strings.Split(name, ".")
+	if len(parts) < 2 {
+		return nil
+	}
+
+	switch parts[0] {
+	case "var", "local":
+		return []string{strings.Join(parts[:2], ".")}
+
+	case "path", "terraform", "count", "each":
+		key := strings.Join(parts[:2], ".")
+		if !builtinReferences[key] {
+			return nil
+		}
+		return []string{key}
+
+	case "data", "ephemeral":
+		if len(parts) < 3 {
+			return nil
+		}
+		return []string{strings.Join(parts[:3], ".")}
+
+	case "module":
+		// record the module call itself as well as the output being used
+		keys := []string{strings.Join(parts[:2], ".")}
+		if len(parts) > 2 {
+			keys = append(keys, strings.Join(parts[:3], "."))
+		}
+		return keys
+	}
+
+	// resource types are always prefixed by their provider, which tells
+	// them apart from iterators like `ingress.value`. Iterators named like
+	// `ingress_rule` are skipped by getUnboundKeys
+	if strings.Contains(parts[0], "_") {
+		return []string{strings.Join(parts[:2], ".")}
+	}
+
+	return nil
+}
+
+// addressAttributes maps the blocks naming the address of a resource to the
+// attribute holding it, like `to` of an import block. The address is what the
+// block is about rather than a reference, so the collectors leave it out
+var addressAttributes = map[string]string{
+	"import":  "to",
+	"removed": "from",
+}
+
+// getUnboundKeys wraps getKeys to skip the names rooted at a name bound by an
+// enclosing scope, like the iterator of a dynamic block
+func getUnboundKeys(bound []string, getKeys func(string) []string) func(string) []string {
+	if len(bound) == 0 {
+		return getKeys
+	}
+
+	return func(name string) []string {
+		root := strings.SplitN(name, ".", 2)[0]
+		for _, iterator := range bound {
+			if iterator == root {
+				return nil
+			}
+		}
+		return getKeys(name)
+	}
+}
+
+// getIteratorKeys returns a key function accepting references to the iterator
+// of a dynamic block, like `ingress.value`
+func getIteratorKeys(iterator string) func(string) []string {
+	return func(name string) []string {
+		parts := strings.Split(name, ".")
+		if len(parts) < 2 || parts[0] != iterator {
+			return nil
+		}
+		return []string{strings.Join(parts[:2], ".")}
+	}
+}
@@ -0,0 +1,67 @@
+package index
+
+import (
+	"testing"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+// TestForAndSplatReferences checks the names referenced within for and splat
+// expressions reach References with their positions, while the names bound
+// by the for expression do not
+func TestForAndSplatReferences(t *testing.T) {
+	tests := []struct {
+		path     string
+		contents string
+		expected map[string]hcltoken.Pos
+	}{
+		{
+			path: "main.tf",
+			contents: `output "ids" {
+  value = [for s in var.subnets : s.id]
+}
+
+output "web" {
+  value = aws_instance.web[*].id
+}
+`,
+			expected: map[string]hcltoken.Pos{
+				"var.subnets":      {Filename: "main.tf", Line: 2, Column: 21},
+				"aws_instance.web": {Filename: "main.tf", Line: 6, Column: 11},
+			},
+		},
+		{
+			path: "main.tf.json",
+			contents: `{"output": {
+  "ids": {"value": "${[for s in var.subnets : s.id]}"},
+  "web": {"value": "${aws_instance.web[*].id}"}
+}}
+`,
+			expected: map[string]hcltoken.Pos{
+				"var.subnets":      {Filename: "main.tf.json", Line: 2, Column: 33},
+				"aws_instance.web": {Filename: "main.tf.json", Line: 3, Column: 23},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		index := NewIndex()
+		if err := index.CollectString([]byte(test.contents), test.path, false); err != nil {
+			t.Fatalf("%s: %s", test.path, err)
+		}
+
+		for name, location := range test.expected {
+			// offsets are left out, positions are what editors jump to
+			locations := index.References[name].Locations
+			if len(locations) != 1 || locations[0].Filename != location.Filename ||
+				locations[0].Line != location.Line || locations[0].Column != location.Column {
+				t.Errorf("%s: expected %s referenced at %v, got %v", test.path, name, location, locations)
+			}
+		}
+		for name := range index.References {
+			if _, ok := test.expected[name]; !ok {
+				t.Errorf("%s: unexpected reference %s", test.path, name)
+			}
+		}
+	}
+}
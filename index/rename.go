@@ -0,0 +1,85 @@
+package index
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+// TextEdit replaces the text from Location up to EndLocation with NewText
+type TextEdit struct {
+	Location    hcltoken.Pos
+	EndLocation hcltoken.Pos
+	NewText     string
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+func shiftPos(pos hcltoken.Pos, n int) hcltoken.Pos {
+	pos.Column += n
+	pos.Offset += n
+	return pos
+}
+
+// nameEdit replaces name where it starts n bytes after location
+func nameEdit(location hcltoken.Pos, n int, name string, newName string) TextEdit {
+	start := shiftPos(location, n)
+	return TextEdit{
+		Location:    start,
+		EndLocation: shiftPos(start, len(name)),
+		NewText:     newName,
+	}
+}
+
+// nameOffset returns how far the name of a declaration starts after its
+// location. Block labels are quoted, as are the keys of JSON files, while
+// locals are bare attribute names in native syntax
+func nameOffset(declaration Declaration, location hcltoken.Pos) int {
+	if _, ok := declaration.(LocalDeclaration); ok && !strings.HasSuffix(location.Filename, ".json") {
+		return 0
+	}
+
+	return 1
+}
+
+// Rename returns the edits renaming the declaration at address, like
+// `var.name` or `aws_instance.web`, to newName along with its declarations in
+// override files and every reference, those within interpolations included.
+// The edits are sorted by file and position
+func (index *Index) Rename(address string, newName string) ([]TextEdit, error) {
+	if !identifierPattern.MatchString(newName) {
+		return nil, fmt.Errorf("'%s' is not a valid name", newName)
+	}
+
+	declaration := index.findDeclaration(address)
+	if declaration == nil {
+		return nil, fmt.Errorf("'%s' is not declared", address)
+	}
+
+	prefix := address[:strings.LastIndex(address, ".")+1]
+	name := address[len(prefix):]
+	if index.findDeclaration(prefix+newName) != nil {
+		return nil, fmt.Errorf("'%s' is already declared", prefix+newName)
+	}
+
+	edits := []TextEdit{}
+	location := declaration.Position()
+	edits = append(edits, nameEdit(location, nameOffset(declaration, location), name, newName))
+	for _, override := range index.Overrides {
+		if override.Address == address {
+			edits = append(edits, nameEdit(override.Location, nameOffset(declaration, override.Location), name, newName))
+		}
+	}
+	for _, reference := range index.ReferencesToAddress(address) {
+		edits = append(edits, nameEdit(reference, len(prefix), name, newName))
+	}
+
+	sort.SliceStable(edits, func(i, j int) bool {
+		a, b := edits[i].Location, edits[j].Location
+		return a.Filename < b.Filename || (a.Filename == b.Filename && a.Offset < b.Offset)
+	})
+	return edits, nil
+}
@@ -0,0 +1,197 @@
+package index
+
+import (
+	"sort"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+type Resolution struct {
+	Name        string
+	Location    hcltoken.Pos
+	Declaration hcltoken.Pos
+}
+
+type Usage struct {
+	Declaration hcltoken.Pos
+	Count       int
+	Locations   []hcltoken.Pos
+}
+
+type Declaration interface {
+	Address() string
+	Position() hcltoken.Pos
+}
+
+func (variable VariableDeclaration) Address() string {
+	return "var." + variable.Name
+}
+
+func (variable VariableDeclaration) Position() hcltoken.Pos {
+	return variable.Location
+}
+
+func (local LocalDeclaration) Address() string {
+	return "local." + local.Name
+}
+
+func (local LocalDeclaration) Position() hcltoken.Pos {
+	return local.Location
+}
+
+func (resource ResourceDeclaration) Address() string {
+	if resource.Kind == RESOURCE_KIND_EPHEMERAL {
+		return "ephemeral." + resource.Type + "." + resource.Name
+	}
+
+	return resource.Type + "." + resource.Name
+}
+
+func (resource ResourceDeclaration) Position() hcltoken.Pos {
+	return resource.Location
+}
+
+func (data DataDeclaration) Address() string {
+	return "data." + data.Type + "." + data.Name
+}
+
+func (data DataDeclaration) Position() hcltoken.Pos {
+	return data.Location
+}
+
+func (module ModuleDeclaration) Address() string {
+	return "module." + module.Name
+}
+
+func (module ModuleDeclaration) Position() hcltoken.Pos {
+	return module.Location
+}
+
+func (output OutputDeclaration) Address() string {
+	return "output." + output.Name
+}
+
+func (output OutputDeclaration) Position() hcltoken.Pos {
+	return output.Location
+}
+
+type declaredAddress struct {
+	Address  string
+	Location hcltoken.Pos
+}
+
+func (index *Index) declaredAddresses() []declaredAddress {
+	addresses := []declaredAddress{}
+	for _, variable := range index.Variables {
+		addresses = append(addresses, declaredAddress{variable.Address(), variable.Location})
+	}
+	for _, local := range index.Locals {
+		addresses = append(addresses, declaredAddress{local.Address(), local.Location})
+	}
+	for _, resource := range index.Resources {
+		addresses = append(addresses, declaredAddress{resource.Address(), resource.Location})
+	}
+	for _, data := range index.Data {
+		addresses = append(addresses, declaredAddress{data.Address(), data.Location})
+	}
+	for _, module := range index.Modules {
+		addresses = append(addresses, declaredAddress{module.Address(), module.Location})
+	}
+	for _, output := range index.Outputs {
+		addresses = append(addresses, declaredAddress{output.Address(), output.Location})
+	}
+
+	return addresses
+}
+
+// Declarations maps the address of every referenceable declaration to its
+// location, if an address is declared more than once the first declaration
+// wins
+func (index *Index) Declarations() map[string]hcltoken.Pos {
+	declarations := map[string]hcltoken.Pos{}
+	for _, declared := range index.declaredAddresses() {
+		if strings.HasPrefix(declared.Address, "output.") {
+			continue
+		}
+		if _, exists := declarations[declared.Address]; !exists {
+			declarations[declared.Address] = declared.Location
+		}
+	}
+
+	return declarations
+}
+
+// Resolve links every collected reference to the declaration it refers to,
+// it should be called once all files have been collected
+func (index *Index) Resolve() {
+	index.lock()
+	defer index.mutex.Unlock()
+
+	index.applyOverrides()
+	declarations := index.Declarations()
+
+	index.Resolved = []Resolution{}
+	index.UsageCounts = map[string]Usage{}
+	for address, declaration := range declarations {
+		locations := index.ReferencesToAddress(address)
+		index.UsageCounts[address] = Usage{
+			Declaration: declaration,
+			Count:       len(locations),
+			Locations:   locations,
+		}
+	}
+
+	for _, name := range index.sortedReferenceNames() {
+		declaration, ok := declarations[name]
+		if !ok {
+			continue
+		}
+
+		for _, location := range index.References[name].Locations {
+			index.Resolved = append(index.Resolved, Resolution{
+				Name:        name,
+				Location:    location,
+				Declaration: declaration,
+			})
+		}
+	}
+}
+
+func (index *Index) ReferencesTo(declaration Declaration) []hcltoken.Pos {
+	return index.ReferencesToAddress(declaration.Address())
+}
+
+func (index *Index) ReferencesToAddress(address string) []hcltoken.Pos {
+	locations := []hcltoken.Pos{}
+	if list, ok := index.References[address]; ok {
+		locations = append(locations, list.Locations...)
+	}
+
+	return locations
+}
+
+// Files returns the sorted paths of the files declaring, referring to or
+// reporting anything in the index
+func (index *Index) Files() []string {
+	seen := map[string]bool{}
+	for _, declared := range index.declaredAddresses() {
+		seen[declared.Location.Filename] = true
+	}
+	for _, list := range index.References {
+		for _, location := range list.Locations {
+			seen[location.Filename] = true
+		}
+	}
+	for _, diagnostic := range index.Diagnostics {
+		seen[diagnostic.Location.Filename] = true
+	}
+	delete(seen, "")
+
+	files := []string{}
+	for file := range seen {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
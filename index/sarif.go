@@ -0,0 +1,205 @@
+package index
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+const (
+	SARIF_VERSION = "2.1.0"
+	SARIF_SCHEMA  = "https://json.schemastore.org/sarif-2.1.0.json"
+	SARIF_SRCROOT = "%SRCROOT%"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                        `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds"`
+	Results            []sarifResult                    `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID           string          `json:"ruleId"`
+	RuleIndex        int             `json:"ruleIndex"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+	Fixes            []sarifFix      `json:"fixes,omitempty"`
+}
+
+type sarifLocation struct {
+	ID               int                   `json:"id,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion  `json:"deletedRegion"`
+	InsertedContent sarifMessage `json:"insertedContent"`
+}
+
+// sarifLevels maps the severities of diagnostics to SARIF levels
+var sarifLevels = map[string]string{
+	SEVERITY_ERROR:   "error",
+	SEVERITY_WARNING: "warning",
+	SEVERITY_INFO:    "note",
+}
+
+// WriteSARIF writes the diagnostics of the analyzed indexes, parse errors
+// included, as a SARIF 2.1.0 log for code scanning. File paths are written
+// relative to projectRoot, which is given as the %SRCROOT% base
+func WriteSARIF(writer io.Writer, projectRoot string, indexes ...*Index) error {
+	root, err := filepath.Abs(projectRoot)
+	if err != nil {
+		return err
+	}
+	artifact := func(path string) sarifArtifactLocation {
+		if absolute, err := filepath.Abs(path); err == nil {
+			if relative, err := filepath.Rel(root, absolute); err == nil {
+				return sarifArtifactLocation{URI: filepath.ToSlash(relative), URIBaseID: SARIF_SRCROOT}
+			}
+		}
+		return sarifArtifactLocation{URI: FileURI(path)}
+	}
+	region := func(start hcltoken.Pos, end hcltoken.Pos) sarifRegion {
+		if end.Line < start.Line || (end.Line == start.Line && end.Column < start.Column) {
+			end = start
+		}
+		return sarifRegion{start.Line, start.Column, end.Line, end.Column}
+	}
+	location := func(start hcltoken.Pos, end hcltoken.Pos) sarifLocation {
+		location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: artifact(start.Filename)}}
+		if start.Line > 0 {
+			region := region(start, end)
+			location.PhysicalLocation.Region = &region
+		}
+		return location
+	}
+
+	diagnostics := []Diagnostic{}
+	codes := []string{}
+	rules := map[string]int{}
+	for _, index := range indexes {
+		for _, diagnostic := range index.Diagnostics {
+			diagnostics = append(diagnostics, diagnostic)
+			if _, ok := rules[diagnostic.Code]; !ok {
+				rules[diagnostic.Code] = 0
+				codes = append(codes, diagnostic.Code)
+			}
+		}
+	}
+	sort.Strings(codes)
+
+	driver := sarifDriver{
+		Name:           "terraform-index",
+		Version:        INDEX_VERSION,
+		InformationURI: "https://github.com/mauve/terraform-index",
+		Rules:          []sarifRule{},
+	}
+	for i, code := range codes {
+		rules[code] = i
+		driver.Rules = append(driver.Rules, sarifRule{ID: code})
+	}
+
+	results := []sarifResult{}
+	for _, diagnostic := range diagnostics {
+		result := sarifResult{
+			RuleID:           diagnostic.Code,
+			RuleIndex:        rules[diagnostic.Code],
+			Level:            sarifLevels[diagnostic.Severity],
+			Message:          sarifMessage{diagnostic.Message},
+			Locations:        []sarifLocation{location(diagnostic.Location, diagnostic.EndLocation)},
+			RelatedLocations: []sarifLocation{},
+			Fixes:            []sarifFix{},
+		}
+		for i, related := range diagnostic.RelatedLocations {
+			relatedLocation := location(related, related)
+			relatedLocation.ID = i + 1
+			result.RelatedLocations = append(result.RelatedLocations, relatedLocation)
+		}
+		if diagnostic.Suggestion != "" && diagnostic.Location.Line > 0 {
+			result.Fixes = append(result.Fixes, sarifFix{
+				Description: sarifMessage{"Replace with " + diagnostic.Suggestion},
+				ArtifactChanges: []sarifArtifactChange{{
+					ArtifactLocation: artifact(diagnostic.Location.Filename),
+					Replacements: []sarifReplacement{{
+						DeletedRegion:   region(diagnostic.Location, diagnostic.EndLocation),
+						InsertedContent: sarifMessage{diagnostic.Suggestion},
+					}},
+				}},
+			})
+		}
+		results = append(results, result)
+	}
+
+	log := sarifLog{
+		Version: SARIF_VERSION,
+		Schema:  SARIF_SCHEMA,
+		Runs: []sarifRun{{
+			Tool: sarifTool{driver},
+			OriginalURIBaseIDs: map[string]sarifArtifactLocation{
+				SARIF_SRCROOT: {URI: FileURI(root) + "/"},
+			},
+			Results: results,
+		}},
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}
@@ -0,0 +1,174 @@
+package index
+
+import (
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+	"github.com/mauve/terraform-index/index/protobuf"
+)
+
+// SCIP_SCHEME is the scheme of the SCIP symbols of Terraform declarations.
+// A symbol names the module directory, relative to the project root, as its
+// package and the address of the declaration as its descriptors:
+//
+//	terraform . <directory> . var/<name>.
+//	terraform . <directory> . local/<name>.
+//	terraform . <directory> . resource/<type>#<name>.
+//	terraform . <directory> . data/<type>#<name>.
+//	terraform . <directory> . module/<name>.
+//	terraform . <directory> . output/<name>.
+//
+// Spaces in the directory are doubled and names which are not simple
+// identifiers are quoted in backticks, as the SCIP grammar requires
+const SCIP_SCHEME = "terraform"
+
+const (
+	scipRoleDefinition = 0x1
+	scipRoleReadAccess = 0x8
+
+	// UTF8 text and UTF-8 code unit columns, matching the byte columns of
+	// the index
+	scipEncodingUTF8 = 1
+	scipPositionUTF8 = 1
+)
+
+const scipSimpleIdChars = "_+-$"
+
+func scipIdentifier(name string) string {
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune(scipSimpleIdChars, r)) {
+			return "`" + strings.Replace(name, "`", "``", -1) + "`"
+		}
+	}
+	return name
+}
+
+// scipSymbol returns the symbol of the declaration at address in directory
+// following SCIP_SCHEME
+func scipSymbol(directory string, address string) string {
+	parts := strings.Split(address, ".")
+	descriptors := ""
+	switch parts[0] {
+	case "var", "local", "module", "output":
+		descriptors = parts[0] + "/" + scipIdentifier(parts[1]) + "."
+	case "data":
+		descriptors = "data/" + scipIdentifier(parts[1]) + "#" + scipIdentifier(parts[2]) + "."
+	default:
+		descriptors = "resource/" + scipIdentifier(parts[0]) + "#" + scipIdentifier(parts[1]) + "."
+	}
+
+	return SCIP_SCHEME + " . " + strings.Replace(directory, " ", "  ", -1) + " . " + descriptors
+}
+
+// scipRange returns the zero-based range of the text from start to end, in
+// the short form SCIP uses for ranges on one line
+func scipRange(start hcltoken.Pos, end hcltoken.Pos) []int {
+	line, character := start.Line-1, start.Column-1
+	if line < 0 {
+		line = 0
+	}
+	if character < 0 {
+		character = 0
+	}
+	if end.Line == start.Line {
+		return []int{line, character, character + end.Column - start.Column}
+	}
+	return []int{line, character, end.Line - 1, end.Column - 1}
+}
+
+type scipDocument struct {
+	occurrences []protobuf.Message
+	symbols     []protobuf.Message
+}
+
+// WriteSCIP writes the declarations of the resolved indexes and every
+// reference to them as a SCIP index, paths are made relative to projectRoot.
+// The symbols follow SCIP_SCHEME and carry the hover text of the declaration
+// as documentation
+func WriteSCIP(writer io.Writer, projectRoot string, indexes ...*Index) error {
+	root, err := filepath.Abs(projectRoot)
+	if err != nil {
+		return err
+	}
+	relative := func(path string) string {
+		if absolute, err := filepath.Abs(path); err == nil {
+			if rel, err := filepath.Rel(root, absolute); err == nil {
+				return filepath.ToSlash(rel)
+			}
+		}
+		return filepath.ToSlash(path)
+	}
+
+	documents := map[string]*scipDocument{}
+	document := func(path string) *scipDocument {
+		path = relative(path)
+		if _, ok := documents[path]; !ok {
+			documents[path] = &scipDocument{occurrences: []protobuf.Message{}, symbols: []protobuf.Message{}}
+		}
+		return documents[path]
+	}
+	occurrence := func(start hcltoken.Pos, end hcltoken.Pos, symbol string, roles int) {
+		doc := document(start.Filename)
+		doc.occurrences = append(doc.occurrences, protobuf.Message{}.Packed(1, scipRange(start, end)).Text(2, symbol).Int(3, roles))
+	}
+
+	for _, index := range indexes {
+		seen := map[string]bool{}
+		for _, declared := range index.declaredAddresses() {
+			address := declared.Address
+			if seen[address] {
+				continue
+			}
+			seen[address] = true
+
+			location := declared.Location
+			symbol := scipSymbol(relative(filepath.Dir(location.Filename)), address)
+			prefix := address[:strings.LastIndex(address, ".")+1]
+			name := address[len(prefix):]
+
+			offset := 1
+			information := protobuf.Message{}.Text(1, symbol).Text(6, name)
+			if declaration := index.findDeclaration(address); declaration != nil {
+				offset = nameOffset(declaration, location)
+				if hover, ok := index.HoverFor(address); ok {
+					information = information.Text(3, hover.Markdown())
+				}
+			}
+			doc := document(location.Filename)
+			doc.symbols = append(doc.symbols, information)
+
+			start := shiftPos(location, offset)
+			occurrence(start, shiftPos(start, len(name)), symbol, scipRoleDefinition)
+			for _, reference := range index.ReferencesToAddress(address) {
+				occurrence(reference, shiftPos(reference, len(address)), symbol, scipRoleReadAccess)
+			}
+		}
+	}
+
+	paths := []string{}
+	for path := range documents {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	toolInfo := protobuf.Message{}.Text(1, "terraform-index").Text(2, INDEX_VERSION)
+	metadata := protobuf.Message{}.Bytes(2, toolInfo).Text(3, FileURI(root)).Int(4, scipEncodingUTF8)
+	message := protobuf.Message{}.Bytes(1, metadata)
+	for _, path := range paths {
+		doc := protobuf.Message{}.Text(1, path)
+		for _, occurrence := range documents[path].occurrences {
+			doc = doc.Bytes(2, occurrence)
+		}
+		for _, symbol := range documents[path].symbols {
+			doc = doc.Bytes(3, symbol)
+		}
+		doc = doc.Text(4, "terraform").Int(6, scipPositionUTF8)
+		message = message.Bytes(2, doc)
+	}
+
+	_, err = writer.Write(message)
+	return err
+}
@@ -0,0 +1,113 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+const SHARD_MANIFEST = "manifest.json"
+
+// ShardManifest links the shards written by WriteShards, one index file per
+// root module, so consumers can load only the roots they need
+type ShardManifest struct {
+	Version string
+	Shards  []Shard
+}
+
+type Shard struct {
+	Root         string
+	File         string
+	Declarations int
+	Diagnostics  int
+}
+
+var shardNameReplacer = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// shardFile returns a file name for the shard of root which is not used yet
+func shardFile(root string, used map[string]bool) string {
+	name := shardNameReplacer.ReplaceAllString(filepath.ToSlash(filepath.Clean(root)), "_")
+	if name == "." || name == "_" {
+		name = "root"
+	}
+
+	file := name + ".json"
+	for i := 2; used[file]; i++ {
+		file = fmt.Sprintf("%s-%d.json", name, i)
+	}
+	used[file] = true
+	return file
+}
+
+func writeJSONFile(path string, value interface{}) error {
+	contents, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, contents, 0644)
+}
+
+// WriteShards writes the index of every root to its own file in directory,
+// along with a manifest.json listing them
+func (workspace *Workspace) WriteShards(directory string) (*ShardManifest, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+
+	manifest := &ShardManifest{
+		Version: workspace.Version,
+		Shards:  []Shard{},
+	}
+	used := map[string]bool{SHARD_MANIFEST: true}
+	for _, root := range workspace.Directories() {
+		index := workspace.Roots[root]
+		shard := Shard{
+			Root:         root,
+			File:         shardFile(root, used),
+			Declarations: len(index.declaredAddresses()),
+			Diagnostics:  len(index.Diagnostics),
+		}
+		if err := writeJSONFile(filepath.Join(directory, shard.File), index); err != nil {
+			return nil, err
+		}
+
+		manifest.Shards = append(manifest.Shards, shard)
+	}
+
+	if err := writeJSONFile(filepath.Join(directory, SHARD_MANIFEST), manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// LoadShardManifest reads the manifest written by WriteShards
+func LoadShardManifest(directory string) (*ShardManifest, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(directory, SHARD_MANIFEST))
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &ShardManifest{}
+	if err := json.Unmarshal(contents, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// LoadShard reads the index of a single shard listed in the manifest
+func LoadShard(directory string, shard Shard) (*Index, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(directory, shard.File))
+	if err != nil {
+		return nil, err
+	}
+
+	index := NewIndex()
+	if err := json.Unmarshal(contents, index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
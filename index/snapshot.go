@@ -0,0 +1,128 @@
+package index
+
+import (
+	"sync"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+// Snapshot returns a view of the index as it is now, which is not changed
+// by collecting into, updating, resolving or analyzing the index afterwards.
+// Taking a snapshot is cheap, the index and the snapshot share everything
+// until either of them changes, which then copies it first. Snapshots of
+// resolved and analyzed indexes may be queried while the index is being
+// reindexed
+func (index *Index) Snapshot() *Index {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	snapshot := *index
+	snapshot.mutex = &sync.Mutex{}
+	snapshot.Children = map[string]*Index{}
+	for name, child := range index.Children {
+		snapshot.Children[name] = child.Snapshot()
+	}
+
+	index.shared = true
+	snapshot.shared = true
+	return &snapshot
+}
+
+// lock locks the index for a change, copying what it shares with a snapshot
+// and dropping the lookup table of names
+func (index *Index) lock() {
+	index.mutex.Lock()
+	index.own()
+	index.names = nil
+}
+
+// own copies the lists and maps the index shares with a snapshot, the
+// declarations they hold are copied along since changes modify them in
+// place, as are the lists within them which overrides merge into. The other
+// indexes they refer to are not copied
+func (index *Index) own() {
+	if !index.shared {
+		return
+	}
+	index.shared = false
+
+	index.Diagnostics = append([]Diagnostic{}, index.Diagnostics...)
+	index.Variables = append([]VariableDeclaration{}, index.Variables...)
+	index.Resources = append([]ResourceDeclaration{}, index.Resources...)
+	index.Data = append([]DataDeclaration{}, index.Data...)
+	index.Modules = append([]ModuleDeclaration{}, index.Modules...)
+	index.Outputs = append([]OutputDeclaration{}, index.Outputs...)
+	index.Locals = append([]LocalDeclaration{}, index.Locals...)
+	index.Settings = append([]SettingsDeclaration{}, index.Settings...)
+	index.Imports = append([]ImportDeclaration{}, index.Imports...)
+	index.Checks = append([]CheckDeclaration{}, index.Checks...)
+	index.Removed = append([]RemovedDeclaration{}, index.Removed...)
+	index.Assignments = append([]VariableAssignment{}, index.Assignments...)
+	index.Overrides = append([]Override{}, index.Overrides...)
+	index.Resolved = append([]Resolution{}, index.Resolved...)
+	index.collectedDiagnostics = append([]Diagnostic{}, index.collectedDiagnostics...)
+	index.pendingOverrides = append([]*Index{}, index.pendingOverrides...)
+
+	for i := range index.Modules {
+		index.Modules[i].Arguments = append([]ModuleArgument{}, index.Modules[i].Arguments...)
+	}
+	for i := range index.Settings {
+		index.Settings[i].RequiredProviders = append([]ProviderRequirement{}, index.Settings[i].RequiredProviders...)
+	}
+
+	children := map[string]*Index{}
+	for name, child := range index.Children {
+		children[name] = child
+	}
+	index.Children = children
+
+	references := map[string]ReferenceList{}
+	for name, list := range index.References {
+		list.Locations = append([]hcltoken.Pos{}, list.Locations...)
+		references[name] = list
+	}
+	index.References = references
+
+	functionCalls := map[string]FunctionCallList{}
+	for name, list := range index.FunctionCalls {
+		list.Locations = append([]hcltoken.Pos{}, list.Locations...)
+		functionCalls[name] = list
+	}
+	index.FunctionCalls = functionCalls
+
+	usageCounts := map[string]Usage{}
+	for address, usage := range index.UsageCounts {
+		usageCounts[address] = usage
+	}
+	index.UsageCounts = usageCounts
+
+	dependencies := map[string][]reference{}
+	for address, references := range index.dependencies {
+		dependencies[address] = append([]reference{}, references...)
+	}
+	index.dependencies = dependencies
+
+	tokens := map[string][]SemanticToken{}
+	for path, list := range index.tokens {
+		tokens[path] = list
+	}
+	index.tokens = tokens
+
+	links := map[string][]DocumentLink{}
+	for path, list := range index.links {
+		links[path] = list
+	}
+	index.links = links
+
+	folds := map[string][]FoldingRange{}
+	for path, list := range index.folds {
+		folds[path] = list
+	}
+	index.folds = folds
+
+	stamps := map[string]FileStamp{}
+	for path, stamp := range index.stamps {
+		stamps[path] = stamp
+	}
+	index.stamps = stamps
+}
@@ -0,0 +1,93 @@
+package index
+
+import (
+	"strings"
+)
+
+// Stats summarizes the size of an index, to track how a configuration grows.
+// Files counts the variable definitions files too. Declarations are counted
+// by kind, ephemeral resources apart from managed
+// ones, resources by type and resources and data sources by provider.
+// References counts every reference and ReferencedNames the distinct names
+// referred to. The children of the index are not included
+type Stats struct {
+	Files           int
+	Declarations    map[string]int
+	ResourceTypes   map[string]int
+	Providers       map[string]int
+	ParseErrors     int
+	Diagnostics     map[string]int
+	References      int
+	ReferencedNames int
+	FunctionCalls   int
+}
+
+// resourceProvider returns the provider of a resource type like
+// `aws_instance`, the prefix before the first `_`
+func resourceProvider(resourceType string) string {
+	if separator := strings.Index(resourceType, "_"); separator > 0 {
+		return resourceType[:separator]
+	}
+	return resourceType
+}
+
+// Stats counts what the index holds
+func (index *Index) Stats() Stats {
+	files := map[string]bool{}
+	for _, file := range index.Files() {
+		files[file] = true
+	}
+	for _, assignment := range index.Assignments {
+		files[assignment.Location.Filename] = true
+	}
+
+	stats := Stats{
+		Files: len(files),
+		Declarations: map[string]int{
+			SYMBOL_VARIABLE: len(index.Variables),
+			SYMBOL_LOCAL:    len(index.Locals),
+			SYMBOL_RESOURCE: 0,
+			SYMBOL_DATA:     len(index.Data),
+			SYMBOL_MODULE:   len(index.Modules),
+			SYMBOL_OUTPUT:   len(index.Outputs),
+			"import":        len(index.Imports),
+			"check":         len(index.Checks),
+			"removed":       len(index.Removed),
+
+			RESOURCE_KIND_EPHEMERAL: 0,
+		},
+		ResourceTypes: map[string]int{},
+		Providers:     map[string]int{},
+		Diagnostics:   map[string]int{},
+	}
+
+	for _, resource := range index.Resources {
+		if resource.Kind == RESOURCE_KIND_EPHEMERAL {
+			stats.Declarations[RESOURCE_KIND_EPHEMERAL]++
+		} else {
+			stats.Declarations[SYMBOL_RESOURCE]++
+		}
+		stats.ResourceTypes[resource.Type]++
+		stats.Providers[resourceProvider(resource.Type)]++
+	}
+	for _, data := range index.Data {
+		stats.Providers[resourceProvider(data.Type)]++
+	}
+
+	for _, diagnostic := range index.Diagnostics {
+		stats.Diagnostics[diagnostic.Severity]++
+		if diagnostic.Code == CODE_PARSE_ERROR {
+			stats.ParseErrors++
+		}
+	}
+
+	for _, list := range index.References {
+		stats.References += len(list.Locations)
+		stats.ReferencedNames++
+	}
+	for _, list := range index.FunctionCalls {
+		stats.FunctionCalls += len(list.Locations)
+	}
+
+	return stats
+}
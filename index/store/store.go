@@ -0,0 +1,210 @@
+// Package store persists indexes in a SQLite database so they can be queried
+// with SQL. The database is written with the sqlite3 command line tool and
+// has the following schema:
+//
+//	declarations  kind, type, name, address, file, line, column
+//	refs          name, kind, file, line, column
+//	diagnostics   severity, code, message, file, line, column, end_line, end_column
+//	metadata      key, value
+//
+// The kind of a declaration is one of variable, local, resource, data,
+// module or output, its type is the type of resources and data sources and
+// its address what references refer to, so refs.name joins
+// declarations.address. Every table but metadata is indexed by name (code
+// for diagnostics) and by file
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+	"github.com/mauve/terraform-index/index"
+)
+
+const SQLITE_BINARY = "sqlite3"
+
+const SCHEMA = `DROP TABLE IF EXISTS declarations;
+DROP TABLE IF EXISTS refs;
+DROP TABLE IF EXISTS diagnostics;
+DROP TABLE IF EXISTS metadata;
+CREATE TABLE declarations (
+  kind TEXT NOT NULL,
+  type TEXT,
+  name TEXT NOT NULL,
+  address TEXT NOT NULL,
+  file TEXT NOT NULL,
+  line INTEGER NOT NULL,
+  column INTEGER NOT NULL
+);
+CREATE TABLE refs (
+  name TEXT NOT NULL,
+  kind TEXT NOT NULL,
+  file TEXT NOT NULL,
+  line INTEGER NOT NULL,
+  column INTEGER NOT NULL
+);
+CREATE TABLE diagnostics (
+  severity TEXT NOT NULL,
+  code TEXT NOT NULL,
+  message TEXT NOT NULL,
+  file TEXT NOT NULL,
+  line INTEGER NOT NULL,
+  column INTEGER NOT NULL,
+  end_line INTEGER NOT NULL,
+  end_column INTEGER NOT NULL
+);
+CREATE TABLE metadata (
+  key TEXT PRIMARY KEY,
+  value TEXT NOT NULL
+);
+CREATE INDEX declarations_name ON declarations (name);
+CREATE INDEX declarations_address ON declarations (address);
+CREATE INDEX declarations_file ON declarations (file);
+CREATE INDEX refs_name ON refs (name);
+CREATE INDEX refs_file ON refs (file);
+CREATE INDEX diagnostics_code ON diagnostics (code);
+CREATE INDEX diagnostics_file ON diagnostics (file);
+`
+
+// quote returns text as an SQL string literal, empty text as NULL when
+// nullable
+func quote(text string, nullable bool) string {
+	if text == "" && nullable {
+		return "NULL"
+	}
+
+	return "'" + strings.Replace(text, "'", "''", -1) + "'"
+}
+
+func writeDeclaration(writer io.Writer, kind string, declarationType string, name string, address string, location hcltoken.Pos) error {
+	_, err := fmt.Fprintf(writer, "INSERT INTO declarations VALUES (%s, %s, %s, %s, %s, %d, %d);\n",
+		quote(kind, false), quote(declarationType, true), quote(name, false), quote(address, false),
+		quote(location.Filename, false), location.Line, location.Column)
+	return err
+}
+
+func writeDeclarations(writer io.Writer, indexed *index.Index) error {
+	for _, variable := range indexed.Variables {
+		if err := writeDeclaration(writer, index.SYMBOL_VARIABLE, "", variable.Name, variable.Address(), variable.Location); err != nil {
+			return err
+		}
+	}
+	for _, local := range indexed.Locals {
+		if err := writeDeclaration(writer, index.SYMBOL_LOCAL, "", local.Name, local.Address(), local.Location); err != nil {
+			return err
+		}
+	}
+	for _, resource := range indexed.Resources {
+		if err := writeDeclaration(writer, index.SYMBOL_RESOURCE, resource.Type, resource.Name, resource.Address(), resource.Location); err != nil {
+			return err
+		}
+	}
+	for _, data := range indexed.Data {
+		if err := writeDeclaration(writer, index.SYMBOL_DATA, data.Type, data.Name, data.Address(), data.Location); err != nil {
+			return err
+		}
+	}
+	for _, module := range indexed.Modules {
+		if err := writeDeclaration(writer, index.SYMBOL_MODULE, "", module.Name, module.Address(), module.Location); err != nil {
+			return err
+		}
+	}
+	for _, output := range indexed.Outputs {
+		if err := writeDeclaration(writer, index.SYMBOL_OUTPUT, "", output.Name, output.Address(), output.Location); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteSQL writes the statements creating the schema and filling it with
+// the indexes, wrapped in a transaction
+func WriteSQL(writer io.Writer, indexes ...*index.Index) error {
+	if _, err := fmt.Fprintf(writer, "BEGIN;\n%s", SCHEMA); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(writer, "INSERT INTO metadata VALUES ('version', %s);\n", quote(index.INDEX_VERSION, false)); err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		if err := writeDeclarations(writer, index); err != nil {
+			return err
+		}
+
+		names := []string{}
+		for name := range index.References {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			list := index.References[name]
+			for _, location := range list.Locations {
+				_, err := fmt.Fprintf(writer, "INSERT INTO refs VALUES (%s, %s, %s, %d, %d);\n",
+					quote(name, false), quote(list.Kind, false), quote(location.Filename, false), location.Line, location.Column)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, diagnostic := range index.Diagnostics {
+			_, err := fmt.Fprintf(writer, "INSERT INTO diagnostics VALUES (%s, %s, %s, %s, %d, %d, %d, %d);\n",
+				quote(diagnostic.Severity, false), quote(diagnostic.Code, false), quote(diagnostic.Message, false),
+				quote(diagnostic.Location.Filename, false), diagnostic.Location.Line, diagnostic.Location.Column,
+				diagnostic.EndLocation.Line, diagnostic.EndLocation.Column)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(writer, "COMMIT;\n")
+	return err
+}
+
+// Check fails when the sqlite3 command line tool Write requires is missing,
+// so callers can tell before indexing anything
+func Check() error {
+	_, err := sqliteBinary()
+	return err
+}
+
+func sqliteBinary() (string, error) {
+	binary, err := exec.LookPath(SQLITE_BINARY)
+	if err != nil {
+		return "", fmt.Errorf("writing a SQLite database requires the %s command line tool in PATH: %w", SQLITE_BINARY, err)
+	}
+	return binary, nil
+}
+
+// Write writes the indexes to the SQLite database at path, replacing the
+// tables of a previous run. It requires the sqlite3 command line tool
+func Write(path string, indexes ...*index.Index) error {
+	binary, err := sqliteBinary()
+	if err != nil {
+		return err
+	}
+
+	script := &bytes.Buffer{}
+	if err := WriteSQL(script, indexes...); err != nil {
+		return err
+	}
+
+	command := exec.Command(binary, "-bail", path)
+	command.Stdin = script
+	if output, err := command.CombinedOutput(); err != nil {
+		if len(output) > 0 {
+			return fmt.Errorf("%s", strings.TrimSpace(string(output)))
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+	"github.com/mauve/terraform-index/index"
+)
+
+const storeTestConfiguration = `variable "region" {}
+
+resource "aws_instance" "web" {
+  region = var.region
+}
+
+output "id" {
+  value = aws_instance.web.id
+}
+`
+
+// query runs statement against the database at path with the sqlite3 tool
+func query(t *testing.T, path string, statement string) string {
+	output, err := exec.Command(SQLITE_BINARY, path, statement).CombinedOutput()
+	if err != nil {
+		t.Fatalf("cannot query %q: %s: %s", statement, err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestWrite(t *testing.T) {
+	if err := Check(); err != nil {
+		t.Skip(err)
+	}
+
+	indexed := index.NewIndex()
+	if err := indexed.CollectString([]byte(storeTestConfiguration), "main.tf", false); err != nil {
+		t.Fatal(err)
+	}
+	indexed.Resolve()
+	location := hcltoken.Pos{Filename: "main.tf", Line: 1, Column: 1}
+	indexed.Diagnostics = append(indexed.Diagnostics, index.Diagnostic{
+		Severity:    index.SEVERITY_WARNING,
+		Code:        "test",
+		Message:     "it's quoted",
+		Location:    location,
+		EndLocation: location,
+	})
+
+	path := filepath.Join(t.TempDir(), "index.db")
+	// writing twice replaces the tables of the first run
+	for i := 0; i < 2; i++ {
+		if err := Write(path, indexed); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if version := query(t, path, "SELECT value FROM metadata WHERE key = 'version'"); version != index.INDEX_VERSION {
+		t.Errorf("expected version %s, got %q", index.INDEX_VERSION, version)
+	}
+	declarations := query(t, path, "SELECT kind, ifnull(type, ''), address, line FROM declarations ORDER BY line")
+	expected := "variable||var.region|1\nresource|aws_instance|aws_instance.web|3\noutput||output.id|7"
+	if declarations != expected {
+		t.Errorf("expected declarations\n%s\ngot\n%s", expected, declarations)
+	}
+	references := query(t, path, "SELECT d.address, count(r.name) FROM declarations d JOIN refs r ON r.name = d.address GROUP BY d.address ORDER BY d.address")
+	if references != "aws_instance.web|1\nvar.region|1" {
+		t.Errorf("unexpected references\n%s", references)
+	}
+	if message := query(t, path, "SELECT message FROM diagnostics WHERE code = 'test'"); message != "it's quoted" {
+		t.Errorf("expected the quoted message, got %q", message)
+	}
+}
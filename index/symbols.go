@@ -0,0 +1,204 @@
+package index
+
+import (
+	"sort"
+
+	hclast "github.com/hashicorp/hcl/hcl/ast"
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+const (
+	SYMBOL_VARIABLE = "variable"
+	SYMBOL_LOCAL    = "local"
+	SYMBOL_RESOURCE = "resource"
+	SYMBOL_DATA     = "data"
+	SYMBOL_MODULE   = "module"
+	SYMBOL_OUTPUT   = "output"
+	SYMBOL_BLOCK    = "block"
+	SYMBOL_META     = "meta"
+)
+
+// NestedBlock is a block within a resource or data source, like
+// `ebs_block_device` or `dynamic "ingress"`. Label is the first label of the
+// block, if any
+type NestedBlock struct {
+	Type        string
+	Label       string
+	Location    hcltoken.Pos
+	EndLocation hcltoken.Pos
+	Blocks      []NestedBlock
+}
+
+// Symbol is an entry of the outline of a file. Location is the position of
+// the name while BlockLocation and EndLocation span the whole declaration
+type Symbol struct {
+	Name          string
+	Kind          string
+	Location      hcltoken.Pos
+	BlockLocation hcltoken.Pos
+	EndLocation   hcltoken.Pos
+	Children      []Symbol
+}
+
+func newSymbol(name string, kind string, location hcltoken.Pos, blockLocation hcltoken.Pos, endLocation hcltoken.Pos) Symbol {
+	// declarations without recorded ranges span just their name
+	if blockLocation.Line == 0 {
+		blockLocation = location
+	}
+	if endLocation.Line == 0 {
+		endLocation = endPos(location, name)
+	}
+
+	return Symbol{
+		Name:          name,
+		Kind:          kind,
+		Location:      location,
+		BlockLocation: blockLocation,
+		EndLocation:   endLocation,
+		Children:      []Symbol{},
+	}
+}
+
+func blockSymbols(blocks []NestedBlock) []Symbol {
+	symbols := []Symbol{}
+	for _, block := range blocks {
+		name := block.Type
+		if block.Label != "" {
+			name += " \"" + block.Label + "\""
+		}
+
+		symbol := newSymbol(name, SYMBOL_BLOCK, block.Location, block.Location, block.EndLocation)
+		symbol.Children = blockSymbols(block.Blocks)
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols
+}
+
+// SymbolsInFile returns the declarations of a file ordered by position, the
+// symbols of resources and data sources hold their nested blocks as children
+func (index *Index) SymbolsInFile(path string) []Symbol {
+	symbols := []Symbol{}
+	for _, variable := range index.Variables {
+		if variable.Location.Filename == path {
+			symbols = append(symbols, newSymbol(variable.Address(), SYMBOL_VARIABLE,
+				variable.Location, variable.BlockLocation, variable.EndLocation))
+		}
+	}
+	for _, local := range index.Locals {
+		if local.Location.Filename == path {
+			symbols = append(symbols, newSymbol(local.Address(), SYMBOL_LOCAL,
+				local.Location, local.Location, local.EndLocation))
+		}
+	}
+	for _, resource := range index.Resources {
+		if resource.Location.Filename == path {
+			symbol := newSymbol(resource.Address(), SYMBOL_RESOURCE,
+				resource.Location, resource.BlockLocation, resource.EndLocation)
+			symbol.Children = blockSymbols(resource.Blocks)
+			symbols = append(symbols, symbol)
+		}
+	}
+	for _, data := range index.Data {
+		if data.Location.Filename == path {
+			symbol := newSymbol(data.Address(), SYMBOL_DATA,
+				data.Location, data.BlockLocation, data.EndLocation)
+			symbol.Children = blockSymbols(data.Blocks)
+			symbols = append(symbols, symbol)
+		}
+	}
+	for _, module := range index.Modules {
+		if module.Location.Filename == path {
+			symbols = append(symbols, newSymbol(module.Address(), SYMBOL_MODULE,
+				module.Location, module.BlockLocation, module.EndLocation))
+		}
+	}
+	for _, output := range index.Outputs {
+		if output.Location.Filename == path {
+			symbols = append(symbols, newSymbol(output.Address(), SYMBOL_OUTPUT,
+				output.Location, output.BlockLocation, output.EndLocation))
+		}
+	}
+
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return symbols[i].BlockLocation.Offset < symbols[j].BlockLocation.Offset
+	})
+	return symbols
+}
+
+// getEndPos returns the position just past an HCL1 node
+func getEndPos(node hclast.Node, path string) hcltoken.Pos {
+	switch node.(type) {
+	case *hclast.ObjectType:
+		{
+			end := node.(*hclast.ObjectType).Rbrace
+			end.Filename = path
+			end.Column++
+			end.Offset++
+			return end
+		}
+
+	case *hclast.ListType:
+		{
+			end := node.(*hclast.ListType).Rbrack
+			end.Filename = path
+			end.Column++
+			end.Offset++
+			return end
+		}
+
+	case *hclast.LiteralType:
+		{
+			token := node.(*hclast.LiteralType).Token
+			return endPos(getPos(token, path), token.Text)
+		}
+	}
+
+	end := node.Pos()
+	end.Filename = path
+	return end
+}
+
+// getNestedBlocks returns the blocks of an HCL1 object, attributes assigned an
+// object like `tags = {}` are not blocks
+func getNestedBlocks(object *hclast.ObjectType, path string) []NestedBlock {
+	blocks := []NestedBlock{}
+	for _, item := range object.List.Items {
+		nested, ok := getObject(item.Val)
+		if !ok || item.Assign.IsValid() {
+			continue
+		}
+
+		block := NestedBlock{
+			Type:        getText(item.Keys[0].Token),
+			Location:    getPos(item.Keys[0].Token, path),
+			EndLocation: getEndPos(item.Val, path),
+			Blocks:      getNestedBlocks(nested, path),
+		}
+		if len(item.Keys) > 1 {
+			block.Label = getText(item.Keys[1].Token)
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks
+}
+
+func (c *hcl2Collector) getNestedBlocks(body *hclsyntax.Body) []NestedBlock {
+	blocks := []NestedBlock{}
+	for _, nested := range body.Blocks {
+		block := NestedBlock{
+			Type:        nested.Type,
+			Location:    c.pos(nested.TypeRange.Start),
+			EndLocation: c.pos(nested.Range().End),
+			Blocks:      c.getNestedBlocks(nested.Body),
+		}
+		if len(nested.Labels) > 0 {
+			block.Label = nested.Labels[0]
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks
+}
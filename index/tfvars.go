@@ -0,0 +1,73 @@
+package index
+
+import (
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	hcljson "github.com/hashicorp/hcl/v2/json"
+)
+
+type VariableAssignment struct {
+	Name     string
+	Value    string
+	Location hcltoken.Pos
+}
+
+// isVariablesFile reports whether path is a variable definitions file,
+// like terraform.tfvars, prod.auto.tfvars or terraform.tfvars.json
+func isVariablesFile(path string) bool {
+	return strings.HasSuffix(path, ".tfvars") || strings.HasSuffix(path, ".tfvars.json")
+}
+
+// CollectVariables collects the assignments of a variable definitions file
+func (index *Index) CollectVariables(contents []byte, path string) error {
+	index.lock()
+	defer index.mutex.Unlock()
+	return index.collectVariables(contents, path)
+}
+
+func (index *Index) collectVariables(contents []byte, path string) error {
+	var file *hcl2.File
+	var diagnostics hcl2.Diagnostics
+	if strings.HasSuffix(path, ".json") {
+		file, diagnostics = hcljson.Parse(contents, path)
+	} else {
+		file, diagnostics = hclsyntax.ParseConfig(contents, path, hcl2.Pos{Line: 1, Column: 1})
+	}
+	if diagnostics.HasErrors() {
+		index.addHCL2Diagnostics(diagnostics, path)
+		return diagnostics
+	}
+
+	attributes, diagnostics := file.Body.JustAttributes()
+	if diagnostics.HasErrors() {
+		index.addHCL2Diagnostics(diagnostics, path)
+	}
+
+	for _, attribute := range sortedHCL2Attributes(attributes) {
+		index.Assignments = append(index.Assignments, VariableAssignment{
+			Name:     attribute.Name,
+			Value:    strings.Trim(string(attribute.Expr.Range().SliceBytes(contents)), "\""),
+			Location: toTokenPos(attribute.NameRange.Start, path),
+		})
+	}
+
+	return nil
+}
+
+func (index *Index) checkUndeclaredAssignments() {
+	declared := map[string]bool{}
+	for _, variable := range index.Variables {
+		declared[variable.Name] = true
+	}
+
+	for _, assignment := range index.Assignments {
+		if !declared[assignment.Name] {
+			diagnostic := index.addDiagnostic(SEVERITY_WARNING, CODE_UNDECLARED_ASSIGNMENT, assignment.Location,
+				"Value for undeclared variable '%s'", assignment.Name)
+			diagnostic.EndLocation = endPos(assignment.Location, assignment.Name)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package index
+
+import (
+	"sort"
+
+	hclast "github.com/hashicorp/hcl/hcl/ast"
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	TOKEN_BLOCK_TYPE  = "block-type"
+	TOKEN_BLOCK_LABEL = "block-label"
+	TOKEN_ATTRIBUTE   = "attribute"
+	TOKEN_REFERENCE   = "reference"
+	TOKEN_FUNCTION    = "function"
+	TOKEN_STRING      = "string"
+	TOKEN_NUMBER      = "number"
+)
+
+// SemanticToken classifies the text from Location up to EndLocation for
+// semantic highlighting
+type SemanticToken struct {
+	Kind        string
+	Location    hcltoken.Pos
+	EndLocation hcltoken.Pos
+}
+
+func (index *Index) addToken(kind string, location hcltoken.Pos, endLocation hcltoken.Pos) {
+	index.tokens[location.Filename] = append(index.tokens[location.Filename], SemanticToken{
+		Kind:        kind,
+		Location:    location,
+		EndLocation: endLocation,
+	})
+}
+
+// SemanticTokens returns the classified tokens of a file ordered by position.
+// Tokens never overlap, strings are split around the references and function
+// calls interpolated into them. JSON files have no tokens since editors
+// highlight JSON on their own
+func (index *Index) SemanticTokens(path string) []SemanticToken {
+	tokens := append([]SemanticToken{}, index.tokens[path]...)
+	sort.SliceStable(tokens, func(i, j int) bool {
+		a, b := tokens[i], tokens[j]
+		if a.Location.Offset != b.Location.Offset {
+			return a.Location.Offset < b.Location.Offset
+		}
+		return a.EndLocation.Offset > b.EndLocation.Offset
+	})
+
+	result := []SemanticToken{}
+	for _, token := range tokens {
+		n := len(result)
+		if n == 0 || token.Location.Offset >= result[n-1].EndLocation.Offset {
+			result = append(result, token)
+			continue
+		}
+
+		// the token lies within the previous one, typically a reference
+		// within a string, so the previous token is cut around it
+		outer := result[n-1]
+		result[n-1].EndLocation = token.Location
+		if result[n-1].Location.Offset == token.Location.Offset {
+			result = result[:n-1]
+		}
+		result = append(result, token)
+		if token.EndLocation.Offset < outer.EndLocation.Offset {
+			outer.Location = token.EndLocation
+			result = append(result, outer)
+		}
+	}
+
+	return result
+}
+
+// addSyntaxTokens records the tokens of an hcl/v2 body
+func (c *hcl2Collector) addSyntaxTokens(body *hclsyntax.Body) {
+	keys := map[int]bool{}
+	hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl2.Diagnostics {
+		switch node.(type) {
+		case *hclsyntax.Block:
+			{
+				block := node.(*hclsyntax.Block)
+				c.index.addToken(TOKEN_BLOCK_TYPE, c.pos(block.TypeRange.Start), c.pos(block.TypeRange.End))
+				for _, label := range block.LabelRanges {
+					c.index.addToken(TOKEN_BLOCK_LABEL, c.pos(label.Start), c.pos(label.End))
+				}
+				break
+			}
+
+		case *hclsyntax.Attribute:
+			{
+				attribute := node.(*hclsyntax.Attribute)
+				c.index.addToken(TOKEN_ATTRIBUTE, c.pos(attribute.NameRange.Start), c.pos(attribute.NameRange.End))
+				break
+			}
+
+		case *hclsyntax.ObjectConsKeyExpr:
+			{
+				// bare object keys parse as traversals but are not references
+				key := node.(*hclsyntax.ObjectConsKeyExpr)
+				if hcl2.ExprAsKeyword(key.Wrapped) != "" && !key.ForceNonLiteral {
+					keys[key.Wrapped.Range().Start.Byte] = true
+					c.index.addToken(TOKEN_ATTRIBUTE, c.pos(key.Wrapped.Range().Start), c.pos(key.Wrapped.Range().End))
+				}
+				break
+			}
+
+		case *hclsyntax.ScopeTraversalExpr:
+			{
+				traversal := node.(*hclsyntax.ScopeTraversalExpr)
+				if !keys[traversal.SrcRange.Start.Byte] {
+					c.index.addToken(TOKEN_REFERENCE, c.pos(traversal.SrcRange.Start), c.pos(traversal.SrcRange.End))
+				}
+				break
+			}
+
+		case *hclsyntax.FunctionCallExpr:
+			{
+				call := node.(*hclsyntax.FunctionCallExpr)
+				c.index.addToken(TOKEN_FUNCTION, c.pos(call.NameRange.Start), c.pos(call.NameRange.End))
+				break
+			}
+
+		case *hclsyntax.LiteralValueExpr:
+			{
+				literal := node.(*hclsyntax.LiteralValueExpr)
+				if literal.Val.Type() == cty.String {
+					c.index.addToken(TOKEN_STRING, c.pos(literal.SrcRange.Start), c.pos(literal.SrcRange.End))
+				} else if literal.Val.Type() == cty.Number {
+					c.index.addToken(TOKEN_NUMBER, c.pos(literal.SrcRange.Start), c.pos(literal.SrcRange.End))
+				}
+				break
+			}
+		}
+
+		return nil
+	})
+}
+
+func (index *Index) addKeyToken(kind string, token hcltoken.Token, path string) {
+	location := getPos(token, path)
+	index.addToken(kind, location, endPos(location, token.Text))
+}
+
+// addASTTokens records the tokens of the items of an HCL1 object list, keys
+// assigned with `=` are attributes while the others name a block
+func (index *Index) addASTTokens(list *hclast.ObjectList, path string) {
+	for _, item := range list.Items {
+		for i, key := range item.Keys {
+			if item.Assign.IsValid() {
+				index.addKeyToken(TOKEN_ATTRIBUTE, key.Token, path)
+			} else if i == 0 {
+				index.addKeyToken(TOKEN_BLOCK_TYPE, key.Token, path)
+			} else {
+				index.addKeyToken(TOKEN_BLOCK_LABEL, key.Token, path)
+			}
+		}
+
+		index.addValueTokens(item.Val, path)
+	}
+}
+
+func (index *Index) addValueTokens(node hclast.Node, path string) {
+	switch node.(type) {
+	case *hclast.ObjectType:
+		{
+			index.addASTTokens(node.(*hclast.ObjectType).List, path)
+			break
+		}
+
+	case *hclast.ListType:
+		{
+			for _, element := range node.(*hclast.ListType).List {
+				index.addValueTokens(element, path)
+			}
+			break
+		}
+
+	case *hclast.LiteralType:
+		{
+			token := node.(*hclast.LiteralType).Token
+			switch token.Type {
+			case hcltoken.STRING, hcltoken.HEREDOC:
+				index.addKeyToken(TOKEN_STRING, token, path)
+			case hcltoken.NUMBER, hcltoken.FLOAT:
+				index.addKeyToken(TOKEN_NUMBER, token, path)
+			}
+			break
+		}
+	}
+}
+
+// addIndexTokens records the references and function calls collected from
+// an HCL1 file, hil does not expose their full ranges so they span the
+// collected name
+func (index *Index) addIndexTokens(path string) {
+	for _, name := range index.sortedReferenceNames() {
+		for _, location := range index.References[name].Locations {
+			if location.Filename == path {
+				index.addToken(TOKEN_REFERENCE, location, shiftPos(location, len(name)))
+			}
+		}
+	}
+	for _, name := range index.sortedFunctionNames() {
+		for _, location := range index.FunctionCalls[name].Locations {
+			if location.Filename == path {
+				index.addToken(TOKEN_FUNCTION, location, shiftPos(location, len(name)))
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package index
+
+import (
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+const (
+	TYPE_STRING = "string"
+	TYPE_NUMBER = "number"
+	TYPE_BOOL   = "bool"
+	TYPE_ANY    = "any"
+	TYPE_LIST   = "list"
+	TYPE_SET    = "set"
+	TYPE_MAP    = "map"
+	TYPE_TUPLE  = "tuple"
+	TYPE_OBJECT = "object"
+)
+
+// TypeConstraint is the structured form of a variable type constraint, only
+// the fields relevant to Kind are set: Element for list, set and map,
+// Elements for tuple and Attributes for object
+type TypeConstraint struct {
+	Kind       string
+	Element    *TypeConstraint
+	Elements   []TypeConstraint
+	Attributes []TypeAttribute
+}
+
+type TypeAttribute struct {
+	Name     string
+	Type     TypeConstraint
+	Optional bool
+	Default  string
+}
+
+func newTypeConstraint(kind string) *TypeConstraint {
+	return &TypeConstraint{
+		Kind:       kind,
+		Elements:   []TypeConstraint{},
+		Attributes: []TypeAttribute{},
+	}
+}
+
+// parseTypeConstraint parses the text of a type constraint like
+// `map(object({ name = string, port = optional(number, 80) }))`, it returns nil
+// when text is not a valid type constraint
+func parseTypeConstraint(text string) *TypeConstraint {
+	source := []byte(text)
+	expr, diagnostics := hclsyntax.ParseExpression(source, "", hcl2.Pos{Line: 1, Column: 1})
+	if diagnostics.HasErrors() {
+		return nil
+	}
+
+	return getTypeConstraint(expr, source)
+}
+
+func getTypeConstraint(expr hcl2.Expression, source []byte) *TypeConstraint {
+	switch keyword := hcl2.ExprAsKeyword(expr); keyword {
+	case TYPE_STRING, TYPE_NUMBER, TYPE_BOOL, TYPE_ANY:
+		return newTypeConstraint(keyword)
+	case TYPE_LIST, TYPE_SET, TYPE_MAP:
+		// legacy bare collection types, like `type = "list"`, accept any element
+		constraint := newTypeConstraint(keyword)
+		constraint.Element = newTypeConstraint(TYPE_ANY)
+		return constraint
+	}
+
+	call, diagnostics := hcl2.ExprCall(expr)
+	if diagnostics.HasErrors() || len(call.Arguments) != 1 {
+		return nil
+	}
+
+	constraint := newTypeConstraint(call.Name)
+	switch call.Name {
+	case TYPE_LIST, TYPE_SET, TYPE_MAP:
+		constraint.Element = getTypeConstraint(call.Arguments[0], source)
+		if constraint.Element == nil {
+			return nil
+		}
+
+	case TYPE_TUPLE:
+		elements, diagnostics := hcl2.ExprList(call.Arguments[0])
+		if diagnostics.HasErrors() {
+			return nil
+		}
+		for _, element := range elements {
+			elementType := getTypeConstraint(element, source)
+			if elementType == nil {
+				return nil
+			}
+			constraint.Elements = append(constraint.Elements, *elementType)
+		}
+
+	case TYPE_OBJECT:
+		items, diagnostics := hcl2.ExprMap(call.Arguments[0])
+		if diagnostics.HasErrors() {
+			return nil
+		}
+		for _, item := range items {
+			attribute, ok := getTypeAttribute(item, source)
+			if !ok {
+				return nil
+			}
+			constraint.Attributes = append(constraint.Attributes, attribute)
+		}
+
+	default:
+		return nil
+	}
+
+	return constraint
+}
+
+// getTypeAttribute returns an object attribute, which may be wrapped in
+// `optional(type)` or `optional(type, default)`
+func getTypeAttribute(item hcl2.KeyValuePair, source []byte) (TypeAttribute, bool) {
+	attribute := TypeAttribute{
+		Name: hcl2.ExprAsKeyword(item.Key),
+	}
+	if attribute.Name == "" {
+		attribute.Name = exprString(item.Key)
+	}
+
+	value := item.Value
+	if call, diagnostics := hcl2.ExprCall(value); !diagnostics.HasErrors() && call.Name == "optional" {
+		if len(call.Arguments) < 1 || len(call.Arguments) > 2 {
+			return attribute, false
+		}
+
+		attribute.Optional = true
+		value = call.Arguments[0]
+		if len(call.Arguments) == 2 {
+			attribute.Default = string(call.Arguments[1].Range().SliceBytes(source))
+		}
+	}
+
+	attributeType := getTypeConstraint(value, source)
+	if attribute.Name == "" || attributeType == nil {
+		return attribute, false
+	}
+
+	attribute.Type = *attributeType
+	return attribute, true
+}
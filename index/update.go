@@ -0,0 +1,68 @@
+package index
+
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	ErrFileExists       = errors.New("file is already collected")
+	ErrOverridesApplied = errors.New("overrides were applied to the declarations")
+)
+
+// AddFile collects a file which is not in the index yet, returning the parse
+// error like CollectString
+func (index *Index) AddFile(path string, contents []byte) error {
+	index.lock()
+	defer index.mutex.Unlock()
+
+	files := index.Files()
+	if found := sort.SearchStrings(files, path); found < len(files) && files[found] == path {
+		return ErrFileExists
+	}
+
+	return index.addString(contents, path, false)
+}
+
+// UpdateFile replaces everything collected from a file with what is
+// collected from contents, returning the parse error like CollectString.
+// Resolve and Analyze have to be called again before querying the index.
+// Override files change the declarations of other files once Resolve
+// applied them, an index with applied overrides cannot be updated file by
+// file and fails with ErrOverridesApplied
+func (index *Index) UpdateFile(path string, contents []byte) error {
+	index.lock()
+	defer index.mutex.Unlock()
+
+	if err := index.removeCollected(path); err != nil {
+		return err
+	}
+
+	return index.addString(contents, path, false)
+}
+
+// RemoveFile removes everything collected from a file, like UpdateFile it
+// fails with ErrOverridesApplied once overrides were applied
+func (index *Index) RemoveFile(path string) error {
+	index.lock()
+	defer index.mutex.Unlock()
+	return index.removeCollected(path)
+}
+
+func (index *Index) removeCollected(path string) error {
+	if len(index.Overrides) > 0 {
+		return ErrOverridesApplied
+	}
+
+	pending := []*Index{}
+	for _, override := range index.pendingOverrides {
+		files := override.Files()
+		if len(files) != 1 || files[0] != path {
+			pending = append(pending, override)
+		}
+	}
+	index.pendingOverrides = pending
+
+	index.removeFile(path)
+	return nil
+}
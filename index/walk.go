@@ -0,0 +1,62 @@
+package index
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// walk calls fn for every file and directory below root like filepath.Walk.
+// When followSymlinks is set links to directories are walked as well, every
+// directory is then walked only once so links forming a cycle terminate and
+// a directory linked into the tree twice is not indexed twice
+func walk(root string, followSymlinks bool, fn filepath.WalkFunc) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+
+	return walkPath(root, info, followSymlinks, map[string]bool{}, fn)
+}
+
+func walkPath(path string, info os.FileInfo, followSymlinks bool, visited map[string]bool, fn filepath.WalkFunc) error {
+	if followSymlinks && info.Mode()&os.ModeSymlink != 0 {
+		// broken links are passed on as links
+		if target, err := os.Stat(path); err == nil {
+			info = target
+		}
+	}
+
+	if !info.IsDir() {
+		return fn(path, info, nil)
+	}
+
+	if followSymlinks {
+		if real, err := filepath.EvalSymlinks(path); err == nil {
+			if visited[real] {
+				return nil
+			}
+			visited[real] = true
+		}
+	}
+
+	if err := fn(path, info, nil); err != nil {
+		if err == filepath.SkipDir {
+			return nil
+		}
+		return err
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return fn(path, info, err)
+	}
+
+	for _, entry := range entries {
+		if err := walkPath(filepath.Join(path, entry.Name()), entry, followSymlinks, visited, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+)
+
+// Workspace holds one Index per root module directory, so several stacks can
+// be indexed together while their declarations stay in separate namespaces
+type Workspace struct {
+	Version string
+	Roots   map[string]*Index
+
+	excludes       []string
+	followSymlinks bool
+	cache          *FileCache
+}
+
+// RootLocation is a location found by a query across the roots of a
+// Workspace
+type RootLocation struct {
+	Root     string
+	Location hcltoken.Pos
+}
+
+func NewWorkspace() *Workspace {
+	workspace := new(Workspace)
+	workspace.Version = INDEX_VERSION
+	workspace.Roots = map[string]*Index{}
+	return workspace
+}
+
+// AddRoot collects the root module in directory, replacing any index
+// previously collected for it
+func (workspace *Workspace) AddRoot(directory string, includeRaw bool) error {
+	index := NewIndex()
+	if err := index.CollectDirectory(directory, includeRaw); err != nil {
+		return err
+	}
+
+	workspace.Roots[filepath.Clean(directory)] = index
+	return nil
+}
+
+// Exclude adds gitignore-style patterns skipped by Discover, they take
+// precedence over the .terraformindexignore file of the discovered directory
+func (workspace *Workspace) Exclude(patterns ...string) {
+	workspace.excludes = append(workspace.excludes, patterns...)
+}
+
+// FollowSymlinks makes Discover walk symbolic links to directories
+func (workspace *Workspace) FollowSymlinks(follow bool) {
+	workspace.followSymlinks = follow
+}
+
+// Cache makes Discover collect the files through cache, so only files it
+// has no result for are parsed
+func (workspace *Workspace) Cache(cache *FileCache) {
+	workspace.cache = cache
+}
+
+// Discover adds every root module below directory. Every directory holding
+// configuration files is a candidate, except those within .terraform, those
+// ignored by the exclude patterns and those called as a local module by
+// another candidate
+func (workspace *Workspace) Discover(directory string, includeRaw bool) error {
+	ignore := NewIgnoreList()
+	if err := ignore.LoadIgnoreFile(directory); err != nil {
+		return err
+	}
+	for _, pattern := range workspace.excludes {
+		ignore.Add(pattern)
+	}
+
+	candidates := []string{}
+	files := map[string][]string{}
+	err := walk(directory, workspace.followSymlinks, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".terraform" {
+			return filepath.SkipDir
+		}
+
+		relative, err := filepath.Rel(directory, path)
+		if err != nil || relative == "." {
+			return err
+		}
+		if ignore.Match(relative, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if parent := filepath.Dir(path); !info.IsDir() && isConfigFile(path) {
+			if _, seen := files[parent]; !seen {
+				candidates = append(candidates, parent)
+			}
+			files[parent] = append(files[parent], path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, candidate := range candidates {
+		index := NewIndex()
+		if err := index.collectFiles(files[candidate], includeRaw, workspace.cache); err != nil {
+			return err
+		}
+		workspace.Roots[filepath.Clean(candidate)] = index
+	}
+
+	called := []string{}
+	for _, root := range workspace.Directories() {
+		for _, module := range workspace.Roots[root].Modules {
+			if module.SourceKind == MODULE_SOURCE_LOCAL {
+				called = append(called, filepath.Join(root, module.Source))
+			}
+		}
+	}
+	for _, directory := range called {
+		delete(workspace.Roots, directory)
+	}
+
+	return nil
+}
+
+// Directories returns the root module directories in sorted order
+func (workspace *Workspace) Directories() []string {
+	directories := make([]string, 0, len(workspace.Roots))
+	for directory := range workspace.Roots {
+		directories = append(directories, directory)
+	}
+	sort.Strings(directories)
+	return directories
+}
+
+func (workspace *Workspace) FollowModules(includeRaw bool) {
+	for _, directory := range workspace.Directories() {
+		workspace.Roots[directory].FollowModules(includeRaw)
+	}
+}
+
+func (workspace *Workspace) Resolve() {
+	for _, directory := range workspace.Directories() {
+		workspace.Roots[directory].Resolve()
+	}
+}
+
+func (workspace *Workspace) Analyze(options AnalysisOptions) {
+	for _, directory := range workspace.Directories() {
+		workspace.Roots[directory].Analyze(options)
+	}
+}
+
+// FindDeclarations returns the declarations of address in every root which
+// declares it
+func (workspace *Workspace) FindDeclarations(address string) []RootLocation {
+	found := []RootLocation{}
+	for _, directory := range workspace.Directories() {
+		if location, ok := workspace.Roots[directory].Declarations()[address]; ok {
+			found = append(found, RootLocation{Root: directory, Location: location})
+		}
+	}
+
+	return found
+}
+
+// ReferencesToAddress returns the references to address in every root
+func (workspace *Workspace) ReferencesToAddress(address string) []RootLocation {
+	found := []RootLocation{}
+	for _, directory := range workspace.Directories() {
+		for _, location := range workspace.Roots[directory].ReferencesToAddress(address) {
+			found = append(found, RootLocation{Root: directory, Location: location})
+		}
+	}
+
+	return found
+}
+
+// Diagnostics returns the diagnostics of every root
+func (workspace *Workspace) Diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, directory := range workspace.Directories() {
+		diagnostics = append(diagnostics, workspace.Roots[directory].Diagnostics...)
+	}
+
+	return diagnostics
+}
@@ -0,0 +1,610 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+	"github.com/mauve/terraform-index/index"
+)
+
+const (
+	LSP_PARSE_ERROR      = -32700
+	LSP_METHOD_NOT_FOUND = -32601
+	LSP_INVALID_PARAMS   = -32602
+)
+
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+// lspResponse always carries a result, null included, as the protocol
+// requires for successful requests
+type lspResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+}
+
+type lspErrorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   lspError         `json:"error"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspDocumentSymbol struct {
+	Name           string              `json:"name"`
+	Kind           int                 `json:"kind"`
+	Range          lspRange            `json:"range"`
+	SelectionRange lspRange            `json:"selectionRange"`
+	Children       []lspDocumentSymbol `json:"children"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspCompletionItem struct {
+	Label    string      `json:"label"`
+	Kind     int         `json:"kind"`
+	Detail   string      `json:"detail,omitempty"`
+	TextEdit lspTextEdit `json:"textEdit"`
+}
+
+type lspTextDocument struct {
+	URI     string `json:"uri"`
+	Text    string `json:"text"`
+	Version int    `json:"version"`
+}
+
+type lspTextDocumentParams struct {
+	TextDocument   lspTextDocument `json:"textDocument"`
+	Position       lspPosition     `json:"position"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+	NewName string `json:"newName"`
+	Context struct {
+		IncludeDeclaration bool `json:"includeDeclaration"`
+	} `json:"context"`
+}
+
+// LanguageServer answers Language Server Protocol requests from the index of
+// the module directory of each document. Open documents are indexed from
+// their unsaved contents and every change reindexes their directory
+type LanguageServer struct {
+	*buffers
+	reader   *bufio.Reader
+	writer   io.Writer
+	shutdown bool
+}
+
+func NewLanguageServer(reader io.Reader, writer io.Writer, options index.AnalysisOptions) *LanguageServer {
+	return &LanguageServer{
+		buffers: newBuffers(options),
+		reader:  bufio.NewReader(reader),
+		writer:  writer,
+	}
+}
+
+// Serve handles messages until the client sends exit or closes the stream
+func (server *LanguageServer) Serve() error {
+	for {
+		body, err := server.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		message := lspMessage{}
+		if err := json.Unmarshal(body, &message); err != nil {
+			server.replyError(nil, LSP_PARSE_ERROR, err.Error())
+			continue
+		}
+		if message.Method == "exit" {
+			if !server.shutdown {
+				return fmt.Errorf("exit before shutdown")
+			}
+			return nil
+		}
+
+		server.handle(message)
+	}
+}
+
+func (server *LanguageServer) read() ([]byte, error) {
+	length := -1
+	for {
+		line, err := server.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(strings.ToLower(line), "content-length:") {
+			length, err = strconv.Atoi(strings.TrimSpace(line[len("content-length:"):]))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+
+	body := make([]byte, length)
+	_, err := io.ReadFull(server.reader, body)
+	return body, err
+}
+
+func (server *LanguageServer) write(value interface{}) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(server.writer, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (server *LanguageServer) reply(id *json.RawMessage, result interface{}) {
+	server.write(lspResponse{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (server *LanguageServer) replyError(id *json.RawMessage, code int, message string) {
+	server.write(lspErrorResponse{JSONRPC: "2.0", ID: id, Error: lspError{Code: code, Message: message}})
+}
+
+func (server *LanguageServer) handle(message lspMessage) {
+	params := lspTextDocumentParams{}
+	if len(message.Params) > 0 {
+		if err := json.Unmarshal(message.Params, &params); err != nil && message.ID != nil {
+			server.replyError(message.ID, LSP_INVALID_PARAMS, err.Error())
+			return
+		}
+	}
+
+	switch message.Method {
+	case "initialize":
+		server.reply(message.ID, map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":       1,
+				"definitionProvider":     true,
+				"referencesProvider":     true,
+				"documentSymbolProvider": true,
+				"hoverProvider":          true,
+				"renameProvider":         true,
+				"foldingRangeProvider":   true,
+				"documentLinkProvider":   map[string]bool{"resolveProvider": false},
+				"semanticTokensProvider": map[string]interface{}{
+					"legend": map[string]interface{}{
+						"tokenTypes":     lspTokenTypes,
+						"tokenModifiers": []string{},
+					},
+					"full": true,
+				},
+				"completionProvider": map[string]interface{}{
+					"triggerCharacters": []string{"."},
+				},
+			},
+			"serverInfo": map[string]string{
+				"name":    BINARY,
+				"version": index.INDEX_VERSION,
+			},
+		})
+	case "initialized":
+	case "shutdown":
+		server.shutdown = true
+		server.reply(message.ID, nil)
+	case "textDocument/didOpen":
+		server.update(params.TextDocument.URI, []byte(params.TextDocument.Text))
+	case "textDocument/didChange":
+		if len(params.ContentChanges) > 0 {
+			text := params.ContentChanges[len(params.ContentChanges)-1].Text
+			server.update(params.TextDocument.URI, []byte(text))
+		}
+	case "textDocument/didSave":
+	case "textDocument/didClose":
+		server.update(params.TextDocument.URI, nil)
+	case "textDocument/definition":
+		server.reply(message.ID, server.definition(params))
+	case "textDocument/references":
+		server.reply(message.ID, server.references(params))
+	case "textDocument/documentSymbol":
+		server.reply(message.ID, server.documentSymbols(params))
+	case "textDocument/rename":
+		server.rename(message.ID, params)
+	case "textDocument/semanticTokens/full":
+		server.reply(message.ID, server.semanticTokens(params))
+	case "textDocument/foldingRange":
+		server.reply(message.ID, server.foldingRanges(params))
+	case "textDocument/documentLink":
+		server.reply(message.ID, server.documentLinks(params))
+	case "textDocument/hover":
+		server.reply(message.ID, server.hover(params))
+	case "textDocument/completion":
+		server.reply(message.ID, server.completions(params))
+	default:
+		if message.ID != nil {
+			server.replyError(message.ID, LSP_METHOD_NOT_FOUND, "Unsupported method '"+message.Method+"'")
+		}
+	}
+}
+
+func uriToPath(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return uri
+	}
+
+	return filepath.FromSlash(parsed.Path)
+}
+
+func pathToURI(path string) string {
+	return index.FileURI(path)
+}
+
+// toLSPPosition converts the one-based positions of the index to the
+// zero-based positions of the protocol
+func toLSPPosition(pos hcltoken.Pos) lspPosition {
+	position := lspPosition{Line: pos.Line - 1, Character: pos.Column - 1}
+	if position.Line < 0 {
+		position.Line = 0
+	}
+	if position.Character < 0 {
+		position.Character = 0
+	}
+	return position
+}
+
+func toLSPLocation(start hcltoken.Pos, end hcltoken.Pos) lspLocation {
+	if end.Line == 0 {
+		end = start
+	}
+
+	return lspLocation{
+		URI:   pathToURI(start.Filename),
+		Range: lspRange{Start: toLSPPosition(start), End: toLSPPosition(end)},
+	}
+}
+
+func nameLocation(pos hcltoken.Pos, name string) lspLocation {
+	end := pos
+	end.Column += len(name)
+	return toLSPLocation(pos, end)
+}
+
+// update replaces the unsaved contents of a document, nil contents close it,
+// and reindexes its directory
+func (server *LanguageServer) update(uri string, contents []byte) {
+	server.publishDiagnostics(server.set(uriToPath(uri), contents))
+}
+
+func lspSeverity(severity string) int {
+	switch severity {
+	case index.SEVERITY_ERROR:
+		return 1
+	case index.SEVERITY_WARNING:
+		return 2
+	}
+
+	return 3
+}
+
+// publishDiagnostics sends the diagnostics of every file in directory, files
+// without diagnostics are sent an empty list to clear earlier ones
+func (server *LanguageServer) publishDiagnostics(directory string) {
+	published := server.files[directory]
+	index := server.directoryIndex(directory)
+
+	diagnostics := map[string][]lspDiagnostic{}
+	for _, path := range append(published, server.files[directory]...) {
+		diagnostics[path] = []lspDiagnostic{}
+	}
+	for _, diagnostic := range index.Diagnostics {
+		path := diagnostic.Location.Filename
+		if path == "" {
+			continue
+		}
+
+		diagnostics[path] = append(diagnostics[path], lspDiagnostic{
+			Range:    toLSPLocation(diagnostic.Location, diagnostic.EndLocation).Range,
+			Severity: lspSeverity(diagnostic.Severity),
+			Code:     diagnostic.Code,
+			Source:   BINARY,
+			Message:  diagnostic.Message,
+		})
+	}
+
+	paths := []string{}
+	for path := range diagnostics {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		server.write(lspNotification{
+			JSONRPC: "2.0",
+			Method:  "textDocument/publishDiagnostics",
+			Params: map[string]interface{}{
+				"uri":         pathToURI(path),
+				"diagnostics": diagnostics[path],
+			},
+		})
+	}
+}
+
+func (server *LanguageServer) definition(params lspTextDocumentParams) interface{} {
+	path := uriToPath(params.TextDocument.URI)
+	index := server.indexFor(path)
+	declaration, ok := index.DeclarationAt(path, params.Position.Line+1, params.Position.Character+1)
+	if !ok {
+		return nil
+	}
+
+	return toLSPLocation(declaration.Position(), declaration.Position())
+}
+
+func (server *LanguageServer) references(params lspTextDocumentParams) interface{} {
+	path := uriToPath(params.TextDocument.URI)
+	index := server.indexFor(path)
+	address, ok := index.AddressAt(path, params.Position.Line+1, params.Position.Character+1)
+	if !ok {
+		return nil
+	}
+
+	locations := []lspLocation{}
+	if declaration, ok := index.Declarations()[address]; ok && params.Context.IncludeDeclaration {
+		locations = append(locations, toLSPLocation(declaration, declaration))
+	}
+	for _, location := range index.ReferencesToAddress(address) {
+		locations = append(locations, nameLocation(location, address))
+	}
+
+	return locations
+}
+
+// lspSymbolKinds numbers the symbol kinds as the protocol does
+var lspSymbolKinds = map[string]int{
+	index.SYMBOL_VARIABLE: 13,
+	index.SYMBOL_LOCAL:    14,
+	index.SYMBOL_RESOURCE: 5,
+	index.SYMBOL_DATA:     23,
+	index.SYMBOL_MODULE:   2,
+	index.SYMBOL_OUTPUT:   7,
+	index.SYMBOL_BLOCK:    19,
+}
+
+func toDocumentSymbols(symbols []index.Symbol) []lspDocumentSymbol {
+	documentSymbols := []lspDocumentSymbol{}
+	for _, symbol := range symbols {
+		documentSymbols = append(documentSymbols, lspDocumentSymbol{
+			Name:           symbol.Name,
+			Kind:           lspSymbolKinds[symbol.Kind],
+			Range:          toLSPLocation(symbol.BlockLocation, symbol.EndLocation).Range,
+			SelectionRange: toLSPLocation(symbol.Location, symbol.Location).Range,
+			Children:       toDocumentSymbols(symbol.Children),
+		})
+	}
+
+	return documentSymbols
+}
+
+func (server *LanguageServer) documentSymbols(params lspTextDocumentParams) interface{} {
+	path := uriToPath(params.TextDocument.URI)
+	return toDocumentSymbols(server.indexFor(path).SymbolsInFile(path))
+}
+
+// lspCompletionKinds numbers the completion item kinds as the protocol does
+var lspCompletionKinds = map[string]int{
+	index.SYMBOL_VARIABLE: 6,
+	index.SYMBOL_LOCAL:    21,
+	index.SYMBOL_RESOURCE: 7,
+	index.SYMBOL_DATA:     22,
+	index.SYMBOL_MODULE:   9,
+	index.SYMBOL_META:     14,
+}
+
+// wordBefore returns the address characters preceding a position
+func wordBefore(contents []byte, position lspPosition) string {
+	lines := strings.Split(string(contents), "\n")
+	if position.Line >= len(lines) {
+		return ""
+	}
+
+	line := lines[position.Line]
+	end := position.Character
+	if end > len(line) {
+		end = len(line)
+	}
+	start := end
+	for start > 0 && strings.ContainsRune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-.", rune(line[start-1])) {
+		start--
+	}
+
+	return line[start:end]
+}
+
+func (server *LanguageServer) completions(params lspTextDocumentParams) interface{} {
+	path := uriToPath(params.TextDocument.URI)
+	prefix := wordBefore(server.contents(path), params.Position)
+	start := params.Position
+	start.Character -= len(prefix)
+
+	items := []lspCompletionItem{}
+	for _, completion := range server.indexFor(path).CompletionsAt(path, params.Position.Line+1, params.Position.Character+1, prefix) {
+		items = append(items, lspCompletionItem{
+			Label:  completion.Label,
+			Kind:   lspCompletionKinds[completion.Kind],
+			Detail: completion.Detail,
+			TextEdit: lspTextEdit{
+				Range:   lspRange{Start: start, End: params.Position},
+				NewText: completion.Label,
+			},
+		})
+	}
+
+	return items
+}
+
+func (server *LanguageServer) hover(params lspTextDocumentParams) interface{} {
+	path := uriToPath(params.TextDocument.URI)
+	hover, ok := server.indexFor(path).HoverAt(path, params.Position.Line+1, params.Position.Character+1)
+	if !ok {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"contents": map[string]string{
+			"kind":  "markdown",
+			"value": hover.Markdown(),
+		},
+	}
+}
+
+func (server *LanguageServer) rename(id *json.RawMessage, params lspTextDocumentParams) {
+	path := uriToPath(params.TextDocument.URI)
+	index := server.indexFor(path)
+	address, ok := index.AddressAt(path, params.Position.Line+1, params.Position.Character+1)
+	if !ok {
+		server.reply(id, nil)
+		return
+	}
+
+	edits, err := index.Rename(address, params.NewName)
+	if err != nil {
+		server.replyError(id, LSP_INVALID_PARAMS, err.Error())
+		return
+	}
+
+	changes := map[string][]lspTextEdit{}
+	for _, edit := range edits {
+		uri := pathToURI(edit.Location.Filename)
+		changes[uri] = append(changes[uri], lspTextEdit{
+			Range:   toLSPLocation(edit.Location, edit.EndLocation).Range,
+			NewText: edit.NewText,
+		})
+	}
+	server.reply(id, map[string]interface{}{"changes": changes})
+}
+
+// lspTokenTypes is the legend of the semantic tokens, in the order of
+// lspTokenKinds
+var lspTokenTypes = []string{"keyword", "type", "property", "variable", "function", "string", "number"}
+
+var lspTokenKinds = map[string]int{
+	index.TOKEN_BLOCK_TYPE:  0,
+	index.TOKEN_BLOCK_LABEL: 1,
+	index.TOKEN_ATTRIBUTE:   2,
+	index.TOKEN_REFERENCE:   3,
+	index.TOKEN_FUNCTION:    4,
+	index.TOKEN_STRING:      5,
+	index.TOKEN_NUMBER:      6,
+}
+
+// semanticTokens encodes the tokens of a document relative to each other as
+// the protocol requires, tokens spanning several lines like heredocs are left
+// out since not every client supports them
+func (server *LanguageServer) semanticTokens(params lspTextDocumentParams) interface{} {
+	path := uriToPath(params.TextDocument.URI)
+
+	data := []int{}
+	previous := lspPosition{}
+	for _, token := range server.indexFor(path).SemanticTokens(path) {
+		if token.Location.Line != token.EndLocation.Line || token.EndLocation.Column <= token.Location.Column {
+			continue
+		}
+
+		start := toLSPPosition(token.Location)
+		character := start.Character
+		if start.Line == previous.Line {
+			character -= previous.Character
+		}
+		data = append(data, start.Line-previous.Line, character,
+			token.EndLocation.Column-token.Location.Column, lspTokenKinds[token.Kind], 0)
+		previous = start
+	}
+
+	return map[string]interface{}{"data": data}
+}
+
+// foldingRanges ends every range on the line before its closing brace or
+// anchor, which stays visible when the range is folded
+func (server *LanguageServer) foldingRanges(params lspTextDocumentParams) interface{} {
+	path := uriToPath(params.TextDocument.URI)
+
+	ranges := []map[string]int{}
+	for _, folding := range server.indexFor(path).FoldingRanges(path) {
+		if folding.EndLine-1 > folding.StartLine {
+			ranges = append(ranges, map[string]int{
+				"startLine": folding.StartLine - 1,
+				"endLine":   folding.EndLine - 2,
+			})
+		}
+	}
+
+	return ranges
+}
+
+func (server *LanguageServer) documentLinks(params lspTextDocumentParams) interface{} {
+	path := uriToPath(params.TextDocument.URI)
+
+	links := []map[string]interface{}{}
+	for _, link := range server.indexFor(path).DocumentLinks(path) {
+		links = append(links, map[string]interface{}{
+			"range":   toLSPLocation(link.Location, link.EndLocation).Range,
+			"target":  link.Target,
+			"tooltip": link.Text,
+		})
+	}
+
+	return links
+}
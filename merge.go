@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mauve/terraform-index/index"
+)
+
+// merge runs the merge subcommand, which combines indexes printed by the
+// index command into one
+func merge(args []string) {
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	format := flags.String("format", FORMAT_JSON, "output format: "+FORMAT_JSON+" or "+FORMAT_BINARY)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s merge [-format json|binary] <indexes>\n\n", BINARY)
+		fmt.Fprintf(os.Stderr, "Combines indexes printed as JSON or with -format binary, like those of\n")
+		fmt.Fprintf(os.Stderr, "directories indexed by parallel jobs, into one index. Files held by\n")
+		fmt.Fprintf(os.Stderr, "several indexes are taken from the last one. References are resolved\n")
+		fmt.Fprintf(os.Stderr, "again while the diagnostics of the indexes are kept as they are\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	if *format != FORMAT_JSON && *format != FORMAT_BINARY {
+		fmt.Fprintf(os.Stderr, "ERROR: Unknown format '%s'\n", *format)
+		os.Exit(1)
+	}
+
+	merged := index.NewIndex()
+	for _, path := range flags.Args() {
+		loaded, err := index.ReadIndexFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Cannot read index '%s': %s\n", path, err)
+			os.Exit(2)
+		}
+		merged.Merge(loaded)
+	}
+	merged.Resolve()
+
+	if *format == FORMAT_BINARY {
+		writeFormat(*format, nil, merged)
+		return
+	}
+	writeJSON(merged)
+}
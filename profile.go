@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/pprof"
+)
+
+// profiles are the paths the -cpuprofile and -memprofile flags write to
+type profiles struct {
+	cpu    *string
+	memory *string
+}
+
+func addProfileFlags(flags *flag.FlagSet) profiles {
+	return profiles{
+		cpu:    flags.String("cpuprofile", "", "write a CPU profile to this file"),
+		memory: flags.String("memprofile", "", "write a heap profile to this file when done"),
+	}
+}
+
+// start starts the CPU profile, the returned function stops it and writes
+// the heap profile. Either is only written when the command finishes
+// without an error
+func (profiles profiles) start() func() {
+	var cpu *os.File
+	if *profiles.cpu != "" {
+		var err error
+		if cpu, err = os.Create(*profiles.cpu); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Cannot create CPU profile: %s\n", err)
+			os.Exit(2)
+		}
+		if err := pprof.StartCPUProfile(cpu); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Cannot start CPU profile: %s\n", err)
+			os.Exit(2)
+		}
+	}
+
+	return func() {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			cpu.Close()
+		}
+
+		if *profiles.memory != "" {
+			memory, err := os.Create(*profiles.memory)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: Cannot create heap profile: %s\n", err)
+				return
+			}
+			defer memory.Close()
+
+			// collect first so the profile is up to date
+			runtime.GC()
+			if err := pprof.WriteHeapProfile(memory); err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: Cannot write heap profile: %s\n", err)
+			}
+		}
+	}
+}
+
+// stopOnInterrupt writes the profiles and exits when the servers, which
+// otherwise never finish, are interrupted
+func stopOnInterrupt(stop func()) {
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		<-interrupts
+		stop()
+		os.Exit(130)
+	}()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	hcltoken "github.com/hashicorp/hcl/hcl/token"
+	"github.com/mauve/terraform-index/index"
+)
+
+// query sends a request to the daemon listening on a Unix socket and prints
+// the result as JSON, without a socket it indexes the paths it is given and
+// answers the query itself
+func query(args []string) {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	socket := flags.String("socket", "", "the Unix socket of a daemon started with serve -socket")
+	asJSON := flags.Bool("json", false, "without -socket, print the result as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s query -socket <path> <method> [<param>=<value>...]\n", BINARY)
+		fmt.Fprintf(os.Stderr, "       %s query [-json] def <address> <paths>\n\n", BINARY)
+		fmt.Fprintf(os.Stderr, "Methods are definition, references, symbols and diagnostics, params\n")
+		fmt.Fprintf(os.Stderr, "are path, line, column and name, e.g. references name=var.region.\n")
+		fmt.Fprintf(os.Stderr, "def prints where an address like var.region is declared\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *socket == "" && flags.Arg(0) == "def" && flags.NArg() >= 3 {
+		queryDefinitions(flags.Arg(1), flags.Args()[2:], *asJSON)
+		return
+	}
+	if *socket == "" || len(flags.Args()) == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	params := map[string]interface{}{}
+	for _, arg := range flags.Args()[1:] {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			fmt.Fprintf(os.Stderr, "ERROR: Expected <param>=<value> instead of '%s'\n", arg)
+			os.Exit(1)
+		}
+		if number, err := strconv.Atoi(parts[1]); err == nil && (parts[0] == "line" || parts[0] == "column") {
+			params[parts[0]] = number
+		} else {
+			params[parts[0]] = parts[1]
+		}
+	}
+
+	connection, err := net.Dial("unix", *socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot connect to '%s': %s\n", *socket, err)
+		os.Exit(2)
+	}
+	defer connection.Close()
+
+	request := map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": flags.Arg(0), "params": params}
+	if err := writeRPC(connection, request); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot send query: %s\n", err)
+		os.Exit(2)
+	}
+
+	response := struct {
+		Result json.RawMessage `json:"result"`
+		Error  *lspError       `json:"error"`
+	}{}
+	line, err := bufio.NewReader(connection).ReadBytes('\n')
+	if err == nil {
+		err = json.Unmarshal(line, &response)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot read response: %s\n", err)
+		os.Exit(2)
+	}
+	if response.Error != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", response.Error.Message)
+		os.Exit(3)
+	}
+
+	writeJSON(response.Result)
+}
+
+// queryDefinitions prints every declaration of address in the files matching
+// patterns, one per line as path:line:column followed by the kind, address
+// and type, or as JSON. It fails when address is not declared
+func queryDefinitions(address string, patterns []string, asJSON bool) {
+	loaded, err := loadIndex(patterns, index.AnalysisOptions{}, "", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot index paths: %s\n", err)
+		os.Exit(2)
+	}
+
+	definitions := loaded.Definitions(address)
+	if asJSON {
+		writeJSON(definitions)
+	} else {
+		for _, definition := range definitions {
+			line := fmt.Sprintf("%s: %s %s", formatPosition(definition.Location), definition.Kind, definition.Address)
+			if definition.Type != "" {
+				line += " " + definition.Type
+			}
+			fmt.Println(line)
+		}
+	}
+
+	if len(definitions) == 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: '%s' is not declared\n", address)
+		os.Exit(1)
+	}
+}
+
+// formatPosition formats a position as path:line:column like compilers do,
+// editors and grep -n style tools understand it
+func formatPosition(position hcltoken.Pos) string {
+	return fmt.Sprintf("%s:%d:%d", position.Filename, position.Line, position.Column)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mauve/terraform-index/index"
+)
+
+// refs runs the refs subcommand, which lists where an address is referenced
+func refs(args []string) {
+	flags := flag.NewFlagSet("refs", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the locations as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s refs [-json] <address> <paths>\n\n", BINARY)
+		fmt.Fprintf(os.Stderr, "Prints every reference to an address like aws_security_group.web as\n")
+		fmt.Fprintf(os.Stderr, "path:line:column: followed by the referring line, which grep -n style\n")
+		fmt.Fprintf(os.Stderr, "tools and the quickfix lists of editors read. Fails when there is none\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	loaded, err := loadIndex(flags.Args()[1:], index.AnalysisOptions{}, "", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot index paths: %s\n", err)
+		os.Exit(2)
+	}
+
+	locations := loaded.ReferencesToAddress(flags.Arg(0))
+	sort.SliceStable(locations, func(i, j int) bool {
+		if locations[i].Filename != locations[j].Filename {
+			return locations[i].Filename < locations[j].Filename
+		}
+		return locations[i].Offset < locations[j].Offset
+	})
+
+	if *asJSON {
+		writeJSON(locations)
+	} else {
+		lines := map[string][]string{}
+		for _, location := range locations {
+			if _, ok := lines[location.Filename]; !ok {
+				contents, _ := Contents(location.Filename, "")
+				lines[location.Filename] = strings.Split(string(contents), "\n")
+			}
+
+			text := ""
+			if file := lines[location.Filename]; location.Line >= 1 && location.Line <= len(file) {
+				text = strings.TrimSpace(file[location.Line-1])
+			}
+			fmt.Printf("%s: %s\n", formatPosition(location), text)
+		}
+	}
+
+	if len(locations) == 0 {
+		os.Exit(1)
+	}
+}
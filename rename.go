@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/mauve/terraform-index/index"
+)
+
+// ApplyEdits applies edits to the files they refer to, the edits of a file
+// are applied from its end so earlier offsets stay valid
+func ApplyEdits(edits []index.TextEdit) error {
+	files := map[string][]index.TextEdit{}
+	for _, edit := range edits {
+		files[edit.Location.Filename] = append(files[edit.Location.Filename], edit)
+	}
+
+	for path, fileEdits := range files {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		sort.SliceStable(fileEdits, func(i, j int) bool {
+			return fileEdits[i].Location.Offset > fileEdits[j].Location.Offset
+		})
+		for _, edit := range fileEdits {
+			start, end := edit.Location.Offset, edit.EndLocation.Offset
+			if start < 0 || end > len(contents) || start > end {
+				return fmt.Errorf("edit out of range in '%s'", path)
+			}
+
+			edited := append([]byte{}, contents[:start]...)
+			edited = append(edited, edit.NewText...)
+			contents = append(edited, contents[end:]...)
+		}
+
+		if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rename runs the rename subcommand, which prints the edits renaming a
+// declaration or applies them with -write
+func rename(args []string) {
+	flags := flag.NewFlagSet("rename", flag.ExitOnError)
+	write := flags.Bool("write", false, "apply the edits to the files instead of printing them")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s rename [-write] <address> <new-name> <paths>\n\n", BINARY)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 3 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	paths, err := index.ExpandGlobs(flags.Args()[2:], false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot expand paths: %s\n", err)
+		os.Exit(2)
+	}
+
+	index := index.NewIndex()
+	for _, path := range paths {
+		source, err := Contents(path, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Cannot open path '%s': %s\n", path, err)
+			os.Exit(2)
+		}
+
+		if err := index.CollectString(source, path, false); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Could not parse '%s': %s\n", path, err)
+		}
+	}
+	index.Resolve()
+
+	edits, err := index.Rename(flags.Arg(0), flags.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot rename '%s': %s\n", flags.Arg(0), err)
+		os.Exit(3)
+	}
+
+	if !*write {
+		writeJSON(edits)
+		return
+	}
+	if err := ApplyEdits(edits); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot apply edits: %s\n", err)
+		os.Exit(3)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/mauve/terraform-index/index"
+)
+
+// rpcParams are the parameters of every RPCServer method, positions are
+// one-based like in the JSON output
+type rpcParams struct {
+	Path     string  `json:"path"`
+	Contents *string `json:"contents"`
+	Line     int     `json:"line"`
+	Column   int     `json:"column"`
+	Name     string  `json:"name"`
+}
+
+type rpcIndexResult struct {
+	Path        string             `json:"path"`
+	Symbols     []index.Symbol     `json:"symbols"`
+	Diagnostics []index.Diagnostic `json:"diagnostics"`
+}
+
+// RPCServer answers JSON-RPC 2.0 requests, one per line or a batch array per
+// line, over a stream. Like the language server it indexes the directory of
+// each path with the unsaved contents sent by the index method
+type RPCServer struct {
+	*buffers
+	reader *bufio.Reader
+	writer io.Writer
+}
+
+func NewRPCServer(reader io.Reader, writer io.Writer, options index.AnalysisOptions) *RPCServer {
+	return &RPCServer{
+		buffers: newBuffers(options),
+		reader:  bufio.NewReader(reader),
+		writer:  writer,
+	}
+}
+
+// Serve handles requests until the stream is closed
+func (server *RPCServer) Serve() error {
+	for {
+		line, err := server.reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			response := rpcReply(line, func(message lspMessage) interface{} {
+				return rpcHandle(message, server.run)
+			})
+			if response != nil {
+				writeRPC(server.writer, response)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// rpcReply returns the response to a line holding a request or a batch of
+// requests, nil when nothing is to be written
+func rpcReply(line []byte, handle func(message lspMessage) interface{}) interface{} {
+	if line[0] != '[' {
+		message := lspMessage{}
+		if err := json.Unmarshal(line, &message); err != nil {
+			return rpcError(nil, LSP_PARSE_ERROR, err.Error())
+		}
+		return handle(message)
+	}
+
+	messages := []lspMessage{}
+	if err := json.Unmarshal(line, &messages); err != nil {
+		return rpcError(nil, LSP_PARSE_ERROR, err.Error())
+	}
+	responses := []interface{}{}
+	for _, message := range messages {
+		if response := handle(message); response != nil {
+			responses = append(responses, response)
+		}
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	return responses
+}
+
+func writeRPC(writer io.Writer, value interface{}) error {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(append(body, '\n'))
+	return err
+}
+
+func rpcError(id *json.RawMessage, code int, message string) lspErrorResponse {
+	return lspErrorResponse{JSONRPC: "2.0", ID: id, Error: lspError{Code: code, Message: message}}
+}
+
+// rpcHandle runs a request with run, which returns false for unknown
+// methods, and returns the response, nil for notifications
+func rpcHandle(message lspMessage, run func(method string, params rpcParams) (interface{}, bool)) interface{} {
+	params := rpcParams{}
+	if len(message.Params) > 0 {
+		if err := json.Unmarshal(message.Params, &params); err != nil {
+			return rpcError(message.ID, LSP_INVALID_PARAMS, err.Error())
+		}
+	}
+
+	result, ok := run(message.Method, params)
+	if message.ID == nil {
+		return nil
+	}
+	if !ok {
+		return rpcError(message.ID, LSP_METHOD_NOT_FOUND, "Unsupported method '"+message.Method+"'")
+	}
+	return lspResponse{JSONRPC: "2.0", ID: message.ID, Result: result}
+}
+
+// rpcQuery answers the query methods shared by the stdio and socket servers,
+// without a path symbols and diagnostics cover every file of the index
+func rpcQuery(indexed *index.Index, method string, params rpcParams) (interface{}, bool) {
+	switch method {
+	case "definition":
+		hover, ok := indexed.HoverFor(params.Name)
+		if params.Name == "" {
+			hover, ok = indexed.HoverAt(params.Path, params.Line, params.Column)
+		}
+		if !ok {
+			return nil, true
+		}
+		return hover, true
+
+	case "references":
+		address, ok := params.Name, params.Name != ""
+		if !ok {
+			address, ok = indexed.AddressAt(params.Path, params.Line, params.Column)
+		}
+		if !ok {
+			return []interface{}{}, true
+		}
+		return indexed.ReferencesToAddress(address), true
+
+	case "symbols":
+		files := indexed.Files()
+		if params.Path != "" {
+			files = []string{params.Path}
+		}
+		symbols := []index.Symbol{}
+		for _, file := range files {
+			symbols = append(symbols, indexed.SymbolsInFile(file)...)
+		}
+		return symbols, true
+
+	case "diagnostics":
+		diagnostics := []index.Diagnostic{}
+		for _, diagnostic := range indexed.Diagnostics {
+			if params.Path == "" || diagnostic.Location.Filename == params.Path {
+				diagnostics = append(diagnostics, diagnostic)
+			}
+		}
+		return diagnostics, true
+	}
+
+	return nil, false
+}
+
+func (server *RPCServer) run(method string, params rpcParams) (interface{}, bool) {
+	if method == "index" {
+		return server.index(params), true
+	}
+
+	return rpcQuery(server.indexFor(params.Path), method, params)
+}
+
+// index replaces the contents of a file, or reverts it to the saved
+// contents when none are given, and returns the symbols of the file along
+// with the diagnostics of its directory
+func (server *RPCServer) index(params rpcParams) rpcIndexResult {
+	var contents []byte
+	if params.Contents != nil {
+		contents = []byte(*params.Contents)
+	}
+	server.set(params.Path, contents)
+
+	index := server.indexFor(params.Path)
+	return rpcIndexResult{
+		Path:        params.Path,
+		Symbols:     index.SymbolsInFile(params.Path),
+		Diagnostics: index.Diagnostics,
+	}
+}
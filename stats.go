@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mauve/terraform-index/index"
+)
+
+// directoryPatterns returns the patterns matching the Terraform files of a
+// module directory
+func directoryPatterns(directory string) []string {
+	patterns := []string{}
+	for _, pattern := range []string{"*.tf", "*.tf.json", "*.tfvars", "*.tfvars.json"} {
+		patterns = append(patterns, filepath.Join(directory, pattern))
+	}
+	return patterns
+}
+
+// writeCounts prints counts below a heading, the largest first
+func writeCounts(heading string, counts map[string]int) {
+	names := []string{}
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	fmt.Printf("%s:\n", heading)
+	for _, name := range names {
+		fmt.Printf("  %-40s %d\n", name, counts[name])
+	}
+}
+
+// stats runs the stats subcommand, which prints how many declarations,
+// resources, diagnostics and references the files hold
+func stats(args []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the counts as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s stats [-json] [<paths>]\n\n", BINARY)
+		fmt.Fprintf(os.Stderr, "Counts the declarations by kind, the resources by type and provider,\n")
+		fmt.Fprintf(os.Stderr, "the files, parse errors, diagnostics and references of the paths, the\n")
+		fmt.Fprintf(os.Stderr, "Terraform files of the current directory by default\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		patterns = directoryPatterns(".")
+	}
+
+	loaded, err := loadIndex(patterns, index.AnalysisOptions{}, "", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot index paths: %s\n", err)
+		os.Exit(2)
+	}
+
+	counts := loaded.Stats()
+	if *asJSON {
+		writeJSON(counts)
+		return
+	}
+
+	fmt.Printf("%-42s %d\n", "files:", counts.Files)
+	writeCounts("declarations", counts.Declarations)
+	writeCounts("resources and data sources by provider", counts.Providers)
+	writeCounts("resources by type", counts.ResourceTypes)
+	fmt.Printf("%-42s %d\n", "parse errors:", counts.ParseErrors)
+	writeCounts("diagnostics", counts.Diagnostics)
+	fmt.Printf("%-42s %d\n", "references:", counts.References)
+	fmt.Printf("%-42s %d\n", "referenced names:", counts.ReferencedNames)
+	fmt.Printf("%-42s %d\n", "function calls:", counts.FunctionCalls)
+}
@@ -5,57 +5,699 @@ import (
 	"flag"
 	"io/ioutil"
 	"os"
+	"strings"
+	"text/template"
+	"time"
 
 	"fmt"
 
 	"github.com/mauve/terraform-index/index"
+	"github.com/mauve/terraform-index/index/store"
 )
 
 const (
 	BINARY = "terraform-index"
 )
 
-func Contents(path string) ([]byte, error) {
+const OUTPUT_SQLITE = "sqlite:"
+
+const (
+	FORMAT_JSON    = "json"
+	FORMAT_CTAGS   = "ctags"
+	FORMAT_ETAGS   = "etags"
+	FORMAT_LSIF    = "lsif"
+	FORMAT_SCIP    = "scip"
+	FORMAT_SARIF   = "sarif"
+	FORMAT_DOT     = "dot"
+	FORMAT_MERMAID = "mermaid"
+	FORMAT_CSV     = "csv"
+	FORMAT_TSV     = "tsv"
+	FORMAT_NDJSON  = "ndjson"
+	FORMAT_BINARY  = "binary"
+)
+
+var formats = []string{FORMAT_JSON, FORMAT_CTAGS, FORMAT_ETAGS, FORMAT_LSIF, FORMAT_SCIP, FORMAT_SARIF, FORMAT_DOT, FORMAT_MERMAID, FORMAT_CSV, FORMAT_TSV, FORMAT_NDJSON, FORMAT_BINARY}
+
+func isFormat(format string) bool {
+	for _, known := range formats {
+		if format == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+// commaList is a flag which may be repeated whose values are lists separated
+// by commas
+type commaList []string
+
+func (list *commaList) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *commaList) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*list = append(*list, item)
+		}
+	}
+	return nil
+}
+
+// Contents reads a file from disk, stdin or a git revision. Files larger than
+// index.MaxFileSize fail with an *index.FileTooLargeError, archives are read
+// whole and the files in them are checked instead
+func Contents(path string, revision string) ([]byte, error) {
 	if path == "-" {
-		return ioutil.ReadAll(os.Stdin)
+		return index.ReadLimited(os.Stdin, path)
+	}
+	if revision != "" {
+		return GitContents(revision, path)
+	}
+	if IsArchive(path) {
+		return ioutil.ReadFile(path)
+	}
+
+	return index.ReadFile(path)
+}
+
+// isTooLarge returns the error of a file larger than index.MaxFileSize
+func isTooLarge(err error) (*index.FileTooLargeError, bool) {
+	tooLarge, ok := err.(*index.FileTooLargeError)
+	return tooLarge, ok
+}
+
+// restoreIndex loads the index saved to cache, or starts a new one if there
+// is none or it cannot be used, and collects the paths changed since
+func restoreIndex(cache string, paths []string, includeRaw bool) (*index.Index, error) {
+	restored, err := index.LoadIndex(cache)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "WARNING: Ignoring cache '%s': %s\n", cache, err)
+		}
+		restored = index.NewIndex()
+	}
+
+	if _, err := restored.Refresh(paths, includeRaw); err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// saveIndex writes the index to cache, failing to do so only costs the next
+// run the time to parse everything again
+func saveIndex(cache string, saved *index.Index) {
+	if err := saved.Save(cache); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Cannot write cache '%s': %s\n", cache, err)
+	}
+}
+
+// saveFileCache writes the stamps of a file cache, failing to do so only
+// costs the next run the time to hash the files again
+func saveFileCache(cache *index.FileCache) {
+	if err := cache.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Cannot write hash cache: %s\n", err)
+	}
+}
+
+// loadIndex indexes the files matching patterns for the query servers,
+// files which cannot be parsed are reported and skipped. With a cache only
+// the files changed since it was saved are parsed, otherwise files are only
+// parsed when files has no result for their contents
+func loadIndex(patterns []string, options index.AnalysisOptions, cache string, files *index.FileCache) (*index.Index, error) {
+	ignore := index.NewIgnoreList()
+	if err := ignore.LoadIgnoreFile("."); err != nil {
+		return nil, err
+	}
+
+	paths, err := index.ExpandGlobs(patterns, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if cache != "" {
+		kept := []string{}
+		for _, path := range paths {
+			if !ignore.Match(path, false) {
+				kept = append(kept, path)
+			}
+		}
+
+		loaded, err := restoreIndex(cache, kept, false)
+		if err != nil {
+			return nil, err
+		}
+		loaded.Resolve()
+		loaded.Analyze(options)
+		saveIndex(cache, loaded)
+		return loaded, nil
+	}
+
+	loaded := index.NewIndex()
+	for _, path := range paths {
+		if ignore.Match(path, false) {
+			continue
+		}
+
+		if err := loaded.CollectPathCached(files, path, false); err != nil {
+			if _, ok := err.(*os.PathError); ok {
+				return nil, err
+			}
+			fmt.Fprintf(os.Stderr, "ERROR: Could not parse '%s': %s\n", path, err)
+		}
+	}
+	if files != nil {
+		saveFileCache(files)
+	}
+
+	loaded.Resolve()
+	loaded.Analyze(options)
+	return loaded, nil
+}
+
+// serve runs the servers of the serve subcommand
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	lsp := flags.Bool("lsp", false, "serve the Language Server Protocol over stdio")
+	stdio := flags.Bool("stdio", false, "serve JSON-RPC requests, one per line, over stdio")
+	grpcAddress := flags.String("grpc", "", "serve the gRPC query service on this address, like :7879")
+	httpAddress := flags.String("http", "", "serve the HTTP query service on this address, like :7878")
+	socket := flags.String("socket", "", "serve JSON-RPC queries on a Unix socket and reindex on changes")
+	poll := flags.Duration("poll", time.Second, "with -socket, how often to check the files for changes")
+	warnUnused := flags.Bool("warn-unused", false, "report unused declarations as warnings")
+	cache := flags.String("cache", "", "with -grpc, -http or -socket, restore the index from this file and save it back")
+	hashCache := flags.String("hash-cache", "", "with -grpc, -http or -socket, keep the index of every file in this directory keyed by a hash of its contents")
+	maxFileSize := flags.Int64("max-file-size", index.DEFAULT_MAX_FILE_SIZE, "files larger than this many bytes are reported instead of indexed, 0 for no limit")
+	profiles := addProfileFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s serve -lsp [options]\n", BINARY)
+		fmt.Fprintf(os.Stderr, "       %s serve -stdio [options]\n", BINARY)
+		fmt.Fprintf(os.Stderr, "       %s serve [-grpc <address>] [-http <address>] [options] <paths>\n", BINARY)
+		fmt.Fprintf(os.Stderr, "       %s serve -socket <path> [options] <paths>\n\n", BINARY)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	stop := profiles.start()
+	defer stop()
+	stopOnInterrupt(stop)
+	index.MaxFileSize = *maxFileSize
+	options := index.AnalysisOptions{WarnUnused: *warnUnused}
+	var files *index.FileCache
+	if *hashCache != "" {
+		files = index.NewFileCache(*hashCache)
+	}
+
+	if *grpcAddress != "" || *httpAddress != "" {
+		if len(flags.Args()) == 0 {
+			flags.Usage()
+			os.Exit(1)
+		}
+
+		loaded, err := loadIndex(flags.Args(), options, *cache, files)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Cannot index paths: %s\n", err)
+			os.Exit(2)
+		}
+
+		// both services share the index and run until either fails
+		failures := make(chan error)
+		if *grpcAddress != "" {
+			go func() { failures <- ListenGRPC(*grpcAddress, NewGRPCServer(loaded)) }()
+		}
+		if *httpAddress != "" {
+			go func() { failures <- ListenHTTP(*httpAddress, NewHTTPServer(loaded)) }()
+		}
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", <-failures)
+		os.Exit(2)
+	}
+
+	if *socket != "" {
+		if len(flags.Args()) == 0 {
+			flags.Usage()
+			os.Exit(1)
+		}
+
+		daemon := NewDaemon(flags.Args(), options, *cache, files)
+		if _, err := daemon.Refresh(); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Cannot index paths: %s\n", err)
+			os.Exit(2)
+		}
+		go daemon.Watch(*poll)
+		if err := daemon.ListenUnix(*socket); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(2)
+		}
+		return
 	}
 
-	return ioutil.ReadFile(path)
+	if *stdio {
+		if err := NewRPCServer(os.Stdin, os.Stdout, options).Serve(); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	if !*lsp {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	server := NewLanguageServer(os.Stdin, os.Stdout, options)
+	if err := server.Serve(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(2)
+	}
 }
 
-func main() {
-	includeRaw := flag.Bool("raw-ast", false, "include the raw ast")
+// indexFiles runs the index command, which prints the index of the files
+// matching its arguments and is run when no other command is given
+func indexFiles(args []string) {
+	flags := flag.NewFlagSet("index", flag.ExitOnError)
+	includeRaw := flags.Bool("raw-ast", false, "include the raw ast")
+	warnUnused := flags.Bool("warn-unused", false, "report unused declarations as warnings")
+	followModules := flags.Bool("follow-modules", false, "index the local modules called by the configuration")
+	workspaceMode := flags.Bool("workspace", false, "treat the paths as directories and index every root module below them")
+	followSymlinks := flags.Bool("follow-symlinks", false, "follow symbolic links to directories when walking directories")
+	gitRevision := flags.String("git-rev", "", "read the files from a git revision instead of the working tree")
+	shardDirectory := flags.String("shard", "", "with -workspace, write one index per root module and a manifest to this directory")
+	format := flags.String("format", FORMAT_JSON, "output format: "+strings.Join(formats, ", "))
+	output := flags.String("output", "", "write the index to sqlite:<path> instead of printing it")
+	templateText := flags.String("template", "", "print the index with this Go text/template instead of JSON")
+	cache := flags.String("cache", "", "restore the index from this file and save it back, only files changed since are parsed")
+	hashCache := flags.String("hash-cache", "", "keep the index of every file in this directory keyed by a hash of its contents, only files not found there are parsed")
+	excludes := stringList{}
+	flags.Var(&excludes, "exclude", "gitignore-style pattern of paths to skip, may be repeated")
+	sections := commaList{}
+	flags.Var(&sections, "only", "comma separated sections to print, of "+strings.Join(index.SECTIONS, ", "))
+	resourceTypes := commaList{}
+	flags.Var(&resourceTypes, "resource-type", "comma separated patterns like aws_* the types of the printed resources and data sources match")
+	maxFileSize := flags.Int64("max-file-size", index.DEFAULT_MAX_FILE_SIZE, "files larger than this many bytes are reported instead of indexed, 0 for no limit")
+	profiles := addProfileFlags(flags)
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [index] [options] <paths>\n\n", BINARY)
+		fmt.Fprintf(os.Stderr, "Extracts references and declarations from Terraform files, run\n")
+		fmt.Fprintf(os.Stderr, "'%s help' for the other commands\n\n", BINARY)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if len(flags.Args()) == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	if !isFormat(*format) {
+		fmt.Fprintf(os.Stderr, "ERROR: Unknown format '%s'\n", *format)
+		os.Exit(1)
+	}
+
+	filter, err := index.NewFilter(sections, resourceTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	if *format == FORMAT_NDJSON && (len(sections) > 0 || len(resourceTypes) > 0) {
+		fmt.Fprintf(os.Stderr, "ERROR: -format ndjson cannot be combined with -only or -resource-type\n")
+		os.Exit(1)
+	}
+
+	stop := profiles.start()
+	defer stop()
+	index.MaxFileSize = *maxFileSize
+
+	analysisOptions := index.AnalysisOptions{
+		WarnUnused: *warnUnused,
+	}
+
+	if *workspaceMode && *gitRevision != "" {
+		fmt.Fprintf(os.Stderr, "ERROR: -git-rev cannot be combined with -workspace\n")
+		os.Exit(1)
+	}
+
+	database := strings.TrimPrefix(*output, OUTPUT_SQLITE)
+	if *output != "" && (database == *output || database == "") {
+		fmt.Fprintf(os.Stderr, "ERROR: Unknown output '%s', expected %s<path>\n", *output, OUTPUT_SQLITE)
+		os.Exit(1)
+	}
+	if *output != "" {
+		if err := store.Check(); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *output != "" && *format != FORMAT_JSON {
+		fmt.Fprintf(os.Stderr, "ERROR: -output cannot be combined with -format\n")
+		os.Exit(1)
+	}
+
+	var printer *template.Template
+	if *templateText != "" {
+		if *format != FORMAT_JSON || *output != "" || *shardDirectory != "" {
+			fmt.Fprintf(os.Stderr, "ERROR: -template cannot be combined with -format, -output or -shard\n")
+			os.Exit(1)
+		}
+		if printer, err = parseTemplate(*templateText); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Cannot parse template: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *format == FORMAT_NDJSON && (*workspaceMode || *followModules) {
+		fmt.Fprintf(os.Stderr, "ERROR: -format ndjson cannot be combined with -workspace or -follow-modules\n")
+		os.Exit(1)
+	}
 
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "usage: %s [options] <paths>\n\n", BINARY)
-		fmt.Fprintf(os.Stderr, "Extracts references and declarations from Terraform files\n")
-		flag.PrintDefaults()
+	if *cache != "" && (*workspaceMode || *gitRevision != "" || *followModules || *includeRaw || *format == FORMAT_NDJSON) {
+		fmt.Fprintf(os.Stderr, "ERROR: -cache cannot be combined with -workspace, -git-rev, -follow-modules, -raw-ast or -format ndjson\n")
+		os.Exit(1)
 	}
-	flag.Parse()
 
-	if len(flag.Args()) == 0 {
-		flag.Usage()
+	if *cache != "" && *hashCache != "" {
+		fmt.Fprintf(os.Stderr, "ERROR: -cache cannot be combined with -hash-cache\n")
 		os.Exit(1)
 	}
 
+	var fileCache *index.FileCache
+	if *hashCache != "" {
+		fileCache = index.NewFileCache(*hashCache)
+	}
+
+	if *workspaceMode {
+		workspace := index.NewWorkspace()
+		workspace.Exclude(excludes...)
+		workspace.FollowSymlinks(*followSymlinks)
+		workspace.Cache(fileCache)
+		for _, directory := range flags.Args() {
+			if err := workspace.Discover(directory, *includeRaw); err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: Cannot walk directory '%s': %s\n", directory, err)
+				os.Exit(2)
+			}
+		}
+
+		if *followModules {
+			workspace.FollowModules(*includeRaw)
+		}
+		if fileCache != nil {
+			saveFileCache(fileCache)
+		}
+		workspace.Resolve()
+		workspace.Analyze(analysisOptions)
+		for _, directory := range workspace.Directories() {
+			workspace.Roots[directory] = workspace.Roots[directory].Filtered(filter)
+		}
+		if *shardDirectory != "" {
+			if _, err := workspace.WriteShards(*shardDirectory); err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: Cannot write shards to '%s': %s\n", *shardDirectory, err)
+				os.Exit(3)
+			}
+			return
+		}
+
+		if *format != FORMAT_JSON || *output != "" {
+			indexes := []*index.Index{}
+			for _, directory := range workspace.Directories() {
+				indexes = append(indexes, workspace.Roots[directory])
+			}
+			if *output != "" {
+				writeDatabase(database, indexes...)
+				return
+			}
+			writeFormat(*format, ioutil.ReadFile, indexes...)
+			return
+		}
+
+		if printer != nil {
+			writeTemplate(printer, workspace)
+			return
+		}
+		writeJSON(workspace)
+		return
+	}
+
+	ignore := index.NewIgnoreList()
+	if err := ignore.LoadIgnoreFile("."); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot read '%s': %s\n", index.IGNORE_FILE, err)
+		os.Exit(2)
+	}
+	for _, pattern := range excludes {
+		ignore.Add(pattern)
+	}
+
+	var paths []string
+	if *gitRevision != "" {
+		var files []string
+		files, err = GitFiles(*gitRevision)
+		if err == nil {
+			paths, err = index.MatchGlobs(flags.Args(), files)
+		}
+	} else {
+		paths, err = index.ExpandGlobs(flags.Args(), *followSymlinks)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot expand paths: %s\n", err)
+		os.Exit(2)
+	}
+
+	// only etags needs the contents once the files are collected
+	sources := map[string][]byte{}
+	keepSources := *format == FORMAT_ETAGS
+	stream := json.NewEncoder(os.Stdout)
+	streaming := *format == FORMAT_NDJSON
 	index := index.NewIndex()
-	for _, path := range flag.Args() {
-		source, err := Contents(path)
+	if *cache != "" {
+		kept := []string{}
+		for _, path := range paths {
+			if IsArchive(path) || path == "-" {
+				fmt.Fprintf(os.Stderr, "ERROR: -cache cannot index '%s'\n", path)
+				os.Exit(1)
+			}
+			if !ignore.Match(path, false) {
+				kept = append(kept, path)
+			}
+		}
+
+		index, err = restoreIndex(*cache, kept, false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Cannot index paths: %s\n", err)
+			os.Exit(2)
+		}
+		paths = nil
+	}
+	// files on disk are only read when their stamp changed since they were
+	// cached, unless their contents are needed
+	stampable := fileCache != nil && *gitRevision == "" && !keepSources && !streaming
+	for _, path := range paths {
+		if ignore.Match(path, false) {
+			continue
+		}
+
+		if stampable && path != "-" && !IsArchive(path) {
+			if err := index.CollectPathCached(fileCache, path, *includeRaw); err != nil {
+				if _, ok := err.(*os.PathError); ok {
+					fmt.Fprintf(os.Stderr, "ERROR: Cannot open path '%s': %s\n", path, err)
+					os.Exit(2)
+				}
+				fmt.Fprintf(os.Stderr, "ERROR: Could not parse '%s': %s\n", path, err)
+			}
+			continue
+		}
+
+		source, err := Contents(path, *gitRevision)
+		if tooLarge, ok := isTooLarge(err); ok {
+			fmt.Fprintf(os.Stderr, "ERROR: Could not parse '%s': %s\n", path, err)
+			if !streaming {
+				index.SkipFile(tooLarge)
+			}
+			continue
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "ERROR: Cannot open path '%s': %s\n", path, err)
 			os.Exit(2)
 		}
 
-		err = index.CollectString(source, path, *includeRaw)
+		if IsArchive(path) {
+			files, err := ArchiveFiles(path, source)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: Cannot read archive '%s': %s\n", path, err)
+				os.Exit(2)
+			}
+
+			for _, file := range files {
+				if file.TooLarge != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: Could not parse '%s' in '%s': %s\n", file.Path, path, file.TooLarge)
+					if !streaming {
+						index.SkipFile(file.TooLarge)
+					}
+					continue
+				}
+				if keepSources {
+					sources[file.Path] = file.Contents
+				}
+				if streaming {
+					if err := writeFileRecord(stream, file.Contents, file.Path, *includeRaw); err != nil {
+						fmt.Fprintf(os.Stderr, "ERROR: Could not parse '%s' in '%s': %s\n", file.Path, path, err)
+					}
+					continue
+				}
+				if err := index.CollectCached(fileCache, file.Contents, file.Path, *includeRaw); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: Could not parse '%s' in '%s': %s\n", file.Path, path, err)
+				}
+			}
+			continue
+		}
+
+		if keepSources {
+			sources[path] = source
+		}
+		if streaming {
+			if err := writeFileRecord(stream, source, path, *includeRaw); err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: Could not parse '%s': %s\n", path, err)
+			}
+			continue
+		}
+		err = index.CollectCached(fileCache, source, path, *includeRaw)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "ERROR: Could not parse '%s': %s\n", path, err)
 		}
 	}
 
-	json, err := json.MarshalIndent(index, "", "  ")
+	if streaming {
+		return
+	}
+	if fileCache != nil {
+		saveFileCache(fileCache)
+	}
+
+	if *followModules {
+		index.FollowModules(*includeRaw)
+	}
+	index.Resolve()
+	index.Analyze(analysisOptions)
+	if *cache != "" {
+		saveIndex(*cache, index)
+	}
+	index = index.Filtered(filter)
+	if *output != "" {
+		writeDatabase(database, index)
+		return
+	}
+	if *format != FORMAT_JSON {
+		writeFormat(*format, func(path string) ([]byte, error) {
+			if contents, ok := sources[path]; ok {
+				return contents, nil
+			}
+			return ioutil.ReadFile(path)
+		}, index)
+		return
+	}
+	if printer != nil {
+		writeTemplate(printer, index)
+		return
+	}
+
+	writeJSON(index)
+}
+
+func writeJSON(value interface{}) {
+	json, err := json.MarshalIndent(value, "", "  ")
 	if err != nil {
 		os.Exit(3)
 	}
 
 	os.Stdout.Write(json)
 }
+
+// parseTemplate parses the text of -template, which may call json to print
+// a value as JSON and join to join a list of strings
+func parseTemplate(text string) (*template.Template, error) {
+	return template.New("template").Funcs(template.FuncMap{
+		"json": func(value interface{}) (string, error) {
+			encoded, err := json.Marshal(value)
+			return string(encoded), err
+		},
+		"join": strings.Join,
+	}).Parse(text)
+}
+
+// writeTemplate prints value with a template parsed by parseTemplate
+func writeTemplate(printer *template.Template, value interface{}) {
+	if err := printer.Execute(os.Stdout, value); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot execute template: %s\n", err)
+		os.Exit(3)
+	}
+}
+
+// writeDatabase writes the indexes to the SQLite database at path
+func writeDatabase(path string, indexes ...*index.Index) {
+	if err := store.Write(path, indexes...); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot write database '%s': %s\n", path, err)
+		os.Exit(3)
+	}
+}
+
+// FileRecord is the line written for every file by -format ndjson
+type FileRecord struct {
+	Path  string
+	Index *index.Index
+}
+
+// writeFileRecord indexes a single file and writes it as soon as it has been
+// parsed, the record is written even if the file cannot be parsed so its
+// diagnostics are reported
+func writeFileRecord(stream *json.Encoder, contents []byte, path string, includeRaw bool) error {
+	fileIndex, err := index.CollectFile(contents, path, includeRaw)
+	if writeErr := stream.Encode(FileRecord{path, fileIndex}); writeErr != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot write '%s': %s\n", path, writeErr)
+		os.Exit(3)
+	}
+
+	return err
+}
+
+// writeFormat writes the indexes in a format other than json, contents
+// returns the contents of the collected files
+func writeFormat(format string, contents func(path string) ([]byte, error), indexes ...*index.Index) {
+	var err error
+	switch format {
+	case FORMAT_CTAGS:
+		err = index.WriteCtags(os.Stdout, indexes...)
+	case FORMAT_ETAGS:
+		err = index.WriteEtags(os.Stdout, contents, indexes...)
+	case FORMAT_LSIF:
+		err = index.WriteLSIF(os.Stdout, ".", indexes...)
+	case FORMAT_SCIP:
+		err = index.WriteSCIP(os.Stdout, ".", indexes...)
+	case FORMAT_SARIF:
+		err = index.WriteSARIF(os.Stdout, ".", indexes...)
+	case FORMAT_DOT:
+		err = index.WriteDot(os.Stdout, indexes...)
+	case FORMAT_MERMAID:
+		err = index.WriteMermaid(os.Stdout, indexes...)
+	case FORMAT_CSV:
+		err = index.WriteCSV(os.Stdout, ',', indexes...)
+	case FORMAT_TSV:
+		err = index.WriteCSV(os.Stdout, '\t', indexes...)
+	case FORMAT_BINARY:
+		err = index.WriteBinary(os.Stdout, indexes...)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot write %s: %s\n", format, err)
+		os.Exit(3)
+	}
+}
@@ -0,0 +1,122 @@
+variable "api_instance_type" {
+  type        = string
+  default     = "t3.micro"
+  description = "Instance type of the api service"
+}
+
+variable "api_replicas" {
+  type    = number
+  default = 1
+
+  validation {
+    condition     = var.api_replicas > 0
+    error_message = "The api service needs at least one replica."
+  }
+}
+
+variable "api_ports" {
+  type = map(object({
+    port     = number
+    protocol = string
+  }))
+  default = {
+    http  = { port = 8000, protocol = "tcp" }
+    admin = { port = 9000, protocol = "tcp" }
+  }
+}
+
+locals {
+  api_name = "${var.environment}-api"
+  api_tags = merge(local.common_tags, {
+    Service = "api"
+    Tier    = "frontend"
+  })
+  api_ports = [for key, value in var.api_ports : value.port]
+}
+
+data "aws_ami" "api" {
+  most_recent = true
+  owners      = ["amazon"]
+
+  filter {
+    name   = "name"
+    values = ["al2023-ami-*-x86_64"]
+  }
+}
+
+resource "aws_security_group" "api" {
+  name   = local.api_name
+  vpc_id = module.network.vpc_id
+  tags   = local.api_tags
+
+  dynamic "ingress" {
+    for_each = var.api_ports
+    content {
+      description = ingress.key
+      from_port   = ingress.value.port
+      to_port     = ingress.value.port
+      protocol    = ingress.value.protocol
+      cidr_blocks = [module.network.cidr_block]
+    }
+  }
+}
+
+resource "aws_launch_template" "api" {
+  name_prefix            = "${local.api_name}-"
+  image_id               = data.aws_ami.api.id
+  instance_type          = var.api_instance_type
+  vpc_security_group_ids = [aws_security_group.api.id]
+  user_data              = base64encode(templatefile("${path.module}/templates/api.sh", { name = local.api_name }))
+
+  tag_specifications {
+    resource_type = "instance"
+    tags          = local.api_tags
+  }
+}
+
+resource "aws_autoscaling_group" "api" {
+  name                = local.api_name
+  min_size            = var.api_replicas
+  max_size            = var.api_replicas * 2
+  vpc_zone_identifier = module.network.private_subnet_ids
+
+  launch_template {
+    id      = aws_launch_template.api.id
+    version = "$Latest"
+  }
+}
+
+resource "aws_cloudwatch_metric_alarm" "api_cpu" {
+  count               = var.environment == "production" ? 1 : 0
+  alarm_name          = "${local.api_name}-cpu"
+  comparison_operator = "GreaterThanThreshold"
+  evaluation_periods  = 3
+  metric_name         = "CPUUtilization"
+  namespace           = "AWS/EC2"
+  period              = 60
+  statistic           = "Average"
+  threshold           = 60
+  alarm_actions       = [aws_sns_topic.alerts.arn]
+
+  dimensions = {
+    AutoScalingGroupName = aws_autoscaling_group.api.name
+  }
+}
+
+resource "aws_route53_record" "api" {
+  for_each = toset(["internal", "external"])
+  zone_id  = each.key == "internal" ? module.network.private_zone_id : var.public_zone_id
+  name     = "api.${each.key}.${var.domain}"
+  type     = "A"
+  ttl      = 300
+  records  = [cidrhost(module.network.cidr_block, 10)]
+}
+
+output "api_security_group_id" {
+  value = aws_security_group.api.id
+}
+
+output "api_autoscaling_group" {
+  value       = aws_autoscaling_group.api.name
+  description = "Name of the autoscaling group of the api service"
+}
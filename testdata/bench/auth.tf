@@ -0,0 +1,122 @@
+variable "auth_instance_type" {
+  type        = string
+  default     = "t3.large"
+  description = "Instance type of the auth service"
+}
+
+variable "auth_replicas" {
+  type    = number
+  default = 3
+
+  validation {
+    condition     = var.auth_replicas > 0
+    error_message = "The auth service needs at least one replica."
+  }
+}
+
+variable "auth_ports" {
+  type = map(object({
+    port     = number
+    protocol = string
+  }))
+  default = {
+    http  = { port = 8007, protocol = "tcp" }
+    admin = { port = 9007, protocol = "tcp" }
+  }
+}
+
+locals {
+  auth_name = "${var.environment}-auth"
+  auth_tags = merge(local.common_tags, {
+    Service = "auth"
+    Tier    = "backend"
+  })
+  auth_ports = [for key, value in var.auth_ports : value.port]
+}
+
+data "aws_ami" "auth" {
+  most_recent = true
+  owners      = ["amazon"]
+
+  filter {
+    name   = "name"
+    values = ["al2023-ami-*-x86_64"]
+  }
+}
+
+resource "aws_security_group" "auth" {
+  name   = local.auth_name
+  vpc_id = module.network.vpc_id
+  tags   = local.auth_tags
+
+  dynamic "ingress" {
+    for_each = var.auth_ports
+    content {
+      description = ingress.key
+      from_port   = ingress.value.port
+      to_port     = ingress.value.port
+      protocol    = ingress.value.protocol
+      cidr_blocks = [module.network.cidr_block]
+    }
+  }
+}
+
+resource "aws_launch_template" "auth" {
+  name_prefix            = "${local.auth_name}-"
+  image_id               = data.aws_ami.auth.id
+  instance_type          = var.auth_instance_type
+  vpc_security_group_ids = [aws_security_group.auth.id]
+  user_data              = base64encode(templatefile("${path.module}/templates/auth.sh", { name = local.auth_name }))
+
+  tag_specifications {
+    resource_type = "instance"
+    tags          = local.auth_tags
+  }
+}
+
+resource "aws_autoscaling_group" "auth" {
+  name                = local.auth_name
+  min_size            = var.auth_replicas
+  max_size            = var.auth_replicas * 2
+  vpc_zone_identifier = module.network.private_subnet_ids
+
+  launch_template {
+    id      = aws_launch_template.auth.id
+    version = "$Latest"
+  }
+}
+
+resource "aws_cloudwatch_metric_alarm" "auth_cpu" {
+  count               = var.environment == "production" ? 1 : 0
+  alarm_name          = "${local.auth_name}-cpu"
+  comparison_operator = "GreaterThanThreshold"
+  evaluation_periods  = 3
+  metric_name         = "CPUUtilization"
+  namespace           = "AWS/EC2"
+  period              = 60
+  statistic           = "Average"
+  threshold           = 67
+  alarm_actions       = [aws_sns_topic.alerts.arn]
+
+  dimensions = {
+    AutoScalingGroupName = aws_autoscaling_group.auth.name
+  }
+}
+
+resource "aws_route53_record" "auth" {
+  for_each = toset(["internal", "external"])
+  zone_id  = each.key == "internal" ? module.network.private_zone_id : var.public_zone_id
+  name     = "auth.${each.key}.${var.domain}"
+  type     = "A"
+  ttl      = 300
+  records  = [cidrhost(module.network.cidr_block, 17)]
+}
+
+output "auth_security_group_id" {
+  value = aws_security_group.auth.id
+}
+
+output "auth_autoscaling_group" {
+  value       = aws_autoscaling_group.auth.name
+  description = "Name of the autoscaling group of the auth service"
+}
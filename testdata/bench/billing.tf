@@ -0,0 +1,122 @@
+variable "billing_instance_type" {
+  type        = string
+  default     = "t3.medium"
+  description = "Instance type of the billing service"
+}
+
+variable "billing_replicas" {
+  type    = number
+  default = 2
+
+  validation {
+    condition     = var.billing_replicas > 0
+    error_message = "The billing service needs at least one replica."
+  }
+}
+
+variable "billing_ports" {
+  type = map(object({
+    port     = number
+    protocol = string
+  }))
+  default = {
+    http  = { port = 8006, protocol = "tcp" }
+    admin = { port = 9006, protocol = "tcp" }
+  }
+}
+
+locals {
+  billing_name = "${var.environment}-billing"
+  billing_tags = merge(local.common_tags, {
+    Service = "billing"
+    Tier    = "frontend"
+  })
+  billing_ports = [for key, value in var.billing_ports : value.port]
+}
+
+data "aws_ami" "billing" {
+  most_recent = true
+  owners      = ["amazon"]
+
+  filter {
+    name   = "name"
+    values = ["al2023-ami-*-x86_64"]
+  }
+}
+
+resource "aws_security_group" "billing" {
+  name   = local.billing_name
+  vpc_id = module.network.vpc_id
+  tags   = local.billing_tags
+
+  dynamic "ingress" {
+    for_each = var.billing_ports
+    content {
+      description = ingress.key
+      from_port   = ingress.value.port
+      to_port     = ingress.value.port
+      protocol    = ingress.value.protocol
+      cidr_blocks = [module.network.cidr_block]
+    }
+  }
+}
+
+resource "aws_launch_template" "billing" {
+  name_prefix            = "${local.billing_name}-"
+  image_id               = data.aws_ami.billing.id
+  instance_type          = var.billing_instance_type
+  vpc_security_group_ids = [aws_security_group.billing.id]
+  user_data              = base64encode(templatefile("${path.module}/templates/billing.sh", { name = local.billing_name }))
+
+  tag_specifications {
+    resource_type = "instance"
+    tags          = local.billing_tags
+  }
+}
+
+resource "aws_autoscaling_group" "billing" {
+  name                = local.billing_name
+  min_size            = var.billing_replicas
+  max_size            = var.billing_replicas * 2
+  vpc_zone_identifier = module.network.private_subnet_ids
+
+  launch_template {
+    id      = aws_launch_template.billing.id
+    version = "$Latest"
+  }
+}
+
+resource "aws_cloudwatch_metric_alarm" "billing_cpu" {
+  count               = var.environment == "production" ? 1 : 0
+  alarm_name          = "${local.billing_name}-cpu"
+  comparison_operator = "GreaterThanThreshold"
+  evaluation_periods  = 3
+  metric_name         = "CPUUtilization"
+  namespace           = "AWS/EC2"
+  period              = 60
+  statistic           = "Average"
+  threshold           = 66
+  alarm_actions       = [aws_sns_topic.alerts.arn]
+
+  dimensions = {
+    AutoScalingGroupName = aws_autoscaling_group.billing.name
+  }
+}
+
+resource "aws_route53_record" "billing" {
+  for_each = toset(["internal", "external"])
+  zone_id  = each.key == "internal" ? module.network.private_zone_id : var.public_zone_id
+  name     = "billing.${each.key}.${var.domain}"
+  type     = "A"
+  ttl      = 300
+  records  = [cidrhost(module.network.cidr_block, 16)]
+}
+
+output "billing_security_group_id" {
+  value = aws_security_group.billing.id
+}
+
+output "billing_autoscaling_group" {
+  value       = aws_autoscaling_group.billing.name
+  description = "Name of the autoscaling group of the billing service"
+}
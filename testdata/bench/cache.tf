@@ -0,0 +1,122 @@
+variable "cache_instance_type" {
+  type        = string
+  default     = "t3.micro"
+  description = "Instance type of the cache service"
+}
+
+variable "cache_replicas" {
+  type    = number
+  default = 5
+
+  validation {
+    condition     = var.cache_replicas > 0
+    error_message = "The cache service needs at least one replica."
+  }
+}
+
+variable "cache_ports" {
+  type = map(object({
+    port     = number
+    protocol = string
+  }))
+  default = {
+    http  = { port = 8004, protocol = "tcp" }
+    admin = { port = 9004, protocol = "tcp" }
+  }
+}
+
+locals {
+  cache_name = "${var.environment}-cache"
+  cache_tags = merge(local.common_tags, {
+    Service = "cache"
+    Tier    = "backend"
+  })
+  cache_ports = [for key, value in var.cache_ports : value.port]
+}
+
+data "aws_ami" "cache" {
+  most_recent = true
+  owners      = ["amazon"]
+
+  filter {
+    name   = "name"
+    values = ["al2023-ami-*-x86_64"]
+  }
+}
+
+resource "aws_security_group" "cache" {
+  name   = local.cache_name
+  vpc_id = module.network.vpc_id
+  tags   = local.cache_tags
+
+  dynamic "ingress" {
+    for_each = var.cache_ports
+    content {
+      description = ingress.key
+      from_port   = ingress.value.port
+      to_port     = ingress.value.port
+      protocol    = ingress.value.protocol
+      cidr_blocks = [module.network.cidr_block]
+    }
+  }
+}
+
+resource "aws_launch_template" "cache" {
+  name_prefix            = "${local.cache_name}-"
+  image_id               = data.aws_ami.cache.id
+  instance_type          = var.cache_instance_type
+  vpc_security_group_ids = [aws_security_group.cache.id]
+  user_data              = base64encode(templatefile("${path.module}/templates/cache.sh", { name = local.cache_name }))
+
+  tag_specifications {
+    resource_type = "instance"
+    tags          = local.cache_tags
+  }
+}
+
+resource "aws_autoscaling_group" "cache" {
+  name                = local.cache_name
+  min_size            = var.cache_replicas
+  max_size            = var.cache_replicas * 2
+  vpc_zone_identifier = module.network.private_subnet_ids
+
+  launch_template {
+    id      = aws_launch_template.cache.id
+    version = "$Latest"
+  }
+}
+
+resource "aws_cloudwatch_metric_alarm" "cache_cpu" {
+  count               = var.environment == "production" ? 1 : 0
+  alarm_name          = "${local.cache_name}-cpu"
+  comparison_operator = "GreaterThanThreshold"
+  evaluation_periods  = 3
+  metric_name         = "CPUUtilization"
+  namespace           = "AWS/EC2"
+  period              = 60
+  statistic           = "Average"
+  threshold           = 64
+  alarm_actions       = [aws_sns_topic.alerts.arn]
+
+  dimensions = {
+    AutoScalingGroupName = aws_autoscaling_group.cache.name
+  }
+}
+
+resource "aws_route53_record" "cache" {
+  for_each = toset(["internal", "external"])
+  zone_id  = each.key == "internal" ? module.network.private_zone_id : var.public_zone_id
+  name     = "cache.${each.key}.${var.domain}"
+  type     = "A"
+  ttl      = 300
+  records  = [cidrhost(module.network.cidr_block, 14)]
+}
+
+output "cache_security_group_id" {
+  value = aws_security_group.cache.id
+}
+
+output "cache_autoscaling_group" {
+  value       = aws_autoscaling_group.cache.name
+  description = "Name of the autoscaling group of the cache service"
+}
@@ -0,0 +1,122 @@
+variable "events_instance_type" {
+  type        = string
+  default     = "t3.small"
+  description = "Instance type of the events service"
+}
+
+variable "events_replicas" {
+  type    = number
+  default = 5
+
+  validation {
+    condition     = var.events_replicas > 0
+    error_message = "The events service needs at least one replica."
+  }
+}
+
+variable "events_ports" {
+  type = map(object({
+    port     = number
+    protocol = string
+  }))
+  default = {
+    http  = { port = 8009, protocol = "tcp" }
+    admin = { port = 9009, protocol = "tcp" }
+  }
+}
+
+locals {
+  events_name = "${var.environment}-events"
+  events_tags = merge(local.common_tags, {
+    Service = "events"
+    Tier    = "frontend"
+  })
+  events_ports = [for key, value in var.events_ports : value.port]
+}
+
+data "aws_ami" "events" {
+  most_recent = true
+  owners      = ["amazon"]
+
+  filter {
+    name   = "name"
+    values = ["al2023-ami-*-x86_64"]
+  }
+}
+
+resource "aws_security_group" "events" {
+  name   = local.events_name
+  vpc_id = module.network.vpc_id
+  tags   = local.events_tags
+
+  dynamic "ingress" {
+    for_each = var.events_ports
+    content {
+      description = ingress.key
+      from_port   = ingress.value.port
+      to_port     = ingress.value.port
+      protocol    = ingress.value.protocol
+      cidr_blocks = [module.network.cidr_block]
+    }
+  }
+}
+
+resource "aws_launch_template" "events" {
+  name_prefix            = "${local.events_name}-"
+  image_id               = data.aws_ami.events.id
+  instance_type          = var.events_instance_type
+  vpc_security_group_ids = [aws_security_group.events.id]
+  user_data              = base64encode(templatefile("${path.module}/templates/events.sh", { name = local.events_name }))
+
+  tag_specifications {
+    resource_type = "instance"
+    tags          = local.events_tags
+  }
+}
+
+resource "aws_autoscaling_group" "events" {
+  name                = local.events_name
+  min_size            = var.events_replicas
+  max_size            = var.events_replicas * 2
+  vpc_zone_identifier = module.network.private_subnet_ids
+
+  launch_template {
+    id      = aws_launch_template.events.id
+    version = "$Latest"
+  }
+}
+
+resource "aws_cloudwatch_metric_alarm" "events_cpu" {
+  count               = var.environment == "production" ? 1 : 0
+  alarm_name          = "${local.events_name}-cpu"
+  comparison_operator = "GreaterThanThreshold"
+  evaluation_periods  = 3
+  metric_name         = "CPUUtilization"
+  namespace           = "AWS/EC2"
+  period              = 60
+  statistic           = "Average"
+  threshold           = 69
+  alarm_actions       = [aws_sns_topic.alerts.arn]
+
+  dimensions = {
+    AutoScalingGroupName = aws_autoscaling_group.events.name
+  }
+}
+
+resource "aws_route53_record" "events" {
+  for_each = toset(["internal", "external"])
+  zone_id  = each.key == "internal" ? module.network.private_zone_id : var.public_zone_id
+  name     = "events.${each.key}.${var.domain}"
+  type     = "A"
+  ttl      = 300
+  records  = [cidrhost(module.network.cidr_block, 19)]
+}
+
+output "events_security_group_id" {
+  value = aws_security_group.events.id
+}
+
+output "events_autoscaling_group" {
+  value       = aws_autoscaling_group.events.name
+  description = "Name of the autoscaling group of the events service"
+}
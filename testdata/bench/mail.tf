@@ -0,0 +1,122 @@
+variable "mail_instance_type" {
+  type        = string
+  default     = "t3.large"
+  description = "Instance type of the mail service"
+}
+
+variable "mail_replicas" {
+  type    = number
+  default = 2
+
+  validation {
+    condition     = var.mail_replicas > 0
+    error_message = "The mail service needs at least one replica."
+  }
+}
+
+variable "mail_ports" {
+  type = map(object({
+    port     = number
+    protocol = string
+  }))
+  default = {
+    http  = { port = 8011, protocol = "tcp" }
+    admin = { port = 9011, protocol = "tcp" }
+  }
+}
+
+locals {
+  mail_name = "${var.environment}-mail"
+  mail_tags = merge(local.common_tags, {
+    Service = "mail"
+    Tier    = "data"
+  })
+  mail_ports = [for key, value in var.mail_ports : value.port]
+}
+
+data "aws_ami" "mail" {
+  most_recent = true
+  owners      = ["amazon"]
+
+  filter {
+    name   = "name"
+    values = ["al2023-ami-*-x86_64"]
+  }
+}
+
+resource "aws_security_group" "mail" {
+  name   = local.mail_name
+  vpc_id = module.network.vpc_id
+  tags   = local.mail_tags
+
+  dynamic "ingress" {
+    for_each = var.mail_ports
+    content {
+      description = ingress.key
+      from_port   = ingress.value.port
+      to_port     = ingress.value.port
+      protocol    = ingress.value.protocol
+      cidr_blocks = [module.network.cidr_block]
+    }
+  }
+}
+
+resource "aws_launch_template" "mail" {
+  name_prefix            = "${local.mail_name}-"
+  image_id               = data.aws_ami.mail.id
+  instance_type          = var.mail_instance_type
+  vpc_security_group_ids = [aws_security_group.mail.id]
+  user_data              = base64encode(templatefile("${path.module}/templates/mail.sh", { name = local.mail_name }))
+
+  tag_specifications {
+    resource_type = "instance"
+    tags          = local.mail_tags
+  }
+}
+
+resource "aws_autoscaling_group" "mail" {
+  name                = local.mail_name
+  min_size            = var.mail_replicas
+  max_size            = var.mail_replicas * 2
+  vpc_zone_identifier = module.network.private_subnet_ids
+
+  launch_template {
+    id      = aws_launch_template.mail.id
+    version = "$Latest"
+  }
+}
+
+resource "aws_cloudwatch_metric_alarm" "mail_cpu" {
+  count               = var.environment == "production" ? 1 : 0
+  alarm_name          = "${local.mail_name}-cpu"
+  comparison_operator = "GreaterThanThreshold"
+  evaluation_periods  = 3
+  metric_name         = "CPUUtilization"
+  namespace           = "AWS/EC2"
+  period              = 60
+  statistic           = "Average"
+  threshold           = 71
+  alarm_actions       = [aws_sns_topic.alerts.arn]
+
+  dimensions = {
+    AutoScalingGroupName = aws_autoscaling_group.mail.name
+  }
+}
+
+resource "aws_route53_record" "mail" {
+  for_each = toset(["internal", "external"])
+  zone_id  = each.key == "internal" ? module.network.private_zone_id : var.public_zone_id
+  name     = "mail.${each.key}.${var.domain}"
+  type     = "A"
+  ttl      = 300
+  records  = [cidrhost(module.network.cidr_block, 21)]
+}
+
+output "mail_security_group_id" {
+  value = aws_security_group.mail.id
+}
+
+output "mail_autoscaling_group" {
+  value       = aws_autoscaling_group.mail.name
+  description = "Name of the autoscaling group of the mail service"
+}
@@ -0,0 +1,51 @@
+terraform {
+  required_version = ">= 1.5"
+
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+  }
+}
+
+provider "aws" {
+  region = var.region
+}
+
+variable "region" {
+  type    = string
+  default = "eu-west-1"
+}
+
+variable "environment" {
+  type = string
+}
+
+variable "domain" {
+  type = string
+}
+
+variable "public_zone_id" {
+  type = string
+}
+
+locals {
+  common_tags = {
+    Environment = var.environment
+    ManagedBy   = "terraform"
+  }
+}
+
+module "network" {
+  source = "./modules/network"
+
+  name       = var.environment
+  cidr_block = "10.0.0.0/16"
+  tags       = local.common_tags
+}
+
+resource "aws_sns_topic" "alerts" {
+  name = "${var.environment}-alerts"
+  tags = local.common_tags
+}
@@ -0,0 +1,122 @@
+variable "media_instance_type" {
+  type        = string
+  default     = "t3.medium"
+  description = "Instance type of the media service"
+}
+
+variable "media_replicas" {
+  type    = number
+  default = 1
+
+  validation {
+    condition     = var.media_replicas > 0
+    error_message = "The media service needs at least one replica."
+  }
+}
+
+variable "media_ports" {
+  type = map(object({
+    port     = number
+    protocol = string
+  }))
+  default = {
+    http  = { port = 8010, protocol = "tcp" }
+    admin = { port = 9010, protocol = "tcp" }
+  }
+}
+
+locals {
+  media_name = "${var.environment}-media"
+  media_tags = merge(local.common_tags, {
+    Service = "media"
+    Tier    = "backend"
+  })
+  media_ports = [for key, value in var.media_ports : value.port]
+}
+
+data "aws_ami" "media" {
+  most_recent = true
+  owners      = ["amazon"]
+
+  filter {
+    name   = "name"
+    values = ["al2023-ami-*-x86_64"]
+  }
+}
+
+resource "aws_security_group" "media" {
+  name   = local.media_name
+  vpc_id = module.network.vpc_id
+  tags   = local.media_tags
+
+  dynamic "ingress" {
+    for_each = var.media_ports
+    content {
+      description = ingress.key
+      from_port   = ingress.value.port
+      to_port     = ingress.value.port
+      protocol    = ingress.value.protocol
+      cidr_blocks = [module.network.cidr_block]
+    }
+  }
+}
+
+resource "aws_launch_template" "media" {
+  name_prefix            = "${local.media_name}-"
+  image_id               = data.aws_ami.media.id
+  instance_type          = var.media_instance_type
+  vpc_security_group_ids = [aws_security_group.media.id]
+  user_data              = base64encode(templatefile("${path.module}/templates/media.sh", { name = local.media_name }))
+
+  tag_specifications {
+    resource_type = "instance"
+    tags          = local.media_tags
+  }
+}
+
+resource "aws_autoscaling_group" "media" {
+  name                = local.media_name
+  min_size            = var.media_replicas
+  max_size            = var.media_replicas * 2
+  vpc_zone_identifier = module.network.private_subnet_ids
+
+  launch_template {
+    id      = aws_launch_template.media.id
+    version = "$Latest"
+  }
+}
+
+resource "aws_cloudwatch_metric_alarm" "media_cpu" {
+  count               = var.environment == "production" ? 1 : 0
+  alarm_name          = "${local.media_name}-cpu"
+  comparison_operator = "GreaterThanThreshold"
+  evaluation_periods  = 3
+  metric_name         = "CPUUtilization"
+  namespace           = "AWS/EC2"
+  period              = 60
+  statistic           = "Average"
+  threshold           = 70
+  alarm_actions       = [aws_sns_topic.alerts.arn]
+
+  dimensions = {
+    AutoScalingGroupName = aws_autoscaling_group.media.name
+  }
+}
+
+resource "aws_route53_record" "media" {
+  for_each = toset(["internal", "external"])
+  zone_id  = each.key == "internal" ? module.network.private_zone_id : var.public_zone_id
+  name     = "media.${each.key}.${var.domain}"
+  type     = "A"
+  ttl      = 300
+  records  = [cidrhost(module.network.cidr_block, 20)]
+}
+
+output "media_security_group_id" {
+  value = aws_security_group.media.id
+}
+
+output "media_autoscaling_group" {
+  value       = aws_autoscaling_group.media.name
+  description = "Name of the autoscaling group of the media service"
+}
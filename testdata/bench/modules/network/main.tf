@@ -0,0 +1,58 @@
+variable "name" {
+  type = string
+}
+
+variable "cidr_block" {
+  type = string
+}
+
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
+
+data "aws_availability_zones" "available" {
+  state = "available"
+}
+
+locals {
+  zones = slice(data.aws_availability_zones.available.names, 0, 3)
+}
+
+resource "aws_vpc" "main" {
+  cidr_block           = var.cidr_block
+  enable_dns_hostnames = true
+  tags                 = merge(var.tags, { Name = var.name })
+}
+
+resource "aws_subnet" "private" {
+  count             = length(local.zones)
+  vpc_id            = aws_vpc.main.id
+  availability_zone = local.zones[count.index]
+  cidr_block        = cidrsubnet(var.cidr_block, 8, count.index)
+  tags              = merge(var.tags, { Name = "${var.name}-private-${count.index}" })
+}
+
+resource "aws_route53_zone" "private" {
+  name = "${var.name}.internal"
+
+  vpc {
+    vpc_id = aws_vpc.main.id
+  }
+}
+
+output "vpc_id" {
+  value = aws_vpc.main.id
+}
+
+output "cidr_block" {
+  value = aws_vpc.main.cidr_block
+}
+
+output "private_subnet_ids" {
+  value = aws_subnet.private[*].id
+}
+
+output "private_zone_id" {
+  value = aws_route53_zone.private.zone_id
+}
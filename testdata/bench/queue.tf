@@ -0,0 +1,122 @@
+variable "queue_instance_type" {
+  type        = string
+  default     = "t3.large"
+  description = "Instance type of the queue service"
+}
+
+variable "queue_replicas" {
+  type    = number
+  default = 4
+
+  validation {
+    condition     = var.queue_replicas > 0
+    error_message = "The queue service needs at least one replica."
+  }
+}
+
+variable "queue_ports" {
+  type = map(object({
+    port     = number
+    protocol = string
+  }))
+  default = {
+    http  = { port = 8003, protocol = "tcp" }
+    admin = { port = 9003, protocol = "tcp" }
+  }
+}
+
+locals {
+  queue_name = "${var.environment}-queue"
+  queue_tags = merge(local.common_tags, {
+    Service = "queue"
+    Tier    = "frontend"
+  })
+  queue_ports = [for key, value in var.queue_ports : value.port]
+}
+
+data "aws_ami" "queue" {
+  most_recent = true
+  owners      = ["amazon"]
+
+  filter {
+    name   = "name"
+    values = ["al2023-ami-*-x86_64"]
+  }
+}
+
+resource "aws_security_group" "queue" {
+  name   = local.queue_name
+  vpc_id = module.network.vpc_id
+  tags   = local.queue_tags
+
+  dynamic "ingress" {
+    for_each = var.queue_ports
+    content {
+      description = ingress.key
+      from_port   = ingress.value.port
+      to_port     = ingress.value.port
+      protocol    = ingress.value.protocol
+      cidr_blocks = [module.network.cidr_block]
+    }
+  }
+}
+
+resource "aws_launch_template" "queue" {
+  name_prefix            = "${local.queue_name}-"
+  image_id               = data.aws_ami.queue.id
+  instance_type          = var.queue_instance_type
+  vpc_security_group_ids = [aws_security_group.queue.id]
+  user_data              = base64encode(templatefile("${path.module}/templates/queue.sh", { name = local.queue_name }))
+
+  tag_specifications {
+    resource_type = "instance"
+    tags          = local.queue_tags
+  }
+}
+
+resource "aws_autoscaling_group" "queue" {
+  name                = local.queue_name
+  min_size            = var.queue_replicas
+  max_size            = var.queue_replicas * 2
+  vpc_zone_identifier = module.network.private_subnet_ids
+
+  launch_template {
+    id      = aws_launch_template.queue.id
+    version = "$Latest"
+  }
+}
+
+resource "aws_cloudwatch_metric_alarm" "queue_cpu" {
+  count               = var.environment == "production" ? 1 : 0
+  alarm_name          = "${local.queue_name}-cpu"
+  comparison_operator = "GreaterThanThreshold"
+  evaluation_periods  = 3
+  metric_name         = "CPUUtilization"
+  namespace           = "AWS/EC2"
+  period              = 60
+  statistic           = "Average"
+  threshold           = 63
+  alarm_actions       = [aws_sns_topic.alerts.arn]
+
+  dimensions = {
+    AutoScalingGroupName = aws_autoscaling_group.queue.name
+  }
+}
+
+resource "aws_route53_record" "queue" {
+  for_each = toset(["internal", "external"])
+  zone_id  = each.key == "internal" ? module.network.private_zone_id : var.public_zone_id
+  name     = "queue.${each.key}.${var.domain}"
+  type     = "A"
+  ttl      = 300
+  records  = [cidrhost(module.network.cidr_block, 13)]
+}
+
+output "queue_security_group_id" {
+  value = aws_security_group.queue.id
+}
+
+output "queue_autoscaling_group" {
+  value       = aws_autoscaling_group.queue.name
+  description = "Name of the autoscaling group of the queue service"
+}
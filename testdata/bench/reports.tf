@@ -0,0 +1,122 @@
+variable "reports_instance_type" {
+  type        = string
+  default     = "t3.micro"
+  description = "Instance type of the reports service"
+}
+
+variable "reports_replicas" {
+  type    = number
+  default = 4
+
+  validation {
+    condition     = var.reports_replicas > 0
+    error_message = "The reports service needs at least one replica."
+  }
+}
+
+variable "reports_ports" {
+  type = map(object({
+    port     = number
+    protocol = string
+  }))
+  default = {
+    http  = { port = 8008, protocol = "tcp" }
+    admin = { port = 9008, protocol = "tcp" }
+  }
+}
+
+locals {
+  reports_name = "${var.environment}-reports"
+  reports_tags = merge(local.common_tags, {
+    Service = "reports"
+    Tier    = "data"
+  })
+  reports_ports = [for key, value in var.reports_ports : value.port]
+}
+
+data "aws_ami" "reports" {
+  most_recent = true
+  owners      = ["amazon"]
+
+  filter {
+    name   = "name"
+    values = ["al2023-ami-*-x86_64"]
+  }
+}
+
+resource "aws_security_group" "reports" {
+  name   = local.reports_name
+  vpc_id = module.network.vpc_id
+  tags   = local.reports_tags
+
+  dynamic "ingress" {
+    for_each = var.reports_ports
+    content {
+      description = ingress.key
+      from_port   = ingress.value.port
+      to_port     = ingress.value.port
+      protocol    = ingress.value.protocol
+      cidr_blocks = [module.network.cidr_block]
+    }
+  }
+}
+
+resource "aws_launch_template" "reports" {
+  name_prefix            = "${local.reports_name}-"
+  image_id               = data.aws_ami.reports.id
+  instance_type          = var.reports_instance_type
+  vpc_security_group_ids = [aws_security_group.reports.id]
+  user_data              = base64encode(templatefile("${path.module}/templates/reports.sh", { name = local.reports_name }))
+
+  tag_specifications {
+    resource_type = "instance"
+    tags          = local.reports_tags
+  }
+}
+
+resource "aws_autoscaling_group" "reports" {
+  name                = local.reports_name
+  min_size            = var.reports_replicas
+  max_size            = var.reports_replicas * 2
+  vpc_zone_identifier = module.network.private_subnet_ids
+
+  launch_template {
+    id      = aws_launch_template.reports.id
+    version = "$Latest"
+  }
+}
+
+resource "aws_cloudwatch_metric_alarm" "reports_cpu" {
+  count               = var.environment == "production" ? 1 : 0
+  alarm_name          = "${local.reports_name}-cpu"
+  comparison_operator = "GreaterThanThreshold"
+  evaluation_periods  = 3
+  metric_name         = "CPUUtilization"
+  namespace           = "AWS/EC2"
+  period              = 60
+  statistic           = "Average"
+  threshold           = 68
+  alarm_actions       = [aws_sns_topic.alerts.arn]
+
+  dimensions = {
+    AutoScalingGroupName = aws_autoscaling_group.reports.name
+  }
+}
+
+resource "aws_route53_record" "reports" {
+  for_each = toset(["internal", "external"])
+  zone_id  = each.key == "internal" ? module.network.private_zone_id : var.public_zone_id
+  name     = "reports.${each.key}.${var.domain}"
+  type     = "A"
+  ttl      = 300
+  records  = [cidrhost(module.network.cidr_block, 18)]
+}
+
+output "reports_security_group_id" {
+  value = aws_security_group.reports.id
+}
+
+output "reports_autoscaling_group" {
+  value       = aws_autoscaling_group.reports.name
+  description = "Name of the autoscaling group of the reports service"
+}
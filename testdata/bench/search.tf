@@ -0,0 +1,122 @@
+variable "search_instance_type" {
+  type        = string
+  default     = "t3.small"
+  description = "Instance type of the search service"
+}
+
+variable "search_replicas" {
+  type    = number
+  default = 1
+
+  validation {
+    condition     = var.search_replicas > 0
+    error_message = "The search service needs at least one replica."
+  }
+}
+
+variable "search_ports" {
+  type = map(object({
+    port     = number
+    protocol = string
+  }))
+  default = {
+    http  = { port = 8005, protocol = "tcp" }
+    admin = { port = 9005, protocol = "tcp" }
+  }
+}
+
+locals {
+  search_name = "${var.environment}-search"
+  search_tags = merge(local.common_tags, {
+    Service = "search"
+    Tier    = "data"
+  })
+  search_ports = [for key, value in var.search_ports : value.port]
+}
+
+data "aws_ami" "search" {
+  most_recent = true
+  owners      = ["amazon"]
+
+  filter {
+    name   = "name"
+    values = ["al2023-ami-*-x86_64"]
+  }
+}
+
+resource "aws_security_group" "search" {
+  name   = local.search_name
+  vpc_id = module.network.vpc_id
+  tags   = local.search_tags
+
+  dynamic "ingress" {
+    for_each = var.search_ports
+    content {
+      description = ingress.key
+      from_port   = ingress.value.port
+      to_port     = ingress.value.port
+      protocol    = ingress.value.protocol
+      cidr_blocks = [module.network.cidr_block]
+    }
+  }
+}
+
+resource "aws_launch_template" "search" {
+  name_prefix            = "${local.search_name}-"
+  image_id               = data.aws_ami.search.id
+  instance_type          = var.search_instance_type
+  vpc_security_group_ids = [aws_security_group.search.id]
+  user_data              = base64encode(templatefile("${path.module}/templates/search.sh", { name = local.search_name }))
+
+  tag_specifications {
+    resource_type = "instance"
+    tags          = local.search_tags
+  }
+}
+
+resource "aws_autoscaling_group" "search" {
+  name                = local.search_name
+  min_size            = var.search_replicas
+  max_size            = var.search_replicas * 2
+  vpc_zone_identifier = module.network.private_subnet_ids
+
+  launch_template {
+    id      = aws_launch_template.search.id
+    version = "$Latest"
+  }
+}
+
+resource "aws_cloudwatch_metric_alarm" "search_cpu" {
+  count               = var.environment == "production" ? 1 : 0
+  alarm_name          = "${local.search_name}-cpu"
+  comparison_operator = "GreaterThanThreshold"
+  evaluation_periods  = 3
+  metric_name         = "CPUUtilization"
+  namespace           = "AWS/EC2"
+  period              = 60
+  statistic           = "Average"
+  threshold           = 65
+  alarm_actions       = [aws_sns_topic.alerts.arn]
+
+  dimensions = {
+    AutoScalingGroupName = aws_autoscaling_group.search.name
+  }
+}
+
+resource "aws_route53_record" "search" {
+  for_each = toset(["internal", "external"])
+  zone_id  = each.key == "internal" ? module.network.private_zone_id : var.public_zone_id
+  name     = "search.${each.key}.${var.domain}"
+  type     = "A"
+  ttl      = 300
+  records  = [cidrhost(module.network.cidr_block, 15)]
+}
+
+output "search_security_group_id" {
+  value = aws_security_group.search.id
+}
+
+output "search_autoscaling_group" {
+  value       = aws_autoscaling_group.search.name
+  description = "Name of the autoscaling group of the search service"
+}
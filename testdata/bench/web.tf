@@ -0,0 +1,122 @@
+variable "web_instance_type" {
+  type        = string
+  default     = "t3.small"
+  description = "Instance type of the web service"
+}
+
+variable "web_replicas" {
+  type    = number
+  default = 2
+
+  validation {
+    condition     = var.web_replicas > 0
+    error_message = "The web service needs at least one replica."
+  }
+}
+
+variable "web_ports" {
+  type = map(object({
+    port     = number
+    protocol = string
+  }))
+  default = {
+    http  = { port = 8001, protocol = "tcp" }
+    admin = { port = 9001, protocol = "tcp" }
+  }
+}
+
+locals {
+  web_name = "${var.environment}-web"
+  web_tags = merge(local.common_tags, {
+    Service = "web"
+    Tier    = "backend"
+  })
+  web_ports = [for key, value in var.web_ports : value.port]
+}
+
+data "aws_ami" "web" {
+  most_recent = true
+  owners      = ["amazon"]
+
+  filter {
+    name   = "name"
+    values = ["al2023-ami-*-x86_64"]
+  }
+}
+
+resource "aws_security_group" "web" {
+  name   = local.web_name
+  vpc_id = module.network.vpc_id
+  tags   = local.web_tags
+
+  dynamic "ingress" {
+    for_each = var.web_ports
+    content {
+      description = ingress.key
+      from_port   = ingress.value.port
+      to_port     = ingress.value.port
+      protocol    = ingress.value.protocol
+      cidr_blocks = [module.network.cidr_block]
+    }
+  }
+}
+
+resource "aws_launch_template" "web" {
+  name_prefix            = "${local.web_name}-"
+  image_id               = data.aws_ami.web.id
+  instance_type          = var.web_instance_type
+  vpc_security_group_ids = [aws_security_group.web.id]
+  user_data              = base64encode(templatefile("${path.module}/templates/web.sh", { name = local.web_name }))
+
+  tag_specifications {
+    resource_type = "instance"
+    tags          = local.web_tags
+  }
+}
+
+resource "aws_autoscaling_group" "web" {
+  name                = local.web_name
+  min_size            = var.web_replicas
+  max_size            = var.web_replicas * 2
+  vpc_zone_identifier = module.network.private_subnet_ids
+
+  launch_template {
+    id      = aws_launch_template.web.id
+    version = "$Latest"
+  }
+}
+
+resource "aws_cloudwatch_metric_alarm" "web_cpu" {
+  count               = var.environment == "production" ? 1 : 0
+  alarm_name          = "${local.web_name}-cpu"
+  comparison_operator = "GreaterThanThreshold"
+  evaluation_periods  = 3
+  metric_name         = "CPUUtilization"
+  namespace           = "AWS/EC2"
+  period              = 60
+  statistic           = "Average"
+  threshold           = 61
+  alarm_actions       = [aws_sns_topic.alerts.arn]
+
+  dimensions = {
+    AutoScalingGroupName = aws_autoscaling_group.web.name
+  }
+}
+
+resource "aws_route53_record" "web" {
+  for_each = toset(["internal", "external"])
+  zone_id  = each.key == "internal" ? module.network.private_zone_id : var.public_zone_id
+  name     = "web.${each.key}.${var.domain}"
+  type     = "A"
+  ttl      = 300
+  records  = [cidrhost(module.network.cidr_block, 11)]
+}
+
+output "web_security_group_id" {
+  value = aws_security_group.web.id
+}
+
+output "web_autoscaling_group" {
+  value       = aws_autoscaling_group.web.name
+  description = "Name of the autoscaling group of the web service"
+}
@@ -0,0 +1,122 @@
+variable "worker_instance_type" {
+  type        = string
+  default     = "t3.medium"
+  description = "Instance type of the worker service"
+}
+
+variable "worker_replicas" {
+  type    = number
+  default = 3
+
+  validation {
+    condition     = var.worker_replicas > 0
+    error_message = "The worker service needs at least one replica."
+  }
+}
+
+variable "worker_ports" {
+  type = map(object({
+    port     = number
+    protocol = string
+  }))
+  default = {
+    http  = { port = 8002, protocol = "tcp" }
+    admin = { port = 9002, protocol = "tcp" }
+  }
+}
+
+locals {
+  worker_name = "${var.environment}-worker"
+  worker_tags = merge(local.common_tags, {
+    Service = "worker"
+    Tier    = "data"
+  })
+  worker_ports = [for key, value in var.worker_ports : value.port]
+}
+
+data "aws_ami" "worker" {
+  most_recent = true
+  owners      = ["amazon"]
+
+  filter {
+    name   = "name"
+    values = ["al2023-ami-*-x86_64"]
+  }
+}
+
+resource "aws_security_group" "worker" {
+  name   = local.worker_name
+  vpc_id = module.network.vpc_id
+  tags   = local.worker_tags
+
+  dynamic "ingress" {
+    for_each = var.worker_ports
+    content {
+      description = ingress.key
+      from_port   = ingress.value.port
+      to_port     = ingress.value.port
+      protocol    = ingress.value.protocol
+      cidr_blocks = [module.network.cidr_block]
+    }
+  }
+}
+
+resource "aws_launch_template" "worker" {
+  name_prefix            = "${local.worker_name}-"
+  image_id               = data.aws_ami.worker.id
+  instance_type          = var.worker_instance_type
+  vpc_security_group_ids = [aws_security_group.worker.id]
+  user_data              = base64encode(templatefile("${path.module}/templates/worker.sh", { name = local.worker_name }))
+
+  tag_specifications {
+    resource_type = "instance"
+    tags          = local.worker_tags
+  }
+}
+
+resource "aws_autoscaling_group" "worker" {
+  name                = local.worker_name
+  min_size            = var.worker_replicas
+  max_size            = var.worker_replicas * 2
+  vpc_zone_identifier = module.network.private_subnet_ids
+
+  launch_template {
+    id      = aws_launch_template.worker.id
+    version = "$Latest"
+  }
+}
+
+resource "aws_cloudwatch_metric_alarm" "worker_cpu" {
+  count               = var.environment == "production" ? 1 : 0
+  alarm_name          = "${local.worker_name}-cpu"
+  comparison_operator = "GreaterThanThreshold"
+  evaluation_periods  = 3
+  metric_name         = "CPUUtilization"
+  namespace           = "AWS/EC2"
+  period              = 60
+  statistic           = "Average"
+  threshold           = 62
+  alarm_actions       = [aws_sns_topic.alerts.arn]
+
+  dimensions = {
+    AutoScalingGroupName = aws_autoscaling_group.worker.name
+  }
+}
+
+resource "aws_route53_record" "worker" {
+  for_each = toset(["internal", "external"])
+  zone_id  = each.key == "internal" ? module.network.private_zone_id : var.public_zone_id
+  name     = "worker.${each.key}.${var.domain}"
+  type     = "A"
+  ttl      = 300
+  records  = [cidrhost(module.network.cidr_block, 12)]
+}
+
+output "worker_security_group_id" {
+  value = aws_security_group.worker.id
+}
+
+output "worker_autoscaling_group" {
+  value       = aws_autoscaling_group.worker.name
+  description = "Name of the autoscaling group of the worker service"
+}
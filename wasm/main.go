@@ -0,0 +1,154 @@
+//go:build js && wasm
+
+// Command wasm exposes the indexer to JavaScript as the global
+// terraformIndex object, so browser based editors can index Terraform
+// without a backend. Files only live in memory, every directory is indexed
+// as a module like the language server does:
+//
+//	terraformIndex.collect(contents, path) // {path, symbols, diagnostics}
+//	terraformIndex.remove(path)
+//	terraformIndex.definition(path, line, column)
+//	terraformIndex.references(path, line, column)
+//	terraformIndex.symbols(path)
+//	terraformIndex.diagnostics(path)
+//
+// Positions are one-based like in the JSON output, results are plain
+// objects shaped like the JSON output
+package main
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"syscall/js"
+
+	"github.com/mauve/terraform-index/index"
+)
+
+type collectResult struct {
+	Path        string             `json:"path"`
+	Symbols     []index.Symbol     `json:"symbols"`
+	Diagnostics []index.Diagnostic `json:"diagnostics"`
+}
+
+// documents holds the collected files, the index of a directory is built
+// when it is queried after one of its files changed
+type documents struct {
+	files   map[string][]byte
+	indexes map[string]*index.Index
+}
+
+func newDocuments() *documents {
+	return &documents{
+		files:   map[string][]byte{},
+		indexes: map[string]*index.Index{},
+	}
+}
+
+func (documents *documents) set(file string, contents []byte) {
+	if contents == nil {
+		delete(documents.files, file)
+	} else {
+		documents.files[file] = contents
+	}
+	delete(documents.indexes, path.Dir(file))
+}
+
+func (documents *documents) indexFor(file string) *index.Index {
+	directory := path.Dir(file)
+	if indexed, ok := documents.indexes[directory]; ok {
+		return indexed
+	}
+
+	files := []string{}
+	for file := range documents.files {
+		if path.Dir(file) == directory {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+
+	indexed := index.NewIndex()
+	for _, file := range files {
+		indexed.CollectString(documents.files[file], file, false)
+	}
+	indexed.Resolve()
+	indexed.Analyze(index.AnalysisOptions{})
+
+	documents.indexes[directory] = indexed
+	return indexed
+}
+
+// toJS converts a value to a plain JavaScript object through its JSON form
+func toJS(value interface{}) interface{} {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return js.Null()
+	}
+
+	return js.Global().Get("JSON").Call("parse", string(encoded))
+}
+
+// function wraps handle as a JavaScript function taking count arguments,
+// calls with fewer arguments return null
+func function(count int, handle func(args []js.Value) interface{}) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) < count {
+			return js.Null()
+		}
+
+		return handle(args)
+	})
+}
+
+func main() {
+	documents := newDocuments()
+
+	js.Global().Set("terraformIndex", js.ValueOf(map[string]interface{}{
+		"collect": function(2, func(args []js.Value) interface{} {
+			file := args[1].String()
+			documents.set(file, []byte(args[0].String()))
+
+			indexed := documents.indexFor(file)
+			return toJS(collectResult{
+				Path:        file,
+				Symbols:     indexed.SymbolsInFile(file),
+				Diagnostics: indexed.Diagnostics,
+			})
+		}),
+		"remove": function(1, func(args []js.Value) interface{} {
+			documents.set(args[0].String(), nil)
+			return js.Undefined()
+		}),
+		"definition": function(3, func(args []js.Value) interface{} {
+			hover, ok := documents.indexFor(args[0].String()).HoverAt(args[0].String(), args[1].Int(), args[2].Int())
+			if !ok {
+				return js.Null()
+			}
+			return toJS(hover)
+		}),
+		"references": function(3, func(args []js.Value) interface{} {
+			indexed := documents.indexFor(args[0].String())
+			address, ok := indexed.AddressAt(args[0].String(), args[1].Int(), args[2].Int())
+			if !ok {
+				return toJS([]interface{}{})
+			}
+			return toJS(indexed.ReferencesToAddress(address))
+		}),
+		"symbols": function(1, func(args []js.Value) interface{} {
+			return toJS(documents.indexFor(args[0].String()).SymbolsInFile(args[0].String()))
+		}),
+		"diagnostics": function(1, func(args []js.Value) interface{} {
+			diagnostics := []index.Diagnostic{}
+			for _, diagnostic := range documents.indexFor(args[0].String()).Diagnostics {
+				if diagnostic.Location.Filename == args[0].String() {
+					diagnostics = append(diagnostics, diagnostic)
+				}
+			}
+			return toJS(diagnostics)
+		}),
+	}))
+
+	// the functions are called from JavaScript as long as the page lives
+	select {}
+}
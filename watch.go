@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/mauve/terraform-index/index"
+)
+
+// WatchRecord is the line watch writes for a file which was added, changed
+// or removed, or whose diagnostics changed because another file did.
+// Removed files have neither symbols nor diagnostics
+type WatchRecord struct {
+	Path        string
+	Removed     bool
+	Symbols     []index.Symbol
+	Diagnostics []index.Diagnostic
+}
+
+// watcher writes the records of the files a refresh of its daemon changed,
+// diagnostics holds the diagnostics of every file as last written
+type watcher struct {
+	daemon      *Daemon
+	stream      *json.Encoder
+	diagnostics map[string]string
+}
+
+// write writes a record for every path and every other file whose
+// diagnostics changed since they were last written
+func (watcher *watcher) write(paths []string) {
+	indexed := watcher.daemon.current()
+	diagnostics := map[string][]index.Diagnostic{}
+	for path := range watcher.daemon.stamps {
+		diagnostics[path] = []index.Diagnostic{}
+	}
+	for _, diagnostic := range indexed.Diagnostics {
+		if path := diagnostic.Location.Filename; path != "" {
+			diagnostics[path] = append(diagnostics[path], diagnostic)
+		}
+	}
+
+	changed := map[string]bool{}
+	for _, path := range paths {
+		changed[path] = true
+	}
+	for path := range watcher.diagnostics {
+		if _, ok := diagnostics[path]; !ok {
+			changed[path] = true
+		}
+	}
+	encoded := map[string]string{}
+	for path, list := range diagnostics {
+		text, _ := json.Marshal(list)
+		encoded[path] = string(text)
+		if watcher.diagnostics[path] != encoded[path] {
+			changed[path] = true
+		}
+	}
+	watcher.diagnostics = encoded
+
+	records := []WatchRecord{}
+	for path := range changed {
+		if _, ok := watcher.daemon.stamps[path]; !ok {
+			records = append(records, WatchRecord{path, true, []index.Symbol{}, []index.Diagnostic{}})
+			continue
+		}
+		records = append(records, WatchRecord{path, false, indexed.SymbolsInFile(path), diagnostics[path]})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Path < records[j].Path
+	})
+
+	for _, record := range records {
+		if err := watcher.stream.Encode(record); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Cannot write '%s': %s\n", record.Path, err)
+			os.Exit(3)
+		}
+	}
+}
+
+// addWatches watches directory and every directory below it, except those
+// the ignore list matches
+func addWatches(notifier *fsnotify.Watcher, directory string, ignore *index.IgnoreList) error {
+	return filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != directory && ignore.Match(path, true) {
+			return filepath.SkipDir
+		}
+		return notifier.Add(path)
+	})
+}
+
+// treePatterns returns the patterns of the Terraform files of directory and
+// the directories below it
+func treePatterns(directory string) []string {
+	patterns := []string{}
+	for _, pattern := range directoryPatterns(directory) {
+		patterns = append(patterns, filepath.Join(filepath.Dir(pattern), "**", filepath.Base(pattern)))
+	}
+	return patterns
+}
+
+// watch runs the watch subcommand, which keeps the index of module
+// directories up to date as their files change and writes the changed part
+func watch(args []string) {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	warnUnused := flags.Bool("warn-unused", false, "report unused declarations as warnings")
+	debounce := flags.Duration("debounce", 100*time.Millisecond, "how long to wait for further changes before reindexing")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s watch [options] [<directories>]\n\n", BINARY)
+		fmt.Fprintf(os.Stderr, "Indexes the Terraform files of the directories and those below them,\n")
+		fmt.Fprintf(os.Stderr, "the current one by default, and writes a JSON line with the symbols\n")
+		fmt.Fprintf(os.Stderr, "and diagnostics of every file, then again for the files affected\n")
+		fmt.Fprintf(os.Stderr, "whenever files change\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	directories := flags.Args()
+	if len(directories) == 0 {
+		directories = []string{"."}
+	}
+
+	notifier, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot watch files: %s\n", err)
+		os.Exit(2)
+	}
+	defer notifier.Close()
+
+	ignore := index.NewIgnoreList()
+	if err := ignore.LoadIgnoreFile("."); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot read ignore file: %s\n", err)
+		os.Exit(2)
+	}
+
+	patterns := []string{}
+	for _, directory := range directories {
+		if err := addWatches(notifier, directory, ignore); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Cannot watch '%s': %s\n", directory, err)
+			os.Exit(2)
+		}
+		patterns = append(patterns, treePatterns(directory)...)
+	}
+
+	daemon := NewDaemon(patterns, index.AnalysisOptions{WarnUnused: *warnUnused}, "", nil)
+	paths, err := daemon.refresh()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Cannot index paths: %s\n", err)
+		os.Exit(2)
+	}
+	watcher := &watcher{daemon, json.NewEncoder(os.Stdout), map[string]string{}}
+	watcher.write(paths)
+
+	// the changes arriving together, like those of a checkout, are indexed
+	// at once
+	var reindex <-chan time.Time
+	for {
+		select {
+		case event, ok := <-notifier.Events:
+			if !ok {
+				return
+			}
+
+			// directories created later are watched as well, the files
+			// they came with are found by the refresh, which also drops
+			// the files of directories moved or removed as a whole
+			changed := index.IsTerraformFile(event.Name) || event.Op&(fsnotify.Remove|fsnotify.Rename) != 0
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() && !ignore.Match(event.Name, true) {
+					if err := addWatches(notifier, event.Name, ignore); err != nil {
+						fmt.Fprintf(os.Stderr, "ERROR: Cannot watch '%s': %s\n", event.Name, err)
+					}
+					changed = true
+				}
+			}
+			if changed && reindex == nil {
+				reindex = time.After(*debounce)
+			}
+		case err, ok := <-notifier.Errors:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		case <-reindex:
+			reindex = nil
+			paths, err := daemon.refresh()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: Cannot reindex: %s\n", err)
+				continue
+			}
+			watcher.write(paths)
+		}
+	}
+}